@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cheggaaa/pb/v3"
+	"github.com/fatih/color"
+	"github.com/godspeedcurry/godscan/utils"
+	prettytable "github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/viper"
+)
+
+type ApiProbeOptions struct {
+	Threads int
+	Methods string
+	PostAll bool
+	Root    string
+}
+
+var (
+	apiProbeOptions ApiProbeOptions
+)
+
+func init() {
+	apiProbeCmd := newCommandWithAliases("api-probe", "Replay stored api_paths with GET/POST, with and without -H auth headers, and flag unauthorized data access", []string{"ap"}, &apiProbeOptions)
+	apiProbeCmd.PersistentFlags().IntVarP(&apiProbeOptions.Threads, "threads", "t", 20, "Number of concurrent requests")
+	apiProbeCmd.PersistentFlags().StringVar(&apiProbeOptions.Methods, "methods", "GET,POST", "HTTP methods to replay")
+	apiProbeCmd.PersistentFlags().BoolVar(&apiProbeOptions.PostAll, "post-all", false, "Also POST to paths that look state-changing (delete/reset/update/...)")
+	apiProbeCmd.PersistentFlags().StringVar(&apiProbeOptions.Root, "root", "", "Only probe paths whose root url contains this string")
+	viper.BindPFlag("api-probe-threads", apiProbeCmd.PersistentFlags().Lookup("threads"))
+	viper.SetDefault("api-probe-threads", 20)
+	rootCmd.AddCommand(apiProbeCmd)
+}
+
+func (o *ApiProbeOptions) validateOptions() error {
+	for _, m := range o.methods() {
+		if m != http.MethodGet && m != http.MethodPost {
+			return fmt.Errorf("unsupported method %s", m)
+		}
+	}
+	return nil
+}
+
+func (o *ApiProbeOptions) methods() []string {
+	var out []string
+	for _, m := range strings.Split(o.Methods, ",") {
+		if m = strings.ToUpper(strings.TrimSpace(m)); m != "" {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+type apiProbeTask struct {
+	root   string
+	path   string
+	method string
+	auth   bool
+}
+
+func (o *ApiProbeOptions) run() {
+	utils.InitHttp()
+	db, err := utils.InitSpiderDB("spider.db")
+	if err != nil {
+		utils.Error("open spider.db failed: %v", err)
+		return
+	}
+	defer db.Close()
+	paths, err := utils.LoadAPIPaths(db)
+	if err != nil {
+		utils.Error("load api_paths failed: %v", err)
+		return
+	}
+
+	authModes := []bool{false}
+	if len(viper.GetStringSlice("headers")) > 0 {
+		authModes = append(authModes, true)
+	} else {
+		utils.Info("no -H headers given, replaying without credentials only")
+	}
+	ck := utils.NewCheckpoint(db, "api-probe", viper.GetBool("resume"))
+	defer ck.Close()
+
+	seen := make(map[string]struct{})
+	var tasks []apiProbeTask
+	skippedPost := 0
+	for _, p := range paths {
+		if o.Root != "" && !strings.Contains(p.RootURL, o.Root) {
+			continue
+		}
+		key := p.RootURL + " " + p.Path
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		for _, method := range o.methods() {
+			if method == http.MethodPost && !o.PostAll && utils.IsDestructiveAPIPath(p.Path) {
+				skippedPost++
+				continue
+			}
+			for _, auth := range authModes {
+				if ck.Done(key, apiProbeUnit(method, auth)) {
+					continue
+				}
+				tasks = append(tasks, apiProbeTask{root: p.RootURL, path: p.Path, method: method, auth: auth})
+			}
+		}
+	}
+	if skippedPost > 0 {
+		utils.Info("skip POST on %d state-changing looking path(s), use --post-all to include them", skippedPost)
+	}
+	if ck.Skipped() > 0 {
+		utils.Info("resume: skip %d finished probe(s)", ck.Skipped())
+	}
+	if len(tasks) == 0 {
+		utils.Warning("No api paths to probe, run spider first")
+		return
+	}
+	utils.Info("api-probe: %d request(s) over %d path(s)", len(tasks), len(seen))
+
+	results := o.probe(tasks, db, ck)
+	printAPIProbeResults(results)
+}
+
+func apiProbeUnit(method string, auth bool) string {
+	if auth {
+		return method + " auth"
+	}
+	return method
+}
+
+// apiProbeSaveBatch is how many results are committed at once; a probe is only journaled
+// for --resume after its row is in api_probes.
+const apiProbeSaveBatch = 50
+
+type apiProbeDone struct {
+	task apiProbeTask
+	res  utils.APIProbeResult
+}
+
+func (o *ApiProbeOptions) probe(tasks []apiProbeTask, db *sql.DB, ck *utils.Checkpoint) []utils.APIProbeResult {
+	threads := viper.GetInt("api-probe-threads")
+	if threads <= 0 {
+		threads = 20
+	}
+	var bar *pb.ProgressBar
+	if !viper.GetBool("quiet") {
+		bar = pb.StartNew(len(tasks))
+		bar.SetMaxWidth(90)
+		bar.Set("prefix", color.CyanString("api-probe"))
+		bar.SetTemplateString(`{{string . "prefix"}} {{counters . }} {{bar . "|" "█" "█" "░" "|"}} {{percent . }} | {{etime . }}`)
+		bar.SetRefreshRate(200 * time.Millisecond)
+	}
+	taskCh := make(chan apiProbeTask)
+	resCh := make(chan apiProbeDone, threads)
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range taskCh {
+				res, err := utils.ProbeAPI(t.root, t.path, t.method, t.auth)
+				if bar != nil {
+					bar.Increment()
+				}
+				if err != nil {
+					utils.Debug("api-probe %s %s%s: %v", t.method, t.root, t.path, err)
+					continue
+				}
+				resCh <- apiProbeDone{task: t, res: res}
+			}
+		}()
+	}
+	go func() {
+		for _, t := range tasks {
+			taskCh <- t
+		}
+		close(taskCh)
+		wg.Wait()
+		close(resCh)
+	}()
+	var (
+		results []utils.APIProbeResult
+		pending []apiProbeDone
+	)
+	flush := func() {
+		batch := make([]utils.APIProbeResult, 0, len(pending))
+		for _, d := range pending {
+			batch = append(batch, d.res)
+		}
+		if err := utils.SaveAPIProbes(db, batch); err != nil {
+			utils.Error("save api_probes failed: %v", err)
+		} else {
+			for _, d := range pending {
+				ck.Mark(d.task.root+" "+d.task.path, apiProbeUnit(d.task.method, d.task.auth), d.res.Class)
+			}
+		}
+		pending = pending[:0]
+	}
+	for d := range resCh {
+		results = append(results, d.res)
+		if pending = append(pending, d); len(pending) >= apiProbeSaveBatch {
+			flush()
+		}
+	}
+	flush()
+	if bar != nil {
+		bar.Finish()
+	}
+	return results
+}
+
+func printAPIProbeResults(results []utils.APIProbeResult) {
+	classes := make(map[string]int)
+	var unauth []utils.APIProbeResult
+	for _, r := range results {
+		classes[r.Class]++
+		if r.Unauth {
+			unauth = append(unauth, r)
+		}
+	}
+	var parts []string
+	for class, n := range classes {
+		parts = append(parts, fmt.Sprintf("%s=%d", class, n))
+	}
+	sort.Strings(parts)
+	utils.Info("api-probe: %d response(s): %s", len(results), strings.Join(parts, " "))
+	if len(unauth) == 0 {
+		utils.Info("api-probe: no endpoint returned data without credentials")
+		return
+	}
+	renderAPIProbeTable(unauth)
+	utils.Success("api-probe: %d endpoint(s) return data without credentials, saved to spider.db (api_probes)", len(unauth))
+}
+
+func renderAPIProbeTable(rows []utils.APIProbeResult) {
+	table := prettytable.NewWriter()
+	table.SetOutputMirror(os.Stdout)
+	table.AppendHeader(prettytable.Row{"Root URL", "Method", "Path", "Status", "Length", "Class", "Snippet"})
+	table.SetStyle(prettytable.StyleRounded)
+	table.SetColumnConfigs([]prettytable.ColumnConfig{
+		{Number: 3, WidthMax: 60},
+		{Number: 7, WidthMax: 60},
+	})
+	for _, r := range rows {
+		table.AppendRow(prettytable.Row{r.RootURL, r.Method, r.Path, r.Status, r.Length, r.Class, r.Snippet})
+	}
+	table.Render()
+}
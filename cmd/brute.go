@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/godspeedcurry/godscan/utils"
+	prettytable "github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/viper"
+)
+
+type ServiceBruteOptions struct {
+	Hosts      string
+	HostFile   string
+	Service    string
+	FromPort   bool
+	Users      string
+	UserFile   string
+	Passwords  string
+	PassFile   string
+	Keywords   string
+	Threads    int
+	Rate       int
+	Delay      int
+	MaxPerUser int
+	Timeout    int
+	All        bool
+}
+
+var serviceBruteOptions ServiceBruteOptions
+
+func init() {
+	bruteCmd := newCommandWithAliases("brute", "Brute force ftp/ssh/mysql/postgresql/redis/mongodb/mssql logins of host:port lists or port scan results", []string{"crack"}, &serviceBruteOptions)
+	bruteCmd.PersistentFlags().StringVarP(&serviceBruteOptions.Hosts, "host", "i", "", "host:port or service://host:port list, comma separated")
+	bruteCmd.PersistentFlags().StringVarP(&serviceBruteOptions.HostFile, "host-file", "I", "", "file with one host:port or service://host:port per line")
+	bruteCmd.PersistentFlags().StringVar(&serviceBruteOptions.Service, "service", "", "service of every target ("+strings.Join(utils.BruteServices(), ",")+"), default guessed from scheme or port")
+	bruteCmd.PersistentFlags().BoolVar(&serviceBruteOptions.FromPort, "from-port", false, "also target the services identified by the port command in spider.db")
+	bruteCmd.PersistentFlags().StringVar(&serviceBruteOptions.Users, "user", "", "usernames, comma separated; default the built-in list of each service")
+	bruteCmd.PersistentFlags().StringVar(&serviceBruteOptions.UserFile, "user-file", "", "username file, one per line")
+	bruteCmd.PersistentFlags().StringVar(&serviceBruteOptions.Passwords, "pass", "", "passwords, comma separated; default the built-in weak password list")
+	bruteCmd.PersistentFlags().StringVar(&serviceBruteOptions.PassFile, "pass-file", "", "password file, one per line")
+	bruteCmd.PersistentFlags().StringVarP(&serviceBruteOptions.Keywords, "keyword", "k", "", "also try passwords generated from these keywords, like weakpass -k")
+	bruteCmd.PersistentFlags().IntVarP(&serviceBruteOptions.Threads, "threads", "t", 10, "targets brute forced concurrently (one connection each)")
+	bruteCmd.PersistentFlags().IntVar(&serviceBruteOptions.Rate, "rate", 0, "max login attempts per second over all targets, 0 for no limit")
+	bruteCmd.PersistentFlags().IntVar(&serviceBruteOptions.Delay, "delay", 0, "milliseconds to wait between attempts on the same target")
+	bruteCmd.PersistentFlags().IntVar(&serviceBruteOptions.MaxPerUser, "max-per-user", 0, "max passwords tried per account to stay under lockout policies, 0 for no limit")
+	bruteCmd.PersistentFlags().IntVar(&serviceBruteOptions.Timeout, "timeout", 5, "connect/read timeout in seconds")
+	bruteCmd.PersistentFlags().BoolVar(&serviceBruteOptions.All, "all", false, "keep trying the other usernames of a target after a valid login")
+	rootCmd.AddCommand(bruteCmd)
+}
+
+func (o *ServiceBruteOptions) validateOptions() error {
+	if o.Hosts == "" && o.HostFile == "" && !o.FromPort {
+		return fmt.Errorf("please give -i, -I or --from-port")
+	}
+	return nil
+}
+
+// readList merges a comma separated flag with a one-per-line file, keeping the file order
+// (FileReadLine sorts, wordlists are ordered by likelihood).
+func readList(inline, file string) []string {
+	out := splitList(inline)
+	if file == "" {
+		return out
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		utils.Error("read %s failed: %v", file, err)
+		return out
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+func (o *ServiceBruteOptions) run() {
+	targets, err := utils.ParseBruteTargets(readList(o.Hosts, o.HostFile), o.Service)
+	if err != nil {
+		utils.Error("%v", err)
+		return
+	}
+	db, err := utils.InitSpiderDB("spider.db")
+	if err != nil {
+		utils.Error("failed to init spider.db: %v", err)
+		return
+	}
+	utils.SetSpiderDB(db)
+	defer db.Close()
+	if o.FromPort {
+		rows, err := utils.LoadPortServices(db)
+		if err != nil {
+			utils.Error("load service_results failed: %v", err)
+			return
+		}
+		targets = append(targets, utils.BruteTargetsFromServices(rows)...)
+	}
+	if len(targets) == 0 {
+		utils.Warning("No brute-forceable service (%s)", strings.Join(utils.BruteServices(), ","))
+		return
+	}
+
+	passwords := readList(o.Passwords, o.PassFile)
+	if o.Keywords != "" {
+		viper.Set("keyword", o.Keywords)
+		passwords = append(passwords, utils.BuildWeakPasswords()...)
+	}
+	opts := utils.BruteOptions{
+		Users:         readList(o.Users, o.UserFile),
+		Passwords:     passwords,
+		Threads:       o.Threads,
+		Rate:          o.Rate,
+		Delay:         time.Duration(o.Delay) * time.Millisecond,
+		MaxPerUser:    o.MaxPerUser,
+		Timeout:       time.Duration(o.Timeout) * time.Second,
+		StopOnSuccess: !o.All,
+	}
+	utils.Info("brute: %d target(s)", len(targets))
+	results := utils.RunBrute(targets, opts, db)
+	if len(results) == 0 {
+		utils.Info("brute: no valid credentials")
+		return
+	}
+	renderBruteResults(results)
+	utils.Success("brute: %d credential(s) saved to spider.db (brute_results)", len(results))
+}
+
+func renderBruteResults(results []utils.BruteResult) {
+	table := prettytable.NewWriter()
+	table.SetOutputMirror(os.Stdout)
+	table.AppendHeader(prettytable.Row{"Service", "Target", "Username", "Password", "Note"})
+	table.SetStyle(prettytable.StyleRounded)
+	for _, r := range results {
+		table.AppendRow(prettytable.Row{r.Service, r.Target, r.Username, r.Password, r.Note})
+	}
+	table.Render()
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"sync"
@@ -55,6 +56,18 @@ func (o *DirbruteOptions) run() {
 		return
 	}
 
+	db, err := utils.InitSpiderDB("spider.db")
+	if err != nil {
+		utils.Error("failed to init spider.db: %v", err)
+		return
+	}
+	utils.SetSpiderDB(db)
+	defer db.Close()
+	// every finished task is journaled with its table row and .DS_Store/.svn leads, so a resumed
+	// run shows the earlier hits again and still follows the listed paths
+	ck := utils.NewCheckpoint(db, "dirbrute", viper.GetBool("resume"))
+	defer ck.Close()
+
 	bar := pb.StartNew(totalTasks)
 	bar.SetMaxWidth(90)
 	bar.Set("prefix", color.CyanString("dirbrute"))
@@ -70,32 +83,59 @@ func (o *DirbruteOptions) run() {
 	if maxGoroutines <= 0 {
 		maxGoroutines = 1
 	}
-	tasks := make(chan struct {
+	type dirTask struct {
 		url string
 		dir string
-	})
+	}
+	tasks := make(chan dirTask)
 	rows := make(chan []string, maxGoroutines)
 	var workerWG sync.WaitGroup
+	// pending counts queued tasks, including paths listed by .DS_Store/.svn hits
+	var pending sync.WaitGroup
+	var seen sync.Map
+	leadCount := make(map[string]int)
+	var leadMu sync.Mutex
 
 	for i := 0; i < maxGoroutines; i++ {
 		workerWG.Add(1)
 		go func() {
 			defer workerWG.Done()
 			for task := range tasks {
-				rows <- utils.DirBrute(task.url, task.dir)
+				row, leads := runDirTask(ck, task.url, task.dir, utils.DirBruteWithLeads)
+				for _, lead := range leads {
+					if _, dup := seen.LoadOrStore(task.url+" "+lead, true); dup {
+						continue
+					}
+					leadMu.Lock()
+					over := leadCount[task.url] >= maxDirLeads
+					leadCount[task.url]++
+					leadMu.Unlock()
+					if over {
+						continue
+					}
+					pending.Add(1)
+					bar.AddTotal(1)
+					go func(t dirTask) { tasks <- t }(dirTask{url: task.url, dir: lead})
+				}
+				rows <- row
+				pending.Done()
 			}
 		}()
 	}
 
+	pending.Add(1)
 	go func() {
 		for _, line := range targetUrlList {
 			for _, dir := range targetDirList {
-				tasks <- struct {
-					url string
-					dir string
-				}{url: line, dir: dir}
+				seen.Store(line+" "+dir, true)
+				pending.Add(1)
+				tasks <- dirTask{url: line, dir: dir}
 			}
 		}
+		pending.Done()
+	}()
+	go func() {
+		pending.Wait()
 		close(tasks)
 	}()
 
@@ -111,12 +151,46 @@ func (o *DirbruteOptions) run() {
 		bar.Increment()
 	}
 	bar.Finish()
+	if skipped := ck.Skipped(); skipped > 0 {
+		utils.Info("resume: replayed %d finished task(s) from the journal", skipped)
+	}
 	if table.Length() >= 1 {
 		table.Render()
 	}
 
 }
 
+// dirTaskRecord is the journal payload of a finished dirbrute task.
+type dirTaskRecord struct {
+	Row   []string `json:"row,omitempty"`
+	Leads []string `json:"leads,omitempty"`
+}
+
+// runDirTask returns the row and leads of a task finished by the previous run from the
+// journal, and otherwise runs probe and journals its outcome.
+func runDirTask(ck *utils.Checkpoint, url, dir string, probe func(string, string) ([]string, []string)) ([]string, []string) {
+	if ck.Done(url, dir) {
+		data, _ := ck.Data(url, dir)
+		var rec dirTaskRecord
+		if data != "" && json.Unmarshal([]byte(data), &rec) != nil {
+			utils.Debug("dirbrute: bad journal entry for %s %s", url, dir)
+		}
+		return rec.Row, rec.Leads
+	}
+	row, leads := probe(url, dir)
+	data := ""
+	if len(row) > 0 || len(leads) > 0 {
+		if b, err := json.Marshal(dirTaskRecord{Row: row, Leads: leads}); err == nil {
+			data = string(b)
+		}
+	}
+	ck.Mark(url, dir, data)
+	return row, leads
+}
+
+// maxDirLeads caps the extra paths one target may gain from .DS_Store/.svn listings.
+const maxDirLeads = 2000
+
 func init() {
 	dirbruteCmd := newCommandWithAliases("dirbrute", "Bruteforce common directories/files", []string{"dir", "dirb", "dd"}, &dirbruteOptions)
 	dirbruteCmd.PersistentFlags().StringVarP(&dirbruteOptions.DirFile, "dir-file", "", "", "custom dictionary file")
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/godspeedcurry/godscan/utils"
+	"github.com/spf13/viper"
+)
+
+func TestRunDirTaskReplaysJournal(t *testing.T) {
+	oldOut := viper.Get("output")
+	viper.Set("output", filepath.Join(t.TempDir(), "result.log"))
+	t.Cleanup(func() { viper.Set("output", oldOut) })
+
+	db, err := utils.InitSpiderDB(filepath.Join(t.TempDir(), "spider.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	defer db.Close()
+
+	probe := func(url, dir string) ([]string, []string) {
+		if dir == "/.DS_Store" {
+			return []string{url + dir, "200"}, []string{"backup.zip"}
+		}
+		return nil, nil
+	}
+	ck := utils.NewCheckpoint(db, "dirbrute", false)
+	runDirTask(ck, "http://a", "/.DS_Store", probe)
+	runDirTask(ck, "http://a", "/admin", probe)
+	ck.Close()
+
+	failProbe := func(url, dir string) ([]string, []string) {
+		t.Fatalf("finished task %s %s probed again", url, dir)
+		return nil, nil
+	}
+	resumed := utils.NewCheckpoint(db, "dirbrute", true)
+	row, leads := runDirTask(resumed, "http://a", "/.DS_Store", failProbe)
+	if fmt.Sprint(row) != "[http://a/.DS_Store 200]" || fmt.Sprint(leads) != "[backup.zip]" {
+		t.Fatalf("replayed row=%v leads=%v", row, leads)
+	}
+	if row, leads := runDirTask(resumed, "http://a", "/admin", failProbe); row != nil || leads != nil {
+		t.Fatalf("miss replayed as row=%v leads=%v", row, leads)
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
@@ -86,16 +87,41 @@ func (o *ExposureOptions) run() error {
 	utils.Info("tcp ports to scan: %d", len(ports))
 
 	type result struct {
-		Domain string
-		IP     string
-		Ports  []int
-		Title  string
+		Domain string `json:"domain"`
+		IP     string `json:"ip"`
+		Ports  []int  `json:"ports"`
+		Title  string `json:"title"`
 	}
 	out := make([]result, 0, len(targets))
 
+	db, err := utils.InitSpiderDB("spider.db")
+	if err != nil {
+		return fmt.Errorf("open spider.db failed: %w", err)
+	}
+	defer db.Close()
+	ck := utils.NewCheckpoint(db, "exposure", viper.GetBool("resume"))
+	defer ck.Close()
+	pending := make([]string, 0, len(targets))
+	for _, h := range targets {
+		if !ck.Done(h, "") {
+			pending = append(pending, h)
+			continue
+		}
+		data, _ := ck.Data(h, "")
+		var r result
+		if err := json.Unmarshal([]byte(data), &r); err != nil {
+			pending = append(pending, h)
+			continue
+		}
+		out = append(out, r)
+	}
+	if len(out) > 0 {
+		utils.Info("resume: reuse %d finished target(s)", len(out))
+	}
+
 	type job struct{ host string }
 	jobCh := make(chan job)
-	resCh := make(chan result, len(targets))
+	resCh := make(chan result, len(pending))
 	workers := o.TargetWorkers
 	if workers <= 0 {
 		workers = 20
@@ -112,17 +138,21 @@ func (o *ExposureOptions) run() error {
 				}
 				openPorts := utils.QuickPortScan(ip, ports, o.Workers, time.Duration(o.DialTime)*time.Second)
 				title := fetchTitle(host, o.HTTPTimeout)
-				resCh <- result{Domain: host, IP: ip, Ports: openPorts, Title: title}
+				r := result{Domain: host, IP: ip, Ports: openPorts, Title: title}
+				if data, err := json.Marshal(r); err == nil {
+					ck.Mark(host, "", string(data))
+				}
+				resCh <- r
 			}
 		}()
 	}
 	go func() {
-		for _, h := range targets {
+		for _, h := range pending {
 			jobCh <- job{host: h}
 		}
 		close(jobCh)
 	}()
-	for i := 0; i < len(targets); i++ {
+	for i := 0; i < len(pending); i++ {
 		out = append(out, <-resCh)
 	}
 
@@ -5,11 +5,16 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"sync"
 
 	"github.com/godspeedcurry/godscan/utils"
+	prettytable "github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/viper"
 )
 
 type IconOptions struct {
+	Threads int
 }
 
 var (
@@ -19,12 +24,15 @@ var (
 // iconCmd represents the icon command
 
 func init() {
-	iconCmd := newCommandWithAliases("icon", "Calculate hash of an icon, eg: godscan icon -u http://example.com/favicon.ico", []string{"ico"}, &iconOptions)
+	iconCmd := newCommandWithAliases("icon", "Calculate hash of an icon, eg: godscan icon -u http://example.com/favicon.ico, or resolve a batch of targets to products with -f", []string{"ico"}, &iconOptions)
+	iconCmd.PersistentFlags().IntVarP(&iconOptions.Threads, "threads", "t", 20, "Number of concurrent targets in batch mode (-f)")
+	viper.BindPFlag("icon-threads", iconCmd.PersistentFlags().Lookup("threads"))
+	viper.SetDefault("icon-threads", 20)
 	rootCmd.AddCommand(iconCmd)
 }
 
 func (o *IconOptions) validateOptions() error {
-	if GlobalOption.Url == "" {
+	if GlobalOption.Url == "" && GlobalOption.UrlFile == "" {
 		return fmt.Errorf("please give target url")
 	}
 	return nil
@@ -32,15 +40,66 @@ func (o *IconOptions) validateOptions() error {
 
 func (o *IconOptions) run() {
 	utils.InitHttp()
-	iconURL, err := utils.FindFaviconURL(GlobalOption.Url)
-	if err != nil {
-		utils.Error("%v", err)
+	if GlobalOption.Url == "" {
+		o.runBatch(GetTargetList())
 		return
 	}
-	fofa, hunter, iconB64, err := utils.IconDetect(iconURL)
-	if err != nil {
-		utils.Error("%v", err)
+	res := utils.ResolveIcon(GlobalOption.Url)
+	if res.Err != nil {
+		utils.Error("%v", res.Err)
 		return
 	}
-	utils.Info("icon_url: %s\nfofa: icon_hash=\"%s\"\nhunter: web.icon=\"%s\"\nbase64 (len=%d): %s\n", iconURL, fofa, hunter, len(iconB64), iconB64)
+	product := res.Product
+	if product == "" {
+		product = "-"
+	}
+	utils.Info("icon_url: %s\nfofa: icon_hash=\"%s\"\nhunter: web.icon=\"%s\"\nproduct: %s\nbase64 (len=%d): %s\n", res.IconURL, res.Fofa, res.Hunter, product, len(res.Base64), res.Base64)
+}
+
+// runBatch resolves every target's favicon to a hash and, via icon.json, a product name.
+func (o *IconOptions) runBatch(targets []string) {
+	threads := viper.GetInt("icon-threads")
+	if threads <= 0 {
+		threads = 20
+	}
+	results := make([]utils.IconResult, len(targets))
+	sem := make(chan struct{}, threads)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, target string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = utils.ResolveIcon(target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	table := prettytable.NewWriter()
+	table.SetOutputMirror(os.Stdout)
+	table.AppendHeader(prettytable.Row{"Url", "Icon URL", "FOFA Hash", "Hunter Hash", "Product"})
+	table.SetStyle(prettytable.StyleRounded)
+	table.SetColumnConfigs([]prettytable.ColumnConfig{
+		{Number: 1, WidthMax: 64},
+		{Number: 2, WidthMax: 64},
+	})
+	identified := 0
+	for _, res := range results {
+		if res.Err != nil {
+			utils.Debug("icon %s: %v", res.Target, res.Err)
+			continue
+		}
+		product := res.Product
+		if product == "" {
+			product = "-"
+		} else {
+			identified++
+		}
+		utils.AddDataToTable(table, []string{res.Target, res.IconURL, res.Fofa, res.Hunter, product})
+	}
+	if table.Length() > 0 {
+		table.Render()
+	}
+	utils.Info("icon: %d target(s), %d identified by icon hash", len(targets), identified)
 }
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/godspeedcurry/godscan/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type LocalOptions struct {
+	Paths    []string
+	MaxBytes int64
+}
+
+var localOptions LocalOptions
+
+func init() {
+	localCmd := &cobra.Command{
+		Use:     "local <path>...",
+		Aliases: []string{"lc"},
+		Short:   "Scan local files, directories, zip/tar.gz archives and .hprof heap dumps for APIs, secrets and source maps (Aliases: lc)",
+		Run: func(cmd *cobra.Command, args []string) {
+			localOptions.Paths = args
+			if err := localOptions.validateOptions(); err != nil {
+				utils.Error("%v", err)
+				cmd.Help()
+				return
+			}
+			localOptions.run()
+		},
+	}
+	localCmd.Flags().Int64Var(&localOptions.MaxBytes, "max-bytes", utils.DefaultLocalMaxBytes, "skip files (and archive members) larger than this")
+	rootCmd.AddCommand(localCmd)
+}
+
+func (o *LocalOptions) validateOptions() error {
+	if len(o.Paths) == 0 {
+		return fmt.Errorf("please give a file, directory or archive to scan")
+	}
+	return nil
+}
+
+func (o *LocalOptions) run() {
+	db, err := utils.InitSpiderDB("spider.db")
+	if err != nil {
+		utils.Error("failed to init spider.db: %v", err)
+		return
+	}
+	utils.SetSpiderDB(db)
+	defer db.Close()
+
+	outDir := viper.GetString("output-dir")
+	if outDir == "" {
+		outDir = "output"
+	}
+	for _, p := range o.Paths {
+		name := strings.TrimSuffix(filepath.Base(filepath.Clean(p)), string(os.PathSeparator))
+		directory := fmt.Sprintf("%s/%s/local_%s/spider/", outDir, time.Now().Format("2006-01-02"), name)
+		stats, err := utils.ScanLocal(p, directory, o.MaxBytes, db)
+		if err != nil {
+			utils.Error("local %s: %v", p, err)
+			continue
+		}
+		utils.Success("local %s: %s", stats.RootURL, stats)
+	}
+	utils.Info("Data persisted to spider.db (run `godscan report` to view)")
+}
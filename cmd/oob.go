@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/godspeedcurry/godscan/utils"
+)
+
+type OOBOptions struct {
+	Domain string
+	HTTP   string
+	DNS    string
+	IP     string
+	Secret string
+}
+
+var oobOptions OOBOptions
+
+func init() {
+	oobCmd := newCommandWithAliases("oob", "Run a standalone HTTP+DNS callback listener (e.g. on a VPS) for poc --oob-server", []string{"listen"}, &oobOptions)
+	oobCmd.PersistentFlags().StringVar(&oobOptions.Domain, "domain", "", "domain delegated (NS) to this host, callbacks use <token>.<domain>")
+	oobCmd.PersistentFlags().StringVar(&oobOptions.HTTP, "http", ":80", "http listen address, empty to disable")
+	oobCmd.PersistentFlags().StringVar(&oobOptions.DNS, "dns", ":53", "dns (udp) listen address, empty to disable; needs --domain")
+	oobCmd.PersistentFlags().StringVar(&oobOptions.IP, "ip", "", "public ip of this host, answered to A queries under --domain")
+	oobCmd.PersistentFlags().StringVar(&oobOptions.Secret, "secret", "", "secret clients poll hits with (poc --oob-secret)")
+	rootCmd.AddCommand(oobCmd)
+}
+
+func (o *OOBOptions) validateOptions() error {
+	if o.Secret == "" {
+		return fmt.Errorf("please give --secret")
+	}
+	if o.Domain == "" {
+		o.DNS = ""
+	}
+	if o.HTTP == "" && o.DNS == "" {
+		return fmt.Errorf("nothing to listen on")
+	}
+	return nil
+}
+
+func (o *OOBOptions) run() {
+	l := &utils.OOBListener{Domain: o.Domain, IP: o.IP, Secret: o.Secret}
+	if err := l.Start(o.HTTP, o.DNS); err != nil {
+		utils.Error("start oob listener failed: %v", err)
+		return
+	}
+	defer l.Close()
+	utils.Success("oob listener: http=%s dns=%s domain=%s", l.HTTPAddr(), l.DNSAddr(), l.Domain)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+}
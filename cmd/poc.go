@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cheggaaa/pb/v3"
+	"github.com/fatih/color"
+	"github.com/godspeedcurry/godscan/common"
+	"github.com/godspeedcurry/godscan/utils"
+	prettytable "github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/viper"
+)
+
+type PocOptions struct {
+	common.PocInfo
+	Tags       string
+	Severity   string
+	FromSpider bool
+	List       bool
+
+	// OOB callbacks: an embedded listener (OOBHTTP/OOBDNS) or a remote `godscan oob` (OOBServer)
+	OOBHTTP   string
+	OOBDNS    string
+	OOBIP     string
+	OOBURL    string
+	OOBServer string
+	OOBSecret string
+	OOBWait   int
+}
+
+var pocOptions PocOptions
+
+func init() {
+	pocCmd := newCommandWithAliases("poc", "Run nuclei-style YAML templates (built-in + --poc-dir) against -u/-f targets or spider.db", []string{"vuln"}, &pocOptions)
+	pocCmd.PersistentFlags().StringVar(&pocOptions.PocDir, "poc-dir", "", "extra template file or directory of *.yaml, comma separated; same id overrides built-in")
+	pocCmd.PersistentFlags().StringVar(&pocOptions.PocName, "poc-name", "", "only run templates whose id or name contains one of these, comma separated")
+	pocCmd.PersistentFlags().StringVar(&pocOptions.Tags, "tags", "", "only run templates carrying one of these tags, comma separated")
+	pocCmd.PersistentFlags().StringVar(&pocOptions.Severity, "severity", "", "only run templates of these severities, comma separated")
+	pocCmd.PersistentFlags().StringVar(&pocOptions.Cookie, "cookie", "", "Cookie header sent with every template request")
+	pocCmd.PersistentFlags().IntVarP(&pocOptions.Num, "threads", "t", 20, "Number of concurrent template runs")
+	pocCmd.PersistentFlags().BoolVar(&pocOptions.FromSpider, "from-spider", false, "also target every url stored in spider.db by spider")
+	pocCmd.PersistentFlags().BoolVar(&pocOptions.List, "list", false, "list the selected templates and exit")
+	pocCmd.PersistentFlags().StringVar(&pocOptions.CeyeDomain, "oob-domain", "", "domain delegated (NS) to the oob dns listener, callbacks use <token>.<domain>")
+	pocCmd.PersistentFlags().StringVar(&pocOptions.OOBHTTP, "oob-http", "", "start an embedded oob http listener on this address, e.g. :8088")
+	pocCmd.PersistentFlags().StringVar(&pocOptions.OOBDNS, "oob-dns", "", "start an embedded oob dns listener (udp) on this address, e.g. :53; needs --oob-domain")
+	pocCmd.PersistentFlags().StringVar(&pocOptions.OOBIP, "oob-ip", "", "address targets can reach this host on, answered to dns queries and used in http callbacks")
+	pocCmd.PersistentFlags().StringVar(&pocOptions.OOBURL, "oob-url", "", "public base url of the oob http listener, default http://<oob-ip>:<port>")
+	pocCmd.PersistentFlags().StringVar(&pocOptions.OOBServer, "oob-server", "", "poll a remote godscan oob listener instead, e.g. http://vps:80")
+	pocCmd.PersistentFlags().StringVar(&pocOptions.OOBSecret, "oob-secret", "", "secret of the remote oob listener")
+	pocCmd.PersistentFlags().IntVar(&pocOptions.OOBWait, "oob-wait", 10, "seconds to wait for late oob callbacks after the last request")
+	rootCmd.AddCommand(pocCmd)
+}
+
+func (o *PocOptions) validateOptions() error {
+	if !o.List && !o.FromSpider && GlobalOption.Url == "" && GlobalOption.UrlFile == "" {
+		return fmt.Errorf("please give target url, url file or --from-spider")
+	}
+	if o.OOBServer != "" && o.OOBSecret == "" {
+		return fmt.Errorf("--oob-server needs --oob-secret")
+	}
+	return nil
+}
+
+// oobClient sets up the callback side of OOB templates; nil when no listener was asked for.
+func (o *PocOptions) oobClient(db *sql.DB) (*utils.OOBClient, func(), error) {
+	if o.OOBServer != "" {
+		return utils.NewRemoteOOBClient(o.OOBServer, o.OOBSecret, o.CeyeDomain, db), func() {}, nil
+	}
+	if o.OOBHTTP == "" && o.OOBDNS == "" {
+		return nil, func() {}, nil
+	}
+	l := &utils.OOBListener{Domain: o.CeyeDomain, IP: o.OOBIP}
+	if err := l.Start(o.OOBHTTP, o.OOBDNS); err != nil {
+		return nil, nil, err
+	}
+	base := o.OOBURL
+	if base == "" && l.HTTPAddr() != "" {
+		_, port, _ := net.SplitHostPort(l.HTTPAddr())
+		ip := o.OOBIP
+		if ip == "" {
+			ip = "127.0.0.1"
+			utils.Warning("--oob-ip not set, http callbacks point to %s", ip)
+		}
+		base = "http://" + net.JoinHostPort(ip, port)
+	}
+	utils.Info("oob listener: http=%s dns=%s domain=%s", l.HTTPAddr(), l.DNSAddr(), l.Domain)
+	return utils.NewOOBClient(l, base, db), l.Close, nil
+}
+
+func (o *PocOptions) templates() ([]*utils.PocTemplate, error) {
+	all, err := utils.LoadPocTemplates(splitList(o.PocDir))
+	if err != nil {
+		return nil, err
+	}
+	return utils.FilterPocTemplates(all, splitList(o.PocName), splitList(o.Tags), splitList(o.Severity)), nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+type pocTask struct {
+	target   string
+	template *utils.PocTemplate
+}
+
+func (o *PocOptions) run() {
+	templates, err := o.templates()
+	if err != nil {
+		utils.Error("load poc templates failed: %v", err)
+		return
+	}
+	if o.List {
+		printPocTemplates(templates)
+		return
+	}
+	if len(templates) == 0 {
+		utils.Warning("No poc template selected")
+		return
+	}
+	utils.InitHttp()
+	logProxyUsage()
+	db, err := utils.InitSpiderDB("spider.db")
+	if err != nil {
+		utils.Error("failed to init spider.db: %v", err)
+		return
+	}
+	utils.SetSpiderDB(db)
+	defer db.Close()
+	oob, closeOOB, err := o.oobClient(db)
+	if err != nil {
+		utils.Error("start oob listener failed: %v", err)
+		return
+	}
+	defer closeOOB()
+	utils.SetPocOOB(oob)
+
+	var targets []string
+	if GlobalOption.Url != "" || GlobalOption.UrlFile != "" {
+		targets = GetTargetList()
+	}
+	if o.FromSpider {
+		rows, err := utils.LoadSpiderSummaries(db)
+		if err != nil {
+			utils.Error("load spider_summary failed: %v", err)
+			return
+		}
+		for _, r := range rows {
+			targets = append(targets, r.Url)
+		}
+	}
+	targets = utils.RemoveDuplicatesString(targets)
+	if len(targets) == 0 {
+		utils.Warning("No targets to run")
+		return
+	}
+	utils.Info("poc: %d template(s) x %d target(s)", len(templates), len(targets))
+
+	tasks := make([]pocTask, 0, len(targets)*len(templates))
+	for _, t := range targets {
+		for _, tpl := range templates {
+			tasks = append(tasks, pocTask{target: t, template: tpl})
+		}
+	}
+	findings := o.execute(tasks)
+	if err := utils.SaveFindings(db, findings); err != nil {
+		utils.Error("save findings failed: %v", err)
+	}
+	if oob != nil {
+		findings = append(findings, o.waitOOB(oob)...)
+	}
+	if len(findings) == 0 {
+		utils.Info("poc: no template matched")
+		return
+	}
+	renderPocFindings(findings)
+	utils.Success("poc: %d finding(s) saved to spider.db (findings)", len(findings))
+}
+
+func (o *PocOptions) execute(tasks []pocTask) []utils.Finding {
+	threads := o.Num
+	if threads <= 0 {
+		threads = 20
+	}
+	var bar *pb.ProgressBar
+	if !viper.GetBool("quiet") {
+		bar = pb.StartNew(len(tasks))
+		bar.SetMaxWidth(90)
+		bar.Set("prefix", color.CyanString("poc"))
+		bar.SetTemplateString(`{{string . "prefix"}} {{counters . }} {{bar . "|" "█" "█" "░" "|"}} {{percent . }} | {{etime . }}`)
+		bar.SetRefreshRate(200 * time.Millisecond)
+	}
+	taskCh := make(chan pocTask)
+	resCh := make(chan []utils.Finding, threads)
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range taskCh {
+				res, err := utils.RunPocTemplate(t.template, t.target, o.Cookie)
+				if bar != nil {
+					bar.Increment()
+				}
+				if err != nil {
+					utils.Debug("poc %s %s: %v", t.template.ID, t.target, err)
+				}
+				if len(res) > 0 {
+					resCh <- res
+				}
+			}
+		}()
+	}
+	go func() {
+		for _, t := range tasks {
+			taskCh <- t
+		}
+		close(taskCh)
+		wg.Wait()
+		close(resCh)
+	}()
+	var out []utils.Finding
+	for r := range resCh {
+		out = append(out, r...)
+	}
+	if bar != nil {
+		bar.Finish()
+	}
+	return out
+}
+
+// waitOOB polls for callbacks for OOBWait seconds; the client stores correlated hits itself.
+func (o *PocOptions) waitOOB(oob *utils.OOBClient) []utils.Finding {
+	utils.Info("poc: waiting %ds for oob callbacks", o.OOBWait)
+	var out []utils.Finding
+	deadline := time.Now().Add(time.Duration(o.OOBWait) * time.Second)
+	for {
+		res, err := oob.Poll()
+		if err != nil {
+			utils.Warning("oob poll failed: %v", err)
+		}
+		out = append(out, res...)
+		if !time.Now().Before(deadline) {
+			return out
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func printPocTemplates(templates []*utils.PocTemplate) {
+	table := prettytable.NewWriter()
+	table.SetOutputMirror(os.Stdout)
+	table.AppendHeader(prettytable.Row{"ID", "Name", "Severity", "Tags", "Source"})
+	table.SetStyle(prettytable.StyleRounded)
+	for _, t := range templates {
+		table.AppendRow(prettytable.Row{t.ID, t.Info.Name, t.Info.Severity, t.Info.Tags, t.Source})
+	}
+	table.Render()
+}
+
+func renderPocFindings(findings []utils.Finding) {
+	table := prettytable.NewWriter()
+	table.SetOutputMirror(os.Stdout)
+	table.AppendHeader(prettytable.Row{"Severity", "Template", "URL", "Evidence"})
+	table.SetStyle(prettytable.StyleRounded)
+	table.SetColumnConfigs([]prettytable.ColumnConfig{
+		{Number: 4, WidthMax: 80},
+	})
+	for _, f := range findings {
+		table.AppendRow(prettytable.Row{f.Severity, f.TemplateID, f.URL, f.Evidence})
+	}
+	table.Render()
+}
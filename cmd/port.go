@@ -4,7 +4,9 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"database/sql"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 
@@ -25,6 +27,8 @@ type PortOptions struct {
 	scanRarity      int
 	Threads         int
 	DialTimeout     int
+	ChainSpider     bool
+	SpiderDepth     int
 }
 
 var (
@@ -56,6 +60,8 @@ func init() {
 
 	ipCmd.PersistentFlags().IntVarP(&portOptions.Threads, "threads", "t", 1000, "Number of threads to use")
 	ipCmd.PersistentFlags().IntVarP(&portOptions.DialTimeout, "port-dial-timeout", "", 2, "TCP dial timeout in seconds")
+	ipCmd.PersistentFlags().BoolVar(&portOptions.ChainSpider, "chain-spider", false, "spider every identified http/https service after the scan")
+	ipCmd.PersistentFlags().IntVar(&portOptions.SpiderDepth, "spider-depth", 2, "spider depth used by --chain-spider")
 
 	viper.BindPFlag("host", ipCmd.PersistentFlags().Lookup("host"))
 	viper.SetDefault("host", "")
@@ -95,6 +101,15 @@ func (o *PortOptions) run() {
 		utils.Info("Please provide ip range or ip range file")
 		return
 	}
+	db, err := utils.InitSpiderDB("spider.db")
+	if err != nil {
+		utils.Error("failed to init spider.db: %v", err)
+		return
+	}
+	utils.SetSpiderDB(db)
+	defer db.Close()
+
+	var services []utils.ServiceResultRow
 	if portOptions.TopPorts != "" {
 		if strings.Contains(portOptions.TopPorts, "-") {
 			TopRangeBounds := strings.Split(portOptions.TopPorts, "-")
@@ -115,16 +130,49 @@ func (o *PortOptions) run() {
 				utils.Info("We do not have more than top 20000 ports, please choose a smaller number, or just scan all ports use `-p 0-65535`")
 				return
 			}
-			utils.PortScan(portOptions.IpRange, strings.Join(strings.Split(common.AllPorts, ",")[startPort:endPort], ","))
+			services = utils.PortScan(portOptions.IpRange, strings.Join(strings.Split(common.AllPorts, ",")[startPort:endPort], ","))
 		} else if startPort, err := strconv.Atoi(portOptions.TopPorts); err != nil || startPort > 20000 {
 			utils.Info("We do not have more than top 20000 ports, please choose a smaller number, or just scan all ports use `-p 0-65535`")
 			return
 		} else {
-			utils.PortScan(portOptions.IpRange, strings.Join(strings.Split(common.AllPorts, ",")[0:startPort], ","))
+			services = utils.PortScan(portOptions.IpRange, strings.Join(strings.Split(common.AllPorts, ",")[0:startPort], ","))
 		}
 
 	} else {
-		utils.PortScan(portOptions.IpRange, portOptions.PortRange)
+		services = utils.PortScan(portOptions.IpRange, portOptions.PortRange)
 	}
 
+	if portOptions.ChainSpider {
+		chainSpider(services, portOptions.SpiderDepth, db)
+	}
+}
+
+// chainSpider queues identified web services into the spider pipeline and links results back to the port record.
+func chainSpider(services []utils.ServiceResultRow, depth int, db *sql.DB) {
+	origins := make(map[string]utils.ServiceResultRow)
+	var targets []string
+	for _, svc := range services {
+		u := utils.WebServiceURL(svc)
+		if u == "" {
+			continue
+		}
+		if _, ok := origins[u]; ok {
+			continue
+		}
+		origins[u] = svc
+		targets = append(targets, u)
+	}
+	if len(targets) == 0 {
+		utils.Info("chain-spider: no http/https service identified")
+		return
+	}
+	utils.Info("chain-spider: %d web service(s) queued, depth=%d", len(targets), depth)
+	utils.InitHttp()
+	outDir := viper.GetString("output-dir")
+	if outDir == "" {
+		outDir = "output"
+	}
+	_ = os.MkdirAll(outDir, 0o755)
+	crawlTargets(targets, depth, db, outDir, "port-spider", origins)
+	autoExportReport(db, nil)
 }
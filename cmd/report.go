@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	prettytable "github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var reportLLMOpts LLMCLIOptions
@@ -28,6 +30,12 @@ var reportCmd = &cobra.Command{
 		}
 		defer db.Close()
 
+		if format, _ := cmd.Flags().GetString("export-api"); format != "" {
+			prefix, _ := cmd.Flags().GetString("api")
+			exportAPIs(db, format, prefix)
+			return
+		}
+
 		llmCfg := promptLLMIfNeeded(cmd, &reportLLMOpts)
 		if llmCfg != nil {
 			utils.Info("LLM provider=%s model=%s", llmCfg.Provider, llmCfg.Model)
@@ -35,6 +43,11 @@ var reportCmd = &cobra.Command{
 		printSummary(db)
 		printAPICounts(db)
 		printSensitiveCounts(db)
+		printPortServices(db)
+		printAPIProbes(db)
+		printActuatorFindings(db)
+		printFindings(db)
+		printBruteResults(db)
 		if htmlPath == "" {
 			now := time.Now()
 			htmlPath = fmt.Sprintf("output/report-%04d-%02d-%02d.html", now.Year(), now.Month(), now.Day())
@@ -51,9 +64,25 @@ var reportCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(reportCmd)
 	reportCmd.Flags().String("html", "", "Export spider.db to a standalone HTML report (default output/report-YYYY-MM-DD.html)")
+	reportCmd.Flags().String("export-api", "", "Export discovered APIs per root url as "+strings.Join(utils.APIExportFormats, "|")+" into <output-dir>/api-export")
+	reportCmd.Flags().String("api", "", "API prefix prepended to exported paths that do not carry it yet")
 	addLLMFlags(reportCmd, &reportLLMOpts)
 }
 
+func exportAPIs(db *sql.DB, format, prefix string) {
+	dir := filepath.Join(viper.GetString("output-dir"), "api-export")
+	files, err := utils.ExportAPIs(db, format, dir, prefix)
+	if err != nil {
+		utils.Error("export api failed: %v", err)
+		return
+	}
+	if len(files) == 0 {
+		utils.Warning("no api paths or endpoints in spider.db, nothing exported")
+		return
+	}
+	utils.Success("%s export: %d root(s) written to %s", format, len(files), dir)
+}
+
 func promptLLMIfNeeded(cmd *cobra.Command, opts *LLMCLIOptions) *utils.LLMConfig {
 	if cmd.Flags().NFlag() > 0 || opts == nil || opts.Profile != "" || opts.APIKey != "" || opts.DryRun {
 		return opts.ToConfig()
@@ -147,10 +176,10 @@ func printSummary(db *sql.DB) {
 	}
 	table := prettytable.NewWriter()
 	table.SetOutputMirror(os.Stdout)
-	table.AppendHeader(prettytable.Row{"Url", "IconHash", "API Count", "URLs Found", "CDN URLs", "CDN Hosts", "Status", "Save Dir"})
+	table.AppendHeader(prettytable.Row{"Url", "IconHash", "Icon Finger", "API Count", "URLs Found", "CDN URLs", "CDN Hosts", "Status", "Save Dir"})
 	table.SetStyle(prettytable.StyleRounded)
 	table.SetColumnConfigs([]prettytable.ColumnConfig{
-		{Number: 6, Transformer: func(val interface{}) string {
+		{Number: 7, Transformer: func(val interface{}) string {
 			s := strings.TrimSpace(fmt.Sprint(val))
 			if s == "" {
 				return s
@@ -163,7 +192,11 @@ func printSummary(db *sql.DB) {
 		if icon == "" {
 			icon = "-"
 		}
-		table.AppendRow(prettytable.Row{r.Url, icon, r.ApiCount, r.UrlCount, r.CDNCount, r.CDNHosts, r.Status, r.SaveDir})
+		iconFinger := r.IconFinger
+		if iconFinger == "" {
+			iconFinger = "-"
+		}
+		table.AppendRow(prettytable.Row{r.Url, icon, iconFinger, r.ApiCount, r.UrlCount, r.CDNCount, r.CDNHosts, r.Status, r.SaveDir})
 	}
 	table.Render()
 }
@@ -203,3 +236,101 @@ func printSensitiveCounts(db *sql.DB) {
 	}
 	table.Render()
 }
+
+func printAPIProbes(db *sql.DB) {
+	rows, err := utils.LoadAPIProbes(db, true)
+	if err != nil {
+		utils.Error("load api_probes failed: %v", err)
+		return
+	}
+	if len(rows) == 0 {
+		return
+	}
+	renderAPIProbeTable(rows)
+}
+
+func printActuatorFindings(db *sql.DB) {
+	rows, err := utils.LoadActuatorFindings(db)
+	if err != nil {
+		utils.Error("load actuator_findings failed: %v", err)
+		return
+	}
+	if len(rows) == 0 {
+		return
+	}
+	table := prettytable.NewWriter()
+	table.SetOutputMirror(os.Stdout)
+	table.AppendHeader(prettytable.Row{"Actuator", "Version", "Endpoints", "Mappings", "Props (unmasked)", "Heapdump", "Jolokia", "Gateway"})
+	table.SetStyle(prettytable.StyleRounded)
+	yes := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return ""
+	}
+	for _, r := range rows {
+		table.AppendRow(prettytable.Row{r.BaseURL, r.Version, strings.Join(r.Endpoints, ","), r.Mappings, fmt.Sprintf("%d (%d)", r.Properties, r.Unmasked), yes(r.Heapdump), yes(r.Jolokia), yes(r.GatewayRoutes)})
+	}
+	table.Render()
+}
+
+func printFindings(db *sql.DB) {
+	rows, err := utils.LoadFindings(db)
+	if err != nil {
+		utils.Error("load findings failed: %v", err)
+		return
+	}
+	if len(rows) == 0 {
+		return
+	}
+	table := prettytable.NewWriter()
+	table.SetOutputMirror(os.Stdout)
+	table.AppendHeader(prettytable.Row{"Severity", "Module", "Finding", "URL", "Detail"})
+	table.SetStyle(prettytable.StyleRounded)
+	table.SetColumnConfigs([]prettytable.ColumnConfig{
+		{Number: 5, WidthMax: 80},
+	})
+	for _, r := range rows {
+		module, detail := r.Module, r.Detail
+		if r.TemplateID != "" {
+			module, detail = r.Module+":"+r.TemplateID, r.Evidence
+		}
+		table.AppendRow(prettytable.Row{r.Severity, module, r.Title, r.URL, detail})
+	}
+	table.Render()
+}
+
+func printBruteResults(db *sql.DB) {
+	rows, err := utils.LoadBruteResults(db)
+	if err != nil {
+		utils.Error("load brute_results failed: %v", err)
+		return
+	}
+	if len(rows) == 0 {
+		return
+	}
+	renderBruteResults(rows)
+}
+
+func printPortServices(db *sql.DB) {
+	rows, err := utils.LoadPortServices(db)
+	if err != nil {
+		utils.Error("load service_results failed: %v", err)
+		return
+	}
+	if len(rows) == 0 {
+		return
+	}
+	table := prettytable.NewWriter()
+	table.SetOutputMirror(os.Stdout)
+	table.AppendHeader(prettytable.Row{"Address", "Service", "Product", "Version", "CPE", "Soft", "Spider URL"})
+	table.SetStyle(prettytable.StyleRounded)
+	for _, r := range rows {
+		soft := ""
+		if r.SoftMatch {
+			soft = "yes"
+		}
+		table.AppendRow(prettytable.Row{fmt.Sprintf("%s:%d/%s", r.IP, r.Port, r.Protocol), r.Service, r.Product, r.Version, r.CPE, soft, r.SpiderURL})
+	}
+	table.Render()
+}
@@ -89,6 +89,18 @@ var rootCmd = &cobra.Command{
   godscan weakpass -k "corp"`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		maybeCheckForUpdate()
+		if paths := viper.GetStringSlice("finger-rules"); len(paths) > 0 {
+			if err := utils.InitFingerRules(paths); err != nil {
+				utils.Error("load finger rules failed: %v", err)
+				os.Exit(1)
+			}
+		}
+		if paths := viper.GetStringSlice("secret-rules"); len(paths) > 0 {
+			if err := utils.InitSecretRules(paths); err != nil {
+				utils.Error("load secret rules failed: %v", err)
+				os.Exit(1)
+			}
+		}
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Help()
@@ -176,7 +188,16 @@ func init() {
 	rootCmd.PersistentFlags().Int("http-timeout", 10, "http client timeout (seconds)")
 	rootCmd.PersistentFlags().Int("conn-per-host", 0, "max connections per host (0=auto)")
 	rootCmd.PersistentFlags().Int("max-body-bytes", 2*1024*1024, "max response body bytes to read")
-
+	rootCmd.PersistentFlags().StringArray("finger-rules", nil, "extra fingerprint pack (ehole.json schema, plus FOFA-style expressions under \"rules\"), file or directory of *.json; repeatable, same Cms overrides built-in")
+	rootCmd.PersistentFlags().StringArray("secret-rules", nil, "extra secret detector rules (JSON, see utils/secrets.json), file or directory of *.json; repeatable, same id overrides built-in")
+	rootCmd.PersistentFlags().Bool("git-dump", false, "rebuild exposed .git repositories into the host output dir and scan the recovered files; triggered by dir hits on .git/HEAD or .git/config and by the spider's .git/config probe of crawled directories")
+	rootCmd.PersistentFlags().Bool("artifact-scan", false, "download heapdump/*.hprof and backup archives (www.zip, site.tar.gz...) found by dirbrute/actuator and grep them for secrets")
+	rootCmd.PersistentFlags().Bool("resume", false, "skip units finished by the previous run (spider/dirbrute/port/exposure checkpoint journal in spider.db)")
+
+	viper.BindPFlag("git-dump", rootCmd.PersistentFlags().Lookup("git-dump"))
+	viper.SetDefault("git-dump", false)
+	viper.BindPFlag("artifact-scan", rootCmd.PersistentFlags().Lookup("artifact-scan"))
+	viper.SetDefault("artifact-scan", false)
 	viper.BindPFlag("json", rootCmd.PersistentFlags().Lookup("json"))
 	viper.SetDefault("json", false)
 	viper.BindPFlag("quiet", rootCmd.PersistentFlags().Lookup("quiet"))
@@ -189,6 +210,12 @@ func init() {
 	viper.SetDefault("conn-per-host", 0)
 	viper.BindPFlag("max-body-bytes", rootCmd.PersistentFlags().Lookup("max-body-bytes"))
 	viper.SetDefault("max-body-bytes", 2*1024*1024)
+	viper.BindPFlag("finger-rules", rootCmd.PersistentFlags().Lookup("finger-rules"))
+	viper.SetDefault("finger-rules", []string{})
+	viper.BindPFlag("secret-rules", rootCmd.PersistentFlags().Lookup("secret-rules"))
+	viper.SetDefault("secret-rules", []string{})
+	viper.BindPFlag("resume", rootCmd.PersistentFlags().Lookup("resume"))
+	viper.SetDefault("resume", false)
 }
 
 func Execute() {
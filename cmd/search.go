@@ -31,7 +31,7 @@ func init() {
 	searchCmd := &cobra.Command{
 		Use:     "grep [pattern]",
 		Aliases: []string{"search"},
-		Short:   "Regex search in spider.db (api/sensitive/map/page)",
+		Short:   "Regex search in spider.db (api/sensitive/map/page/service)",
 		Run: func(cmd *cobra.Command, args []string) {
 			if searchOptions.Pattern == "" && len(args) > 0 {
 				searchOptions.Pattern = args[0]
@@ -70,13 +70,21 @@ func runSearch(opt SearchOptions) error {
 		return err
 	}
 
-	cfg := searchTables{api: true, sensitive: true, maps: true, pages: true}
+	cfg := searchTables{api: true, sensitive: true, maps: true, pages: true, services: true}
 	apiRows, sensRows, mapRows, pageRows, err := loadSearchRows(db, opt.UrlLike, cfg)
 	if err != nil {
 		return err
 	}
+	var svcRows []utils.ServiceResultRow
+	if cfg.services {
+		svcRows, err = queryServices(db, opt.UrlLike)
+		if err != nil {
+			return err
+		}
+	}
 
 	hits := collectHits(re, apiRows, sensRows, mapRows, pageRows)
+	hits = append(hits, collectServiceHits(re, svcRows)...)
 	if len(hits) == 0 {
 		utils.Info("No matches (pattern=%q, db=%s)", opt.Pattern, opt.DbPath)
 		return nil
@@ -116,6 +124,7 @@ type searchTables struct {
 	sensitive bool
 	maps      bool
 	pages     bool
+	services  bool
 }
 
 func validateSearchOpts(opt SearchOptions) error {
@@ -246,6 +255,31 @@ func collectHits(re *regexp.Regexp, apiRows []apiRow, sensRows []utils.Sensitive
 	return hits
 }
 
+// collectServiceHits matches port/service rows; the source is ip:port.
+func collectServiceHits(re *regexp.Regexp, rows []utils.ServiceResultRow) []hit {
+	var hits []hit
+	for _, r := range rows {
+		src := fmt.Sprintf("%s://%s:%d", r.Protocol, r.IP, r.Port)
+		fields := []struct {
+			name  string
+			value string
+		}{
+			{"service.name", r.Service},
+			{"service.product", strings.TrimSpace(r.Product + " " + r.Version)},
+			{"service.info", r.Info},
+			{"service.cpe", r.CPE},
+			{"service.banner", r.Banner},
+		}
+		for _, f := range fields {
+			if f.value != "" && re.MatchString(f.value) {
+				hits = append(hits, hit{Source: src, Field: f.name, Value: f.value})
+			}
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Source < hits[j].Source })
+	return hits
+}
+
 func limitHits(h []hit, max int) []hit {
 	if max <= 0 || max > len(h) {
 		return h
@@ -386,3 +420,17 @@ func queryPages(db *sql.DB, like string) ([]pageRow, error) {
 	}
 	return out, rows.Err()
 }
+
+func queryServices(db *sql.DB, like string) ([]utils.ServiceResultRow, error) {
+	rows, err := utils.LoadPortServices(db)
+	if err != nil || like == "" {
+		return rows, err
+	}
+	var out []utils.ServiceResultRow
+	for _, r := range rows {
+		if strings.Contains(fmt.Sprintf("%s:%d", r.IP, r.Port), like) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
@@ -29,6 +29,8 @@ type SpiderOptions struct {
 	ApiPrefix string
 	Threads   int
 	Progress  bool
+
+	SourceMapDownload bool
 }
 
 var (
@@ -45,6 +47,7 @@ func init() {
 	spiderCmd.PersistentFlags().StringVarP(&spiderOptions.ApiPrefix, "api", "", "", "your api prefix")
 	spiderCmd.PersistentFlags().IntVarP(&spiderOptions.Threads, "threads", "t", 20, "Number of concurrent targets")
 	spiderCmd.PersistentFlags().BoolVar(&spiderOptions.Progress, "progress-log", true, "print progress logs and per-target start notices")
+	spiderCmd.PersistentFlags().BoolVar(&spiderOptions.SourceMapDownload, "sourcemap-download", false, "download found source maps, rebuild original sources and scan them for APIs/secrets")
 
 	viper.BindPFlag("ApiPrefix", spiderCmd.PersistentFlags().Lookup("api"))
 	viper.SetDefault("ApiPrefix", "")
@@ -52,6 +55,9 @@ func init() {
 	viper.SetDefault("spider-threads", 20)
 	viper.BindPFlag("spider-progress-log", spiderCmd.PersistentFlags().Lookup("progress-log"))
 	viper.SetDefault("spider-progress-log", false)
+	viper.BindPFlag("spider-sourcemap-download", spiderCmd.PersistentFlags().Lookup("sourcemap-download"))
+	viper.SetDefault("spider-sourcemap-download", false)
+	viper.SetDefault("sourcemap-max-bytes", 32*1024*1024)
 	viper.SetDefault("spider-timeout-per-host", 90)
 	viper.SetDefault("spider-graph-max-edges", 5000)
 	viper.SetDefault("spider-max-urls-per-host", 2000)
@@ -66,7 +72,7 @@ type spiderContext struct {
 	doneCh   chan struct{}
 }
 
-func spawnSpiderWorkers(targets []string, depth int, db *sql.DB, progressLog bool) (*sync.WaitGroup, chan utils.SpiderSummary, *spiderContext) {
+func spawnSpiderWorkers(targets []string, depth int, db *sql.DB, progressLog bool, ck *utils.Checkpoint) (*sync.WaitGroup, chan utils.SpiderSummary, *spiderContext) {
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, maxSpiderGoroutines())
 	results := make(chan utils.SpiderSummary, len(targets))
@@ -85,6 +91,9 @@ func spawnSpiderWorkers(targets []string, depth int, db *sql.DB, progressLog boo
 				utils.Info("spider start: %s (started %d, remaining ~%d)", url, curStart, remaining)
 			}
 			res := utils.FingerSummary(url, depth, db)
+			if res.Err == nil {
+				ck.Mark(url, "", "")
+			}
 			if progressLog {
 				curFinished := atomic.AddInt32(&ctx.finished, 1)
 				remaining := int32(len(targets)) - curFinished
@@ -128,8 +137,9 @@ func heartbeat(progressLog bool, ctx *spiderContext, total int) {
 	}()
 }
 
-func collectSpiderResults(results chan utils.SpiderSummary, total int, progressLog bool) (prettytable.Writer, []utils.SpiderSummary, int, int) {
+func collectSpiderResults(results chan utils.SpiderSummary, total int, progressLog bool, origins map[string]utils.ServiceResultRow) (prettytable.Writer, []utils.SpiderSummary, int, int) {
 	collector := newSpiderCollector(total, progressLog)
+	collector.origins = origins
 	for res := range results {
 		collector.updateProgress()
 		collector.processResult(res)
@@ -154,6 +164,7 @@ type spiderCollector struct {
 	progressLog       bool
 	progressMilestone int
 	total             int
+	origins           map[string]utils.ServiceResultRow
 	mu                sync.Mutex
 }
 
@@ -168,14 +179,15 @@ func cdnHostColor(val interface{}) string {
 func newSpiderCollector(total int, progressLog bool) *spiderCollector {
 	table := prettytable.NewWriter()
 	table.SetOutputMirror(os.Stdout)
-	table.AppendHeader(prettytable.Row{"Url", "IconHash", "API Count", "CDN URLs", "CDN Hosts"})
+	table.AppendHeader(prettytable.Row{"Url", "IconHash", "Icon Finger", "API Count", "CDN URLs", "CDN Hosts"})
 	table.SetStyle(prettytable.StyleRounded)
 	table.SetColumnConfigs([]prettytable.ColumnConfig{
 		{Number: 1, WidthMax: 64},
 		{Number: 2, WidthMax: 48},
-		{Number: 3, WidthMax: 10},
+		{Number: 3, WidthMax: 24},
 		{Number: 4, WidthMax: 10},
-		{Number: 5, WidthMax: 36, Transformer: cdnHostColor},
+		{Number: 5, WidthMax: 10},
+		{Number: 6, WidthMax: 36, Transformer: cdnHostColor},
 	})
 
 	var bar *pb.ProgressBar
@@ -226,9 +238,10 @@ func (c *spiderCollector) processResult(res utils.SpiderSummary) {
 
 	c.mu.Lock()
 	c.summaries = append(c.summaries, res)
-	if err := utils.SaveSpiderSummary(utils.GetSpiderDB(), utils.SpiderRecord{
+	rec := utils.SpiderRecord{
 		Url:        res.URL,
 		IconHash:   res.IconHash,
+		IconFinger: res.IconFinger,
 		IconBase64: res.IconBase64,
 		ApiCount:   res.ApiCount,
 		UrlCount:   res.UrlCount,
@@ -236,7 +249,12 @@ func (c *spiderCollector) processResult(res utils.SpiderSummary) {
 		CDNHosts:   res.CDNHosts,
 		SaveDir:    res.SaveDir,
 		Status:     res.Status,
-	}); err != nil {
+	}
+	if origin, ok := c.origins[res.URL]; ok {
+		rec.ServiceIP = origin.IP
+		rec.ServicePort = origin.Port
+	}
+	if err := utils.SaveSpiderSummary(utils.GetSpiderDB(), rec); err != nil {
 		utils.Error("db save failed: %v", err)
 	}
 	c.mu.Unlock()
@@ -245,11 +263,15 @@ func (c *spiderCollector) processResult(res utils.SpiderSummary) {
 	if icon == "" {
 		icon = "-"
 	}
+	iconFinger := res.IconFinger
+	if iconFinger == "" {
+		iconFinger = "-"
+	}
 	cdnHosts := res.CDNHosts
 	if cdnHosts == "" {
 		cdnHosts = "-"
 	}
-	row := []string{res.URL, icon, fmt.Sprintf("%d", res.ApiCount), fmt.Sprintf("%d", res.CDNCount), cdnHosts}
+	row := []string{res.URL, icon, iconFinger, fmt.Sprintf("%d", res.ApiCount), fmt.Sprintf("%d", res.CDNCount), cdnHosts}
 	utils.AddDataToTable(c.table, row)
 }
 
@@ -266,6 +288,40 @@ func (o *SpiderOptions) validateOptions() error {
 	return nil
 }
 
+// crawlTargets runs the spider pipeline over targets and persists the results.
+// scope names the checkpoint journal; origins optionally maps a target url to the port scan record it came from.
+func crawlTargets(targets []string, depth int, db *sql.DB, outDir, scope string, origins map[string]utils.ServiceResultRow) (int, int) {
+	ck := utils.NewCheckpoint(db, scope, viper.GetBool("resume"))
+	defer ck.Close()
+	pending := make([]string, 0, len(targets))
+	for _, t := range targets {
+		if !ck.Done(t, "") {
+			pending = append(pending, t)
+		}
+	}
+	if skipped := ck.Skipped(); skipped > 0 {
+		utils.Info("resume: skipped %d finished url(s)", skipped)
+	}
+	targets = pending
+	utils.Info("Total: %d url(s)", len(targets))
+	utils.GetGraphCollector().Reset()
+	utils.GetGraphCollector().SetLimit(viper.GetInt("spider-graph-max-edges"))
+
+	progressLog := viper.GetBool("spider-progress-log")
+	wg, results, ctx := spawnSpiderWorkers(targets, depth, db, progressLog, ck)
+	heartbeat(progressLog, ctx, len(targets))
+	wg.Wait()
+	close(results)
+
+	table, summaries, reachable, findings := collectSpiderResults(results, len(targets), progressLog, origins)
+	close(ctx.doneCh)
+	renderSpiderTable(table)
+	writeSpiderJSONSummary(summaries)
+	writeSpiderGraph(outDir, db)
+	utils.Info("Data persisted to spider.db (run `godscan report` to view)")
+	return reachable, findings
+}
+
 func (o *SpiderOptions) run() {
 	start := time.Now()
 	utils.InitHttp()
@@ -275,9 +331,6 @@ func (o *SpiderOptions) run() {
 	}
 	_ = os.MkdirAll(outDir, 0o755)
 	targets := GetTargetList()
-	utils.Info("Total: %d url(s)", len(targets))
-	utils.GetGraphCollector().Reset()
-	utils.GetGraphCollector().SetLimit(viper.GetInt("spider-graph-max-edges"))
 
 	db, err := utils.InitSpiderDB("spider.db")
 	if err != nil {
@@ -287,18 +340,7 @@ func (o *SpiderOptions) run() {
 	utils.SetSpiderDB(db)
 	defer db.Close()
 
-	progressLog := viper.GetBool("spider-progress-log")
-	wg, results, ctx := spawnSpiderWorkers(targets, o.Depth, db, progressLog)
-	heartbeat(progressLog, ctx, len(targets))
-	wg.Wait()
-	close(results)
-
-	table, summaries, reachable, findings := collectSpiderResults(results, len(targets), progressLog)
-	close(ctx.doneCh)
-	renderSpiderTable(table)
-	writeSpiderJSONSummary(summaries)
-	writeSpiderGraph(outDir, db)
-	utils.Info("Data persisted to spider.db (run `godscan report` to view)")
+	reachable, findings := crawlTargets(targets, o.Depth, db, outDir, "spider", nil)
 	llmCfg := spiderLLMOpts.ToConfig()
 	if llmCfg != nil {
 		utils.Info("LLM Abstract provider=%s model=%s", llmCfg.Provider, llmCfg.Model)
@@ -362,6 +404,7 @@ func writeSpiderJSONSummary(summaries []utils.SpiderSummary) {
 		Keyword     string   `json:"keyword"`
 		SimHash     string   `json:"simhash"`
 		IconHash    string   `json:"icon_hash"`
+		IconFinger  string   `json:"icon_finger"`
 		IconBase64  string   `json:"icon_base64"`
 		ApiCount    int      `json:"api_count"`
 		UrlCount    int      `json:"url_count"`
@@ -390,6 +433,7 @@ func writeSpiderJSONSummary(summaries []utils.SpiderSummary) {
 			Keyword:     s.Keyword,
 			SimHash:     s.SimHash,
 			IconHash:    s.IconHash,
+			IconFinger:  s.IconFinger,
 			IconBase64:  s.IconBase64,
 			ApiCount:    s.ApiCount,
 			UrlCount:    s.UrlCount,
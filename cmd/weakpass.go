@@ -5,7 +5,10 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/godspeedcurry/godscan/common"
 	"github.com/godspeedcurry/godscan/utils"
@@ -21,6 +24,25 @@ type WeakPassOptions struct {
 	ListFormat bool
 	Variant    bool
 	Show       bool
+
+	// web login brute force (--target)
+	Target          string
+	Request         string
+	Users           string
+	UserFile        string
+	UserField       string
+	PassField       string
+	SuccessStatus   string
+	FailStatus      string
+	SuccessLocation string
+	FailLocation    string
+	SuccessKeyword  string
+	FailKeyword     string
+	LengthDelta     int
+	Rate            int
+	Delay           int
+	MaxPerUser      int
+	All             bool
 }
 
 var (
@@ -28,11 +50,31 @@ var (
 )
 
 func (o *WeakPassOptions) validateOptions() error {
-	if weakPassOptions.Keywords == "" && !weakPassOptions.Show {
+	if weakPassOptions.Keywords == "" && !weakPassOptions.Show && weakPassOptions.Target == "" {
 		return fmt.Errorf("please give keywords")
 	}
+	if o.Request != "" && o.Target == "" {
+		return fmt.Errorf("--request needs --target for the scheme and host")
+	}
+	for _, list := range []string{o.SuccessStatus, o.FailStatus} {
+		if _, err := parseStatusList(list); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+func parseStatusList(s string) ([]int, error) {
+	var out []int
+	for _, v := range splitList(s) {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status %q", v)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
 func init() {
 	weakpassCmd := newCommandWithAliases("weakpass", "Generate common weak passwords from keywords", []string{"weak", "wp", "wk", "ww"}, &weakPassOptions)
 	rootCmd.AddCommand(weakpassCmd)
@@ -47,6 +89,24 @@ func init() {
 	weakpassCmd.PersistentFlags().BoolVarP(&weakPassOptions.Variant, "variant", "", false, "enable leetspeak variants (a/@, s/5, o/0, i/1, l/!, S/$, plus table mix)")
 	weakpassCmd.PersistentFlags().BoolVarP(&weakPassOptions.Show, "show", "", false, "show the entire list")
 
+	weakpassCmd.PersistentFlags().StringVar(&weakPassOptions.Target, "target", "", "login page url: detect its form and try the generated passwords against it")
+	weakpassCmd.PersistentFlags().StringVar(&weakPassOptions.Request, "request", "", "raw http request file with §USER§/§PASS§ markers, sent to the host of --target instead of the detected form")
+	weakpassCmd.PersistentFlags().StringVar(&weakPassOptions.Users, "user", "", "usernames, comma separated (default admin,test,root...)")
+	weakpassCmd.PersistentFlags().StringVar(&weakPassOptions.UserFile, "user-file", "", "username file, one per line")
+	weakpassCmd.PersistentFlags().StringVar(&weakPassOptions.UserField, "user-field", "", "username input name, overrides detection")
+	weakpassCmd.PersistentFlags().StringVar(&weakPassOptions.PassField, "pass-field", "", "password input name, overrides detection")
+	weakpassCmd.PersistentFlags().StringVar(&weakPassOptions.SuccessStatus, "success-status", "", "status codes of a valid login, comma separated")
+	weakpassCmd.PersistentFlags().StringVar(&weakPassOptions.FailStatus, "fail-status", "", "status codes of a failed login, comma separated")
+	weakpassCmd.PersistentFlags().StringVar(&weakPassOptions.SuccessLocation, "success-location", "", "redirect location substring of a valid login")
+	weakpassCmd.PersistentFlags().StringVar(&weakPassOptions.FailLocation, "fail-location", "", "redirect location substring of a failed login")
+	weakpassCmd.PersistentFlags().StringVar(&weakPassOptions.SuccessKeyword, "success-keyword", "", "body keywords of a valid login, comma separated")
+	weakpassCmd.PersistentFlags().StringVar(&weakPassOptions.FailKeyword, "fail-keyword", "", "body keywords of a failed login, comma separated")
+	weakpassCmd.PersistentFlags().IntVar(&weakPassOptions.LengthDelta, "length-delta", 0, "body length difference to a wrong-password response counted as a valid login, 0 to disable")
+	weakpassCmd.PersistentFlags().IntVar(&weakPassOptions.Rate, "rate", 0, "max login attempts per second, 0 for no limit")
+	weakpassCmd.PersistentFlags().IntVar(&weakPassOptions.Delay, "delay", 0, "milliseconds to wait between attempts")
+	weakpassCmd.PersistentFlags().IntVar(&weakPassOptions.MaxPerUser, "max-per-user", 0, "max passwords tried per account to stay under lockout policies, 0 for no limit")
+	weakpassCmd.PersistentFlags().BoolVar(&weakPassOptions.All, "all", false, "keep trying the other usernames after a valid login")
+
 	viper.BindPFlag("keyword", weakpassCmd.PersistentFlags().Lookup("keyword"))
 	viper.SetDefault("keyword", "")
 
@@ -74,5 +134,75 @@ func (o *WeakPassOptions) run() {
 		utils.ShowInfo()
 		return
 	}
+	if o.Target != "" {
+		o.runTarget()
+		return
+	}
 	utils.GenerateWeakPassword()
 }
+
+func (o *WeakPassOptions) runTarget() {
+	utils.InitHttp()
+	var (
+		login *utils.WebLogin
+		err   error
+	)
+	if o.Request != "" {
+		var raw []byte
+		if raw, err = os.ReadFile(o.Request); err == nil {
+			login, err = utils.ParseWebLoginRequest(o.Target, string(raw))
+		}
+	} else {
+		login, err = utils.DetectWebLogin(o.Target)
+	}
+	if err != nil {
+		utils.Error("weak %s: %v", o.Target, err)
+		return
+	}
+	if login.Raw == "" {
+		if o.UserField != "" {
+			login.UserField = o.UserField
+			login.Fields.Del(o.UserField)
+		}
+		if o.PassField != "" {
+			login.PassField = o.PassField
+			login.Fields.Del(o.PassField)
+		}
+		utils.Info("weak %s: form %s %s user=%q pass=%q csrf=%q", o.Target, login.Method, login.Action, login.UserField, login.PassField, login.CSRFField)
+	}
+	successStatus, _ := parseStatusList(o.SuccessStatus)
+	failStatus, _ := parseStatusList(o.FailStatus)
+	match := utils.WebLoginMatch{
+		SuccessStatus:   successStatus,
+		FailStatus:      failStatus,
+		SuccessLocation: o.SuccessLocation,
+		FailLocation:    o.FailLocation,
+		SuccessKeywords: splitList(o.SuccessKeyword),
+		FailKeywords:    splitList(o.FailKeyword),
+		LengthDelta:     o.LengthDelta,
+	}
+	// the common list first, the generated one is sorted rather than ordered by likelihood
+	passwords := append(append([]string{}, common.Passwords...), utils.BuildWeakPasswords()...)
+
+	db, err := utils.InitSpiderDB("spider.db")
+	if err != nil {
+		utils.Error("failed to init spider.db: %v", err)
+		return
+	}
+	utils.SetSpiderDB(db)
+	defer db.Close()
+	opts := utils.BruteOptions{
+		Users:         readList(o.Users, o.UserFile),
+		Passwords:     passwords,
+		Rate:          o.Rate,
+		Delay:         time.Duration(o.Delay) * time.Millisecond,
+		MaxPerUser:    o.MaxPerUser,
+		StopOnSuccess: !o.All,
+	}
+	results := utils.RunWebLogin(login, match, opts, db)
+	if len(results) == 0 {
+		return
+	}
+	renderBruteResults(results)
+	utils.Success("weak: %d credential(s) saved to spider.db (brute_results)", len(results))
+}
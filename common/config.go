@@ -16,13 +16,14 @@ var Userdict = map[string][]string{
 	"oracle":     {"sys", "system", "admin", "test", "web", "orcl", "oracle", "root"},
 	"mem":        {"admin", "test", "root", "web", "memcached"},
 	"vnc":        {"root"},
+	"web":        {"admin", "test", "root", "administrator", "system", "user", "guest"},
 }
 var NoFinger = "No finger!!"
 var Patterns = []string{"@", "_", "#", ""}
 var Passwords = []string{"!@#QWEASD", "!@#QWEASDZXC", "!QAZ2wsx", "0", "00000", "00001", "000000", "00000000", "1", "111111", "12", "123", "123123", "123321", "123456", "123!@#qwe", "123!@#asd", "123!@#zxc", "123456!a", "1234567", "12345678", "123456789", "1234567890", "123456~a", "123654", "123qwe", "123qwe!@#", "1q2w#E$R", "1q2w3e", "1q2w3e4r", "1qaz!QAZ", "1qaz2wsx", "1qaz2wsx3edc", "1qaz@WSX", "1qaz@wsx#edc", "2wsx@WSX", "654123", "654321", "666666", "8888888", "88888888", "a11111", "a123123", "a12345", "a123456", "a123456.", "A123456s!", "Aa123123", "Aa1234", "Aa1234.", "Aa12345", "Aa12345.", "Aa123456", "Aa123456!", "Aa123456789", "abc123", "abc@123", "abc123456", "admin", "admin01", "admin123", "admin123!@#", "admin@123", "Admin@123", "Change_Me", "Change_Me123", "Charge123", "manager", "P@ssw0rd", "P@ssw0rd!", "pass123", "pass@123", "Passw0rd", "password", "qazwsxedc", "qwe123", "qwe123!@#", "root", "sa123456", "shell", "sysadmin", "system", "talent", "test", "test01", "test123", "toor", "admin0", "admin1", "admin2", "adminadmin", "Test@123", "Abd@1234"}
 
 var DirList = []string{
-	"..;/actuator/env", "..;/api-docs", "..;/env", "..;/swagger-ui.html", "..;/v2/api-docs", ".DS_Store", ".git/config", ".git/HEAD", ".git/index", ".svn", "actuator", "actuator/env", "actuator;.js", "admin", "api", "api-docs", "api-docs/", "api-docs/index.html", "api/", "api/actuator", "api/index.html", "api/swagger-resources", "api/swagger-ui.html", "api/v2/api-docs", "apidocs/", "apidocs/index.html", "core/auth/login", "docs/", "docs/index.html", "env", "geoserver/index.html", "jeecg-boot", "mappings", "nacos", "nacos/", "nacos/#/", "service", "services", "site.tar.gz", "swagger-resources", "swagger-ui.html", "swagger/", "swagger/index.html", "v2/api-docs", "web.tar.gz", "www.tar.gz", "xxl-job-admin", "version", "log", "metrics", "cluster", "node", "api/v1/nodes", "pods", "v2/keys", "..;/actuator", "..;/..;/actuator", "..;/..;/..;/actuator", "graphql", "graphiql", "webservice", "webservices", ".well-known/openid-configuration", "jwks.json",
+	"..;/actuator/env", "..;/api-docs", "..;/env", "..;/swagger-ui.html", "..;/v2/api-docs", ".DS_Store", ".git/config", ".git/HEAD", ".git/index", ".svn", "actuator", "actuator/env", "actuator;.js", "admin", "api", "api-docs", "api-docs/", "api-docs/index.html", "api/", "api/actuator", "api/index.html", "api/swagger-resources", "api/swagger-ui.html", "api/v2/api-docs", "apidocs/", "apidocs/index.html", "core/auth/login", "docs/", "docs/index.html", "env", "geoserver/index.html", "jeecg-boot", "mappings", "nacos", "nacos/", "nacos/#/", "service", "services", "site.tar.gz", "swagger-resources", "swagger-ui.html", "swagger/", "swagger/index.html", "v2/api-docs", "v3/api-docs", "v3/api-docs/swagger-config", "web.tar.gz", "www.tar.gz", "xxl-job-admin", "version", "log", "metrics", "cluster", "node", "api/v1/nodes", "pods", "v2/keys", "..;/actuator", "..;/..;/actuator", "..;/..;/..;/actuator", "graphql", "graphiql", "webservice", "webservices", ".well-known/openid-configuration", "jwks.json",
 }
 
 var ImportantApi = []string{"/api/v1", "/api/user", "/api/blade-user", "/api/blade-log", "/api/diag", "/api/terminal", "/api/method", "/api/triggerSnapshot", "/api/sys", "/api/system", "/api/userrolelist", "/api/hyper", "/api/dataapp", "/api/clusters", "/api/node", "/api/resourceOperations", "/api/files", "/api/external", "/api/json", "/api/latest", "/api/rest", "/api/Software", "/api/ecode", "/api/group", "/api/project", "/api/interface", "/api/plugin", "/api/v2", "/api/client", "/api/jmeter", "/api/content", "/api/experimental", "/api/portal", "/api/switch-value", "/api/Console", "/api/dp", "/api/ec", "/api/repos", "/api/session", "/api/setup", "/api/v4", "/api/image", "/api/jsonws", "/api/attachment", "/api/empower", "/api/devices", "/api/search", "/api/portalTsLogin", "/api/swagger", "/api/hrm", "/api/virtual", "/api/admin", "/api/settings", "/api/open", "/api/directive", "/api/timelion", "/api/web", "/graphql", "/graphiql"}
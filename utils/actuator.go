@@ -0,0 +1,492 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ActuatorFinding is the structured result of inspecting one Spring Boot Actuator base url.
+type ActuatorFinding struct {
+	RootURL string `json:"root_url"`
+	BaseURL string `json:"base_url"`
+	// Version is "2.x" when the base serves a _links index, "1.x" for root-level endpoints.
+	Version       string   `json:"version"`
+	Endpoints     []string `json:"endpoints"`
+	Mappings      int      `json:"mappings"`
+	Properties    int      `json:"properties"`
+	Unmasked      int      `json:"unmasked"`
+	Heapdump      bool     `json:"heapdump"`
+	Jolokia       bool     `json:"jolokia"`
+	GatewayRoutes bool     `json:"gateway_routes"`
+}
+
+// actuatorProbeEndpoints are tried one by one when the base has no _links index.
+var actuatorProbeEndpoints = []string{"env", "configprops", "mappings", "beans", "health", "info", "metrics", "loggers",
+	"threaddump", "dump", "trace", "httptrace", "scheduledtasks", "heapdump", "jolokia", "gateway/routes", "refresh", "restart", "shutdown", "logfile"}
+
+var (
+	actuatorSeen          sync.Map
+	actuatorSecretKeyRe   = regexp.MustCompile(`(?i)(password|passwd|pwd|secret|token|credential|private[-_.]?key|access[-_.]?key|api[-_.]?key|app[-_.]?key|jdbc|datasource\.(url|username)|\.username$)`)
+	actuatorPredicateRe   = regexp.MustCompile(`^\{(?:([A-Z][A-Z, |]*) )?\[([^\]]+)\]`)
+	actuatorV1MappingRe   = regexp.MustCompile(`^\{\[([^\]]+)\](?:,methods=\[([^\]]*)\])?`)
+	actuatorMaskedValueRe = regexp.MustCompile(`^\*+$`)
+)
+
+// ActuatorBase maps a dirbrute hit (actuator, actuator/env, env, mappings, ..;/actuator) to the
+// actuator base url, or "" when the path is not actuator related.
+func ActuatorBase(fullURL string) string {
+	u, err := url.Parse(fullURL)
+	if err != nil {
+		return ""
+	}
+	p := strings.TrimSuffix(u.Path, "/")
+	switch {
+	case strings.HasSuffix(p, "/actuator"):
+	case strings.HasSuffix(p, "/env"), strings.HasSuffix(p, "/mappings"), strings.HasSuffix(p, "/configprops"):
+		p = p[:strings.LastIndex(p, "/")]
+	default:
+		return ""
+	}
+	u.Path, u.RawQuery, u.Fragment = p, "", ""
+	return u.String()
+}
+
+// MaybeInspectActuator inspects an actuator base once per run after a dirbrute hit.
+func MaybeInspectActuator(fullURL string, body []byte) {
+	base := ActuatorBase(fullURL)
+	if base == "" || !looksLikeJSON(body) {
+		return
+	}
+	if _, loaded := actuatorSeen.LoadOrStore(base, true); loaded {
+		return
+	}
+	f, err := InspectActuator(base, GetSpiderDB())
+	if err != nil {
+		Debug("actuator %s: %v", base, err)
+		return
+	}
+	Success("[actuator] %s %s: %d endpoint(s) [%s], %d mapping(s), %d sensitive propert(ies) (%d unmasked), heapdump=%v jolokia=%v gateway=%v",
+		f.BaseURL, f.Version, len(f.Endpoints), strings.Join(f.Endpoints, ","), f.Mappings, f.Properties, f.Unmasked, f.Heapdump, f.Jolokia, f.GatewayRoutes)
+	if f.Heapdump {
+		MaybeScanArtifact(f.BaseURL + "/heapdump")
+	}
+}
+
+// InspectActuator enumerates the endpoints under base, stores /mappings into api_endpoints,
+// sensitive /env and /configprops properties into sensitive_hits and the summary into actuator_findings.
+func InspectActuator(base string, db *sql.DB) (*ActuatorFinding, error) {
+	base = strings.TrimSuffix(base, "/")
+	f := &ActuatorFinding{RootURL: apiDocRoot(base), BaseURL: base, Version: "1.x"}
+	links := make(map[string]string)
+	if body, status, _ := actuatorGet(base); status == http.StatusOK {
+		var idx struct {
+			Links map[string]struct {
+				Href string `json:"href"`
+			} `json:"_links"`
+		}
+		if json.Unmarshal(body, &idx) == nil && len(idx.Links) > 0 {
+			f.Version = "2.x"
+			for name, l := range idx.Links {
+				if name == "self" || strings.Contains(l.Href, "{") {
+					continue
+				}
+				links[name] = l.Href
+			}
+		}
+	}
+	if f.Version == "1.x" {
+		for _, name := range actuatorProbeEndpoints {
+			if actuatorReachable(base + "/" + name) {
+				links[name] = base + "/" + name
+			}
+		}
+	} else {
+		// _links lists gateway as "gateway" only; heapdump may be hidden from the index
+		for _, name := range []string{"gateway/routes", "heapdump", "jolokia"} {
+			if _, ok := links[name]; !ok && actuatorReachable(base+"/"+name) {
+				links[name] = base + "/" + name
+			}
+		}
+	}
+	if len(links) == 0 {
+		return nil, fmt.Errorf("no actuator endpoint reachable")
+	}
+	for name := range links {
+		f.Endpoints = append(f.Endpoints, name)
+	}
+	sort.Strings(f.Endpoints)
+	_, f.Heapdump = links["heapdump"]
+	_, f.Jolokia = links["jolokia"]
+	_, f.GatewayRoutes = links["gateway/routes"]
+
+	if href, ok := links["mappings"]; ok {
+		if body, status, _ := actuatorGet(href); status == http.StatusOK {
+			rows := ParseActuatorMappings(body)
+			for i := range rows {
+				rows[i].RootURL, rows[i].DocURL = f.RootURL, href
+			}
+			if err := SaveAPIEndpoints(db, rows); err != nil {
+				Error("actuator: save mappings %s failed: %v", href, err)
+			}
+			f.Mappings = len(rows)
+		}
+	}
+	var hits []SensitiveHit
+	for _, name := range []string{"env", "configprops"} {
+		href, ok := links[name]
+		if !ok {
+			continue
+		}
+		body, status, _ := actuatorGet(href)
+		if status != http.StatusOK {
+			continue
+		}
+		for _, p := range ParseActuatorProperties(body) {
+			if !actuatorSecretKeyRe.MatchString(p.Key) {
+				continue
+			}
+			hit := SensitiveHit{
+				SourceURL:  href,
+				Category:   "Actuator " + name,
+				RuleID:     "actuator-" + name,
+				Confidence: ConfidenceHigh,
+				Content:    p.Key + " = " + p.Value,
+				Entropy:    calculateEntropy(p.Value),
+				Status:     SecretUnverified,
+				Detail:     p.Source,
+			}
+			if p.Masked {
+				hit.Confidence, hit.Status = ConfidenceLow, SecretMasked
+			} else {
+				f.Unmasked++
+			}
+			hits = append(hits, hit)
+		}
+	}
+	f.Properties = len(hits)
+	if err := SaveSensitiveHits(db, hits); err != nil {
+		Error("actuator: save properties failed: %v", err)
+	}
+	if err := SaveActuatorFindings(db, []ActuatorFinding{*f}); err != nil {
+		Error("actuator: save finding failed: %v", err)
+	}
+	return f, nil
+}
+
+func actuatorGet(rawURL string) ([]byte, int, error) {
+	resp, err := fetchGet(rawURL)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, apiDocMaxBytes))
+	return body, resp.StatusCode, err
+}
+
+// actuatorPostOnly endpoints answer 405 to anything but POST, which is the only sign they exist.
+var actuatorPostOnly = map[string]bool{"refresh": true, "restart": true, "shutdown": true}
+
+// actuatorReachable checks a POST-only endpoint with HEAD and any other one with a ranged GET
+// whose content type must fit the endpoint, so that heapdump or logfile are not downloaded
+// and catch-all pages do not count.
+func actuatorReachable(rawURL string) bool {
+	name := rawURL[strings.LastIndex(rawURL, "/")+1:]
+	if actuatorPostOnly[name] {
+		req, err := http.NewRequest(http.MethodHead, rawURL, nil)
+		if err != nil {
+			return false
+		}
+		SetHeaders(req)
+		resp, err := Client.Do(req)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusMethodNotAllowed
+	}
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return false
+	}
+	SetHeaders(req)
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := Client.Do(req)
+	if err != nil {
+		return false
+	}
+	// Range may be ignored; never read more than a few bytes of the body
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 512))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return false
+	}
+	ct := strings.ToLower(resp.Header.Get("Content-Type"))
+	switch name {
+	case "heapdump":
+		return strings.Contains(ct, "octet-stream") || strings.Contains(ct, "hprof")
+	case "logfile", "threaddump", "dump":
+		return strings.Contains(ct, "text/plain") || strings.Contains(ct, "json")
+	}
+	return strings.Contains(ct, "json")
+}
+
+// ParseActuatorMappings reads a /mappings document of Spring Boot 1.x or 2.x/3.x.
+func ParseActuatorMappings(body []byte) []APIEndpointRow {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var out []APIEndpointRow
+	add := func(methods, patterns []string, handler string) {
+		if len(methods) == 0 {
+			methods = []string{"GET"}
+		}
+		for _, p := range patterns {
+			p = strings.TrimSpace(p)
+			if !strings.HasPrefix(p, "/") {
+				continue
+			}
+			for _, m := range methods {
+				m = strings.ToUpper(strings.TrimSpace(m))
+				if m == "" || seen[m+" "+p] {
+					continue
+				}
+				seen[m+" "+p] = true
+				out = append(out, APIEndpointRow{Method: m, Path: p, Summary: handler})
+			}
+		}
+	}
+	if ctxs, ok := raw["contexts"]; ok {
+		var contexts map[string]struct {
+			Mappings map[string]json.RawMessage `json:"mappings"`
+		}
+		if json.Unmarshal(ctxs, &contexts) != nil {
+			return nil
+		}
+		for _, c := range contexts {
+			for kind, groupRaw := range c.Mappings {
+				if kind != "dispatcherServlets" && kind != "dispatcherHandlers" {
+					continue
+				}
+				var groups map[string][]struct {
+					Handler   string `json:"handler"`
+					Predicate string `json:"predicate"`
+					Details   *struct {
+						Conditions struct {
+							Methods  []string `json:"methods"`
+							Patterns []string `json:"patterns"`
+						} `json:"requestMappingConditions"`
+					} `json:"details"`
+				}
+				if json.Unmarshal(groupRaw, &groups) != nil {
+					continue
+				}
+				for _, entries := range groups {
+					for _, e := range entries {
+						if e.Details != nil && len(e.Details.Conditions.Patterns) > 0 {
+							add(e.Details.Conditions.Methods, e.Details.Conditions.Patterns, e.Handler)
+						} else if m := actuatorPredicateRe.FindStringSubmatch(e.Predicate); m != nil {
+							add(splitActuatorList(m[1]), splitActuatorList(m[2]), e.Handler)
+						}
+					}
+				}
+			}
+		}
+	} else {
+		for key, v := range raw {
+			m := actuatorV1MappingRe.FindStringSubmatch(key)
+			if m == nil {
+				continue
+			}
+			var h struct {
+				Method string `json:"method"`
+			}
+			_ = json.Unmarshal(v, &h)
+			add(splitActuatorList(m[2]), splitActuatorList(m[1]), h.Method)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Path != out[j].Path {
+			return out[i].Path < out[j].Path
+		}
+		return out[i].Method < out[j].Method
+	})
+	return out
+}
+
+func splitActuatorList(s string) []string {
+	var out []string
+	for _, p := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '|' }) {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// ActuatorProperty is one flattened /env or /configprops entry.
+type ActuatorProperty struct {
+	Key    string
+	Value  string
+	Source string
+	Masked bool
+}
+
+// ParseActuatorProperties flattens /env (1.x and 2.x) and /configprops documents.
+func ParseActuatorProperties(body []byte) []ActuatorProperty {
+	var raw map[string]any
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil
+	}
+	var out []ActuatorProperty
+	emit := func(key string, v any, source string) {
+		s, ok := actuatorScalar(v)
+		if !ok {
+			return
+		}
+		out = append(out, ActuatorProperty{Key: key, Value: s, Source: source, Masked: actuatorMaskedValueRe.MatchString(s)})
+	}
+	var flatten func(prefix string, v any, source string)
+	flatten = func(prefix string, v any, source string) {
+		switch t := v.(type) {
+		case map[string]any:
+			for k, child := range t {
+				key := k
+				if prefix != "" {
+					key = prefix + "." + k
+				}
+				flatten(key, child, source)
+			}
+		case []any:
+			for i, child := range t {
+				flatten(fmt.Sprintf("%s[%d]", prefix, i), child, source)
+			}
+		default:
+			emit(prefix, v, source)
+		}
+	}
+
+	switch {
+	case raw["propertySources"] != nil:
+		sources, _ := raw["propertySources"].([]any)
+		for _, s := range sources {
+			src, _ := s.(map[string]any)
+			name, _ := src["name"].(string)
+			props, _ := src["properties"].(map[string]any)
+			for k, p := range props {
+				if pm, ok := p.(map[string]any); ok {
+					emit(k, pm["value"], name)
+				}
+			}
+		}
+	case raw["contexts"] != nil:
+		ctxs, _ := raw["contexts"].(map[string]any)
+		for _, c := range ctxs {
+			cm, _ := c.(map[string]any)
+			beans, _ := cm["beans"].(map[string]any)
+			actuatorConfigBeans(beans, flatten)
+		}
+	default:
+		// 1.x: /env is {source: {key: value}}, /configprops is {bean: {prefix, properties}}
+		isConfigProps := false
+		for _, v := range raw {
+			if m, ok := v.(map[string]any); ok && m["prefix"] != nil {
+				isConfigProps = true
+				break
+			}
+		}
+		if isConfigProps {
+			actuatorConfigBeans(raw, flatten)
+			break
+		}
+		for source, v := range raw {
+			if props, ok := v.(map[string]any); ok {
+				for k, val := range props {
+					emit(k, val, source)
+				}
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Key != out[j].Key {
+			return out[i].Key < out[j].Key
+		}
+		return out[i].Source < out[j].Source
+	})
+	return out
+}
+
+func actuatorConfigBeans(beans map[string]any, flatten func(string, any, string)) {
+	for name, b := range beans {
+		bm, _ := b.(map[string]any)
+		prefix, _ := bm["prefix"].(string)
+		if props, ok := bm["properties"].(map[string]any); ok {
+			flatten(prefix, props, name)
+		}
+	}
+}
+
+func actuatorScalar(v any) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, t != ""
+	case float64:
+		return fmt.Sprint(t), true
+	case bool:
+		return fmt.Sprint(t), true
+	}
+	return "", false
+}
+
+func SaveActuatorFindings(db *sql.DB, findings []ActuatorFinding) error {
+	if db == nil || len(findings) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO actuator_findings (root_url, base_url, version, endpoints, mappings, properties, unmasked, heapdump, jolokia, gateway_routes, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, f := range findings {
+		if _, err := stmt.Exec(f.RootURL, f.BaseURL, f.Version, strings.Join(f.Endpoints, ","), f.Mappings, f.Properties, f.Unmasked, f.Heapdump, f.Jolokia, f.GatewayRoutes); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func LoadActuatorFindings(db *sql.DB) ([]ActuatorFinding, error) {
+	rows, err := db.Query(`SELECT root_url, base_url, version, endpoints, mappings, properties, unmasked, heapdump, jolokia, gateway_routes FROM actuator_findings ORDER BY root_url, base_url`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ActuatorFinding
+	for rows.Next() {
+		var f ActuatorFinding
+		var endpoints string
+		if err := rows.Scan(&f.RootURL, &f.BaseURL, &f.Version, &endpoints, &f.Mappings, &f.Properties, &f.Unmasked, &f.Heapdump, &f.Jolokia, &f.GatewayRoutes); err != nil {
+			return nil, err
+		}
+		if endpoints != "" {
+			f.Endpoints = strings.Split(endpoints, ",")
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInspectActuator(t *testing.T) {
+	docs := map[string]string{
+		"/actuator": `{"_links":{"self":{"href":"BASE/actuator"},"env":{"href":"BASE/actuator/env"},"env-toMatch":{"href":"BASE/actuator/env/{toMatch}","templated":true},
+			"configprops":{"href":"BASE/actuator/configprops"},"mappings":{"href":"BASE/actuator/mappings"},"heapdump":{"href":"BASE/actuator/heapdump"}}}`,
+		"/actuator/env": `{"activeProfiles":["prod"],"propertySources":[{"name":"applicationConfig: [classpath:/application.yml]","properties":{
+			"spring.datasource.password":{"value":"S3cr3t!pass","origin":"class path resource [application.yml]:5:15"},
+			"spring.redis.password":{"value":"******"},"server.port":{"value":8080}}}]}`,
+		"/actuator/configprops": `{"contexts":{"app":{"beans":{"aliyunOss":{"prefix":"oss","properties":{"accessKeySecret":"LTAIsecretvalue123","endpoint":"oss-cn-hangzhou.aliyuncs.com"}}}}}}`,
+		"/actuator/mappings": `{"contexts":{"app":{"mappings":{"dispatcherServlets":{"dispatcherServlet":[
+			{"handler":"com.demo.UserController#list()","predicate":"{GET [/api/users]}","details":{"requestMappingConditions":{"methods":["GET"],"patterns":["/api/users"]}}},
+			{"handler":"com.demo.UserController#save()","predicate":"{POST [/api/users, /api/user/save]}"},
+			{"handler":"ResourceHttpRequestHandler","predicate":"/**"}]}}}}}`,
+		"/actuator/gateway/routes": `[{"route_id":"admin","uri":"http://10.0.0.1:8080"}]`,
+	}
+	srv := mustTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/actuator/heapdump" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			return
+		}
+		doc, ok := docs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.spring-boot.actuator.v3+json")
+		w.Write([]byte(strings.ReplaceAll(doc, "BASE", "http://"+r.Host)))
+	}))
+	defer srv.Close()
+	oldClient := Client
+	Client = srv.Client()
+	defer func() { Client = oldClient }()
+	db, err := InitSpiderDB(filepath.Join(t.TempDir(), "spider.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if base := ActuatorBase(srv.URL + "/actuator/env"); base != srv.URL+"/actuator" {
+		t.Fatalf("ActuatorBase = %q", base)
+	}
+	f, err := InspectActuator(srv.URL+"/actuator", db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Version != "2.x" || !f.Heapdump || !f.GatewayRoutes || f.Jolokia || f.Mappings != 3 || f.Properties != 3 || f.Unmasked != 2 {
+		t.Fatalf("unexpected finding %+v", f)
+	}
+
+	eps, _ := LoadAPIEndpoints(db)
+	got := map[string]bool{}
+	for _, e := range eps {
+		got[e.Method+" "+e.Path] = true
+	}
+	for _, want := range []string{"GET /api/users", "POST /api/users", "POST /api/user/save"} {
+		if !got[want] {
+			t.Errorf("missing mapping %s in %v", want, got)
+		}
+	}
+	hits, _ := LoadSensitiveHits(db)
+	status := map[string]string{}
+	for _, h := range hits {
+		status[h.Content] = h.Status
+	}
+	if status["spring.datasource.password = S3cr3t!pass"] != SecretUnverified || status["spring.redis.password = ******"] != SecretMasked || status["oss.accessKeySecret = LTAIsecretvalue123"] == "" {
+		t.Fatalf("unexpected hits %v", status)
+	}
+	saved, err := LoadActuatorFindings(db)
+	if err != nil || len(saved) != 1 || saved[0].BaseURL != srv.URL+"/actuator" {
+		t.Fatalf("actuator_findings: %v %+v", err, saved)
+	}
+}
+
+func TestParseActuatorV1(t *testing.T) {
+	rows := ParseActuatorMappings([]byte(`{"/webjars/**":{"bean":"resourceHandlerMapping"},"{[/login],methods=[POST]}":{"bean":"requestMappingHandlerMapping","method":"public String LoginController.login()"},"{[/env || /env.json],methods=[GET]}":{"bean":"endpointHandlerMapping"}}`))
+	if len(rows) != 3 || rows[0].Path != "/env" || rows[2].Method != "POST" || rows[2].Path != "/login" {
+		t.Fatalf("v1 mappings: %+v", rows)
+	}
+	props := ParseActuatorProperties([]byte(`{"profiles":[],"systemEnvironment":{"DB_PASSWORD":"******","HOME":"/root"},"applicationConfig: [classpath:/application.properties]":{"jwt.secret":"abc123"}}`))
+	if len(props) != 3 || props[0].Key != "DB_PASSWORD" || !props[0].Masked || props[2].Key != "jwt.secret" || props[2].Masked {
+		t.Fatalf("v1 env: %+v", props)
+	}
+}
+
+func TestActuatorReachable(t *testing.T) {
+	srv := mustTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			// a gateway that rejects HEAD everywhere
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		switch r.URL.Path {
+		case "/actuator/env":
+			w.Header().Set("Content-Type", "application/vnd.spring-boot.actuator.v3+json")
+			w.Write([]byte(`{"propertySources":[]}`))
+		case "/actuator/heapdump":
+			if r.Header.Get("Range") != "bytes=0-0" {
+				t.Errorf("heapdump fetched without a range")
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte("J"))
+		case "/actuator/logfile":
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+			w.Write([]byte("2024-01-01 INFO started"))
+		case "/actuator/health":
+			http.NotFound(w, r)
+		default:
+			// SPA fallback
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		}
+	}))
+	defer srv.Close()
+	oldClient := Client
+	Client = srv.Client()
+	defer func() { Client = oldClient }()
+
+	for name, want := range map[string]bool{"env": true, "heapdump": true, "logfile": true, "refresh": true, "shutdown": true,
+		"beans": false, "health": false, "jolokia": false, "mappings": false} {
+		if got := actuatorReachable(srv.URL + "/actuator/" + name); got != want {
+			t.Errorf("actuatorReachable(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// API export formats accepted by ExportAPIs.
+const (
+	APIExportOpenAPI = "openapi"
+	APIExportPostman = "postman"
+	APIExportRawHTTP = "raw-http"
+)
+
+var APIExportFormats = []string{APIExportOpenAPI, APIExportPostman, APIExportRawHTTP}
+
+var pathTemplateRe = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// apiExportOp is one request to export: a path from api_paths (GET unless a method was seen) or an
+// operation parsed from a Swagger/OpenAPI document.
+type apiExportOp struct {
+	Method  string
+	Path    string
+	Summary string
+	Params  []APIParam
+}
+
+type apiExportRoot struct {
+	Root string
+	Ops  []apiExportOp
+}
+
+// buildAPIExport groups paths and endpoints by root url. The prefix is prepended to bare
+// paths that do not carry it yet; query strings become query parameters.
+func buildAPIExport(paths []APIPathRow, endpoints []APIEndpointRow, prefix string) []apiExportRoot {
+	byRoot := make(map[string]*apiExportRoot)
+	seen := make(map[string]struct{})
+	add := func(root string, op apiExportOp) {
+		root = strings.TrimRight(root, "/")
+		if root == "" || op.Path == "" {
+			return
+		}
+		key := root + " " + op.Method + " " + op.Path
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		r, ok := byRoot[root]
+		if !ok {
+			r = &apiExportRoot{Root: root}
+			byRoot[root] = r
+		}
+		r.Ops = append(r.Ops, op)
+	}
+	for _, e := range endpoints {
+		add(e.RootURL, apiExportOp{Method: e.Method, Path: e.Path, Summary: e.Summary, Params: e.Params})
+	}
+	for _, p := range paths {
+		raw := strings.TrimSpace(p.Path)
+		if prefix != "" && !strings.HasPrefix(raw, prefix) {
+			raw = strings.TrimRight(prefix, "/") + "/" + strings.TrimLeft(raw, "/")
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Path == "" {
+			continue
+		}
+		methods := []string{"GET"}
+		if p.Method != "" {
+			methods = strings.Split(p.Method, ",")
+		}
+		for _, method := range methods {
+			op := apiExportOp{Method: method, Path: "/" + strings.TrimLeft(u.Path, "/")}
+			for name := range u.Query() {
+				op.Params = append(op.Params, APIParam{Name: name, In: "query"})
+			}
+			// parameter names seen at the JavaScript call site go to the query for
+			// GET-like methods and to a form body otherwise
+			in := "formData"
+			if method == "GET" || method == "DELETE" || method == "HEAD" {
+				in = "query"
+			}
+			for _, name := range strings.Split(p.Params, ",") {
+				if name != "" && !hasParam(op.Params, name, "query") && !hasParam(op.Params, name, in) {
+					op.Params = append(op.Params, APIParam{Name: name, In: in})
+				}
+			}
+			sort.Slice(op.Params, func(i, j int) bool { return op.Params[i].Name < op.Params[j].Name })
+			add(p.RootURL, op)
+		}
+	}
+	roots := make([]apiExportRoot, 0, len(byRoot))
+	for _, r := range byRoot {
+		sort.SliceStable(r.Ops, func(i, j int) bool {
+			if r.Ops[i].Path != r.Ops[j].Path {
+				return r.Ops[i].Path < r.Ops[j].Path
+			}
+			return r.Ops[i].Method < r.Ops[j].Method
+		})
+		roots = append(roots, *r)
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].Root < roots[j].Root })
+	return roots
+}
+
+// exportHeaders returns the global -H headers plus the User-Agent as ordered key/value pairs.
+func exportHeaders() [][2]string {
+	var out [][2]string
+	if ua := viper.GetString("DefaultUA"); ua != "" {
+		out = append(out, [2]string{"User-Agent", ua})
+	}
+	for _, h := range viper.GetStringSlice("headers") {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		replaced := false
+		for i := range out {
+			if strings.EqualFold(out[i][0], key) {
+				out[i][1] = value
+				replaced = true
+			}
+		}
+		if !replaced {
+			out = append(out, [2]string{key, value})
+		}
+	}
+	return out
+}
+
+func bodyContentType(p APIParam) string {
+	if p.Type != "" && strings.Contains(p.Type, "/") {
+		return strings.SplitN(p.Type, ",", 2)[0]
+	}
+	return "application/json"
+}
+
+func renderOpenAPI(r apiExportRoot, headers [][2]string) ([]byte, error) {
+	paths := make(map[string]map[string]any)
+	for _, op := range r.Ops {
+		var params []map[string]any
+		var body map[string]any
+		for _, p := range op.Params {
+			switch p.In {
+			case "body", "formData":
+				ct := bodyContentType(p)
+				if p.In == "formData" {
+					ct = "application/x-www-form-urlencoded"
+				}
+				body = map[string]any{"required": p.Required, "content": map[string]any{ct: map[string]any{"schema": map[string]any{"type": "object"}}}}
+			default:
+				params = append(params, map[string]any{"name": p.Name, "in": p.In, "required": p.Required || p.In == "path", "schema": map[string]any{"type": "string"}})
+			}
+		}
+		for _, m := range pathTemplateRe.FindAllStringSubmatch(op.Path, -1) {
+			if !hasParam(op.Params, m[1], "path") {
+				params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+			}
+		}
+		for _, h := range headers {
+			params = append(params, map[string]any{"name": h[0], "in": "header", "schema": map[string]any{"type": "string"}, "example": h[1]})
+		}
+		operation := map[string]any{"responses": map[string]any{"default": map[string]any{"description": "response"}}}
+		if op.Summary != "" {
+			operation["summary"] = op.Summary
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if body != nil {
+			operation["requestBody"] = body
+		}
+		if paths[op.Path] == nil {
+			paths[op.Path] = make(map[string]any)
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = operation
+	}
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]any{"title": r.Root, "version": "1.0.0", "description": "exported by godscan"},
+		"servers": []map[string]any{{"url": r.Root}},
+		"paths":   paths,
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func hasParam(params []APIParam, name, in string) bool {
+	for _, p := range params {
+		if p.Name == name && p.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+func renderPostman(r apiExportRoot, headers [][2]string) ([]byte, error) {
+	var items []map[string]any
+	for _, op := range r.Ops {
+		path := pathTemplateRe.ReplaceAllString(op.Path, ":$1")
+		var segments []string
+		for _, s := range strings.Split(strings.Trim(path, "/"), "/") {
+			if s != "" {
+				segments = append(segments, s)
+			}
+		}
+		var query, variables []map[string]any
+		var body map[string]any
+		reqHeaders := []map[string]any{}
+		for _, h := range headers {
+			reqHeaders = append(reqHeaders, map[string]any{"key": h[0], "value": h[1]})
+		}
+		for _, p := range op.Params {
+			switch p.In {
+			case "query":
+				query = append(query, map[string]any{"key": p.Name, "value": ""})
+			case "path":
+				variables = append(variables, map[string]any{"key": p.Name, "value": ""})
+			case "header":
+				reqHeaders = append(reqHeaders, map[string]any{"key": p.Name, "value": ""})
+			case "body":
+				reqHeaders = append(reqHeaders, map[string]any{"key": "Content-Type", "value": bodyContentType(p)})
+				body = map[string]any{"mode": "raw", "raw": "{}"}
+			case "formData":
+				if body == nil || body["mode"] != "urlencoded" {
+					body = map[string]any{"mode": "urlencoded", "urlencoded": []map[string]any{}}
+				}
+				body["urlencoded"] = append(body["urlencoded"].([]map[string]any), map[string]any{"key": p.Name, "value": ""})
+			}
+		}
+		raw := "{{baseUrl}}" + path
+		if len(query) > 0 {
+			var q []string
+			for _, kv := range query {
+				q = append(q, kv["key"].(string)+"=")
+			}
+			raw += "?" + strings.Join(q, "&")
+		}
+		reqURL := map[string]any{"raw": raw, "host": []string{"{{baseUrl}}"}, "path": segments}
+		if len(query) > 0 {
+			reqURL["query"] = query
+		}
+		if len(variables) > 0 {
+			reqURL["variable"] = variables
+		}
+		request := map[string]any{"method": op.Method, "header": reqHeaders, "url": reqURL}
+		if body != nil {
+			request["body"] = body
+		}
+		name := op.Method + " " + op.Path
+		if op.Summary != "" {
+			name += " - " + op.Summary
+		}
+		items = append(items, map[string]any{"name": name, "request": request})
+	}
+	collection := map[string]any{
+		"info": map[string]any{
+			"name":   r.Root,
+			"schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json",
+		},
+		"item":     items,
+		"variable": []map[string]any{{"key": "baseUrl", "value": r.Root}},
+	}
+	return json.MarshalIndent(collection, "", "  ")
+}
+
+// renderRawHTTP writes one raw request per operation, separated by a blank line, ready to
+// paste into Burp Repeater/Intruder. Path templates are filled with "1".
+func renderRawHTTP(r apiExportRoot, headers [][2]string) ([]byte, error) {
+	u, err := url.Parse(r.Root)
+	if err != nil {
+		return nil, err
+	}
+	base := strings.TrimRight(u.Path, "/")
+	var b strings.Builder
+	for _, op := range r.Ops {
+		target := base + pathTemplateRe.ReplaceAllString(op.Path, "1")
+		var query []string
+		var contentType, body string
+		var form []string
+		for _, p := range op.Params {
+			switch p.In {
+			case "query":
+				query = append(query, url.QueryEscape(p.Name)+"=")
+			case "body":
+				contentType, body = bodyContentType(p), "{}"
+			case "formData":
+				form = append(form, url.QueryEscape(p.Name)+"=")
+			}
+		}
+		if len(form) > 0 && body == "" {
+			contentType, body = "application/x-www-form-urlencoded", strings.Join(form, "&")
+		}
+		if len(query) > 0 {
+			target += "?" + strings.Join(query, "&")
+		}
+		fmt.Fprintf(&b, "%s %s HTTP/1.1\r\nHost: %s\r\n", op.Method, target, u.Host)
+		for _, h := range headers {
+			if strings.EqualFold(h[0], "Host") {
+				continue
+			}
+			fmt.Fprintf(&b, "%s: %s\r\n", h[0], h[1])
+		}
+		if body != "" {
+			fmt.Fprintf(&b, "Content-Type: %s\r\nContent-Length: %d\r\n", contentType, len(body))
+		}
+		fmt.Fprintf(&b, "Connection: close\r\n\r\n%s\r\n\r\n", body)
+	}
+	return []byte(b.String()), nil
+}
+
+func apiExportFileName(root, format string) string {
+	name := root
+	if u, err := url.Parse(root); err == nil && u.Host != "" {
+		name = u.Scheme + "_" + u.Host + u.Path
+	}
+	name = strings.NewReplacer(":", "_", "/", "_", "\\", "_").Replace(strings.TrimRight(name, "/"))
+	switch format {
+	case APIExportOpenAPI:
+		return name + ".openapi.json"
+	case APIExportPostman:
+		return name + ".postman_collection.json"
+	default:
+		return name + ".http"
+	}
+}
+
+// ExportAPIs writes one file per root url built from api_paths and api_endpoints in the given
+// format (openapi, postman or raw-http) into dir and returns the written files.
+func ExportAPIs(db *sql.DB, format, dir, prefix string) ([]string, error) {
+	var render func(apiExportRoot, [][2]string) ([]byte, error)
+	switch format {
+	case APIExportOpenAPI:
+		render = renderOpenAPI
+	case APIExportPostman:
+		render = renderPostman
+	case APIExportRawHTTP:
+		render = renderRawHTTP
+	default:
+		return nil, fmt.Errorf("unknown api export format %q (want %s)", format, strings.Join(APIExportFormats, "|"))
+	}
+	paths, err := LoadAPIPaths(db)
+	if err != nil {
+		return nil, fmt.Errorf("load api_paths: %w", err)
+	}
+	endpoints, err := LoadAPIEndpoints(db)
+	if err != nil {
+		return nil, fmt.Errorf("load api_endpoints: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	headers := exportHeaders()
+	var files []string
+	for _, r := range buildAPIExport(paths, endpoints, prefix) {
+		data, err := render(r, headers)
+		if err != nil {
+			Warning("export %s failed: %v", r.Root, err)
+			continue
+		}
+		file := filepath.Join(dir, apiExportFileName(r.Root, format))
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			return files, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestExportAPIs(t *testing.T) {
+	dir := t.TempDir()
+	db, err := InitSpiderDB(filepath.Join(dir, "spider.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	defer db.Close()
+
+	oldHeaders, oldUA := viper.GetStringSlice("headers"), viper.GetString("DefaultUA")
+	viper.Set("headers", []string{"Cookie: sid=1", "X-Token: abc"})
+	viper.Set("DefaultUA", "test-ua")
+	defer func() {
+		viper.Set("headers", oldHeaders)
+		viper.Set("DefaultUA", oldUA)
+	}()
+
+	root := "http://10.0.0.1:8080"
+	if err := SaveAPIPaths(db, root, root+"/app.js", []string{"/user/list?page=1", "/prod-api/user/list?page=1"}, dir); err != nil {
+		t.Fatal(err)
+	}
+	err = SaveAPIEndpoints(db, []APIEndpointRow{{RootURL: root, DocURL: root + "/v2/api-docs", Method: "POST", Path: "/prod-api/user/{id}",
+		Params: []APIParam{{Name: "id", In: "path", Required: true}, {Name: "user", In: "body", Type: "application/json"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outDir := filepath.Join(dir, "export")
+	files, err := ExportAPIs(db, APIExportOpenAPI, outDir, "/prod-api")
+	if err != nil || len(files) != 1 {
+		t.Fatalf("openapi files=%v err=%v", files, err)
+	}
+	var doc struct {
+		Servers []struct{ URL string }
+		Paths   map[string]map[string]json.RawMessage
+	}
+	data, _ := os.ReadFile(files[0])
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("openapi json: %v", err)
+	}
+	if doc.Servers[0].URL != root || len(doc.Paths) != 2 || doc.Paths["/prod-api/user/list"]["get"] == nil || doc.Paths["/prod-api/user/{id}"]["post"] == nil {
+		t.Fatalf("openapi doc = %s", data)
+	}
+
+	files, err = ExportAPIs(db, APIExportPostman, outDir, "/prod-api")
+	if err != nil || len(files) != 1 || !strings.HasSuffix(files[0], ".postman_collection.json") {
+		t.Fatalf("postman files=%v err=%v", files, err)
+	}
+	data, _ = os.ReadFile(files[0])
+	if !strings.Contains(string(data), "collection/v2.1.0") || !strings.Contains(string(data), `{{baseUrl}}/prod-api/user/:id`) || !strings.Contains(string(data), "sid=1") {
+		t.Fatalf("postman collection = %s", data)
+	}
+
+	files, err = ExportAPIs(db, APIExportRawHTTP, outDir, "/prod-api")
+	if err != nil || len(files) != 1 {
+		t.Fatalf("raw files=%v err=%v", files, err)
+	}
+	data, _ = os.ReadFile(files[0])
+	raw := string(data)
+	for _, want := range []string{
+		"GET /prod-api/user/list?page= HTTP/1.1\r\nHost: 10.0.0.1:8080\r\nUser-Agent: test-ua\r\nCookie: sid=1\r\nX-Token: abc\r\n",
+		"POST /prod-api/user/1 HTTP/1.1\r\n",
+		"Content-Type: application/json\r\nContent-Length: 2\r\nConnection: close\r\n\r\n{}",
+	} {
+		if !strings.Contains(raw, want) {
+			t.Fatalf("raw export missing %q:\n%s", want, raw)
+		}
+	}
+
+	if _, err := ExportAPIs(db, "har", outDir, ""); err == nil {
+		t.Fatalf("expected unknown format error")
+	}
+}
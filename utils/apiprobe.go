@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Response classes assigned by ClassifyAPIResponse.
+const (
+	APIClassAuthRequired  = "auth-required"
+	APIClassJSONData      = "json-data"
+	APIClassErrorLeak     = "error-leak"
+	APIClassLoginRedirect = "login-redirect"
+	APIClassRedirect      = "redirect"
+	APIClassNotFound      = "not-found"
+	APIClassOther         = "other"
+)
+
+const apiProbeSniffBytes = 4096
+
+var (
+	apiAuthRe      = regexp.MustCompile(`(?i)(unauthori[sz]ed|not\s*log(ged)?\s*in|no\s*login|need\s*login|login\s*required|access\s*denied|permission\s*denied|(invalid|expired|missing)\s*token|token\s*(is\s*)?(invalid|expired|missing)|token\s*(不能为空|失效|过期|无效)|未登录|请登录|请先登录|登录超时|登录失效|登录过期|认证失败|无权限|没有权限|"code"\s*:\s*"?40[13]\b)`)
+	apiErrorLeakRe = regexp.MustCompile(`(?i)(\b([a-z_$][\w$]*\.)+[a-z_$]*exception\b|exception in thread|traceback \(most recent call last\)|stack\s?trace|\bat (java|javax|org|com|sun)\.[\w.$]+\(|sqlstate|sql syntax|whitelabel error page|java\.lang\.|<b>(warning|fatal error)</b>:)`)
+	apiLoginLocRe  = regexp.MustCompile(`(?i)(login|logon|signin|sign-in|sso|/cas/|oauth|/auth)`)
+	apiPasswordRe  = regexp.MustCompile(`(?i)type\s*=\s*["']?password`)
+	// POST is skipped for paths that look like they change state beyond a read.
+	apiDestructiveRe = regexp.MustCompile(`(?i)(delete|remove|drop|destroy|truncate|clear|reset|logout|signout|shutdown|restart|reboot|kill|disable|update|modify|edit|upload|import|batch)`)
+)
+
+// APIProbeResult is one replay of an api path with or without the user's -H headers.
+type APIProbeResult struct {
+	RootURL     string `json:"root_url"`
+	Path        string `json:"path"`
+	Method      string `json:"method"`
+	Auth        bool   `json:"auth"`
+	Status      int    `json:"status"`
+	Length      int    `json:"length"`
+	ContentType string `json:"content_type"`
+	Location    string `json:"location"`
+	Class       string `json:"class"`
+	// Unauth marks data returned without credentials.
+	Unauth  bool   `json:"unauth"`
+	Snippet string `json:"snippet"`
+}
+
+// IsDestructiveAPIPath reports whether POSTing to the path could plausibly change state.
+func IsDestructiveAPIPath(path string) bool {
+	return apiDestructiveRe.MatchString(path)
+}
+
+// ClassifyAPIResponse buckets a response into one of the APIClass* values.
+func ClassifyAPIResponse(status int, contentType, location string, body []byte) string {
+	sniff := body
+	if len(sniff) > apiProbeSniffBytes {
+		sniff = sniff[:apiProbeSniffBytes]
+	}
+	switch {
+	case isRedirect(status):
+		if apiLoginLocRe.MatchString(location) {
+			return APIClassLoginRedirect
+		}
+		return APIClassRedirect
+	case status == 401 || status == 403:
+		return APIClassAuthRequired
+	case status == 404 || status == 405:
+		return APIClassNotFound
+	}
+	if apiAuthRe.Match(sniff) {
+		return APIClassAuthRequired
+	}
+	if apiErrorLeakRe.Match(sniff) {
+		return APIClassErrorLeak
+	}
+	if status >= 500 {
+		return APIClassOther
+	}
+	if status >= 200 && status < 300 {
+		if strings.Contains(strings.ToLower(contentType), "json") || looksLikeJSON(sniff) {
+			return APIClassJSONData
+		}
+		if apiPasswordRe.Match(sniff) {
+			return APIClassLoginRedirect
+		}
+	}
+	return APIClassOther
+}
+
+// ProbeAPI requests root+path without following redirects. With auth the global -H headers
+// are sent; without it only the User-Agent is. POST sends an empty json object.
+func ProbeAPI(rootURL, path, method string, auth bool) (APIProbeResult, error) {
+	res := APIProbeResult{RootURL: rootURL, Path: path, Method: method, Auth: auth}
+	var body io.Reader
+	if method == http.MethodPost {
+		body = strings.NewReader("{}")
+	}
+	req, err := http.NewRequest(method, strings.TrimRight(rootURL, "/")+"/"+strings.TrimLeft(path, "/"), body)
+	if err != nil {
+		return res, err
+	}
+	if auth {
+		SetHeaders(req)
+	} else {
+		req.Header.Set("User-Agent", viper.GetString("DefaultUA"))
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := enforceNoRedirectClient(ClientNoRedirect).Do(req)
+	if err != nil {
+		return res, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	res.Status = resp.StatusCode
+	res.Length = len(data)
+	res.ContentType = resp.Header.Get("Content-Type")
+	res.Location = resp.Header.Get("Location")
+	res.Class = ClassifyAPIResponse(res.Status, res.ContentType, res.Location, data)
+	res.Unauth = !auth && res.Class == APIClassJSONData
+	res.Snippet = apiProbeSnippet(data, 200)
+	return res, nil
+}
+
+func apiProbeSnippet(data []byte, max int) string {
+	if len(data) > max {
+		data = data[:max]
+	}
+	return strings.Join(strings.Fields(strings.ToValidUTF8(string(data), "")), " ")
+}
+
+func SaveAPIProbes(db *sql.DB, results []APIProbeResult) error {
+	if db == nil || len(results) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO api_probes (root_url, path, method, auth, status, length, content_type, location, class, unauth, snippet, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range results {
+		if _, err := stmt.Exec(r.RootURL, r.Path, r.Method, r.Auth, r.Status, r.Length, r.ContentType, r.Location, r.Class, r.Unauth, r.Snippet); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadAPIProbes returns stored probe results; unauthOnly keeps the endpoints that returned data without credentials.
+func LoadAPIProbes(db *sql.DB, unauthOnly bool) ([]APIProbeResult, error) {
+	query := `SELECT root_url, path, method, auth, status, length, content_type, location, class, unauth, snippet FROM api_probes`
+	if unauthOnly {
+		query += ` WHERE unauth = 1`
+	}
+	rows, err := db.Query(query + ` ORDER BY root_url, path, method, auth`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []APIProbeResult
+	for rows.Next() {
+		var r APIProbeResult
+		if err := rows.Scan(&r.RootURL, &r.Path, &r.Method, &r.Auth, &r.Status, &r.Length, &r.ContentType, &r.Location, &r.Class, &r.Unauth, &r.Snippet); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
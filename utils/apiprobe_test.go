@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestClassifyAPIResponse(t *testing.T) {
+	cases := []struct {
+		status   int
+		ct, loc  string
+		body     string
+		expected string
+	}{
+		{200, "application/json", "", `{"code":0,"data":[{"id":1}]}`, APIClassJSONData},
+		{200, "application/json", "", `{"code":401,"msg":"未登录"}`, APIClassAuthRequired},
+		{200, "text/plain", "", `{"msg":"token is invalid"}`, APIClassAuthRequired},
+		{403, "text/html", "", `forbidden`, APIClassAuthRequired},
+		{302, "", "/cas/login?service=x", ``, APIClassLoginRedirect},
+		{302, "", "/index", ``, APIClassRedirect},
+		{500, "text/html", "", `<h1>Whitelabel Error Page</h1>`, APIClassErrorLeak},
+		{200, "application/json", "", `{"error":"java.lang.NullPointerException at com.acme.UserService.get(UserService.java:12)"}`, APIClassErrorLeak},
+		{200, "text/html", "", `<form><input type="password" name="pwd"></form>`, APIClassLoginRedirect},
+		{404, "text/html", "", `not found`, APIClassNotFound},
+	}
+	for _, c := range cases {
+		if got := ClassifyAPIResponse(c.status, c.ct, c.loc, []byte(c.body)); got != c.expected {
+			t.Errorf("classify(%d, %q) = %s, want %s", c.status, c.body, got, c.expected)
+		}
+	}
+}
+
+func TestProbeAPIUnauth(t *testing.T) {
+	srv := mustTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/public/list":
+			_, _ = w.Write([]byte(`{"rows":[{"name":"alice","phone":"13800000000"}]}`))
+		case "/api/user/info":
+			if r.Header.Get("Authorization") != "Bearer t" {
+				_, _ = w.Write([]byte(`{"code":401,"msg":"Unauthorized"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"name":"admin"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	oldClient, oldNoRedirect := Client, ClientNoRedirect
+	Client, ClientNoRedirect = srv.Client(), srv.Client()
+	defer func() {
+		Client, ClientNoRedirect = oldClient, oldNoRedirect
+	}()
+	oldHeaders := viper.GetStringSlice("headers")
+	viper.Set("headers", []string{"Authorization: Bearer t"})
+	defer viper.Set("headers", oldHeaders)
+
+	var results []APIProbeResult
+	for _, c := range []struct {
+		path string
+		auth bool
+	}{{"/api/public/list", false}, {"/api/user/info", false}, {"/api/user/info", true}} {
+		res, err := ProbeAPI(srv.URL, c.path, http.MethodGet, c.auth)
+		if err != nil {
+			t.Fatalf("probe %s: %v", c.path, err)
+		}
+		results = append(results, res)
+	}
+	if !results[0].Unauth || results[1].Class != APIClassAuthRequired || results[1].Unauth {
+		t.Fatalf("unexpected unauth classification: %+v", results[:2])
+	}
+	if results[2].Class != APIClassJSONData || results[2].Unauth {
+		t.Fatalf("authenticated data must not be flagged: %+v", results[2])
+	}
+
+	db, err := InitSpiderDB(filepath.Join(t.TempDir(), "spider.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	defer db.Close()
+	if err := SaveAPIProbes(db, results); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	flagged, err := LoadAPIProbes(db, true)
+	if err != nil || len(flagged) != 1 || flagged[0].Path != "/api/public/list" {
+		t.Fatalf("flagged=%+v err=%v", flagged, err)
+	}
+}
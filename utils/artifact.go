@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// maxArtifactBytes bounds backup archive downloads; heap dumps are streamed and not bounded.
+const maxArtifactBytes = 1024 * 1024 * 1024
+
+var artifactSeen sync.Map
+
+// IsArtifactPath reports whether a dirbrute path names a heap dump or a backup archive.
+func IsArtifactPath(p string) bool {
+	return IsHeapDumpName(p) || archiveKind(p) != ""
+}
+
+// MaybeScanArtifact runs ScanArtifact once per url when --artifact-scan is on.
+func MaybeScanArtifact(artifactURL string) {
+	if !viper.GetBool("artifact-scan") {
+		return
+	}
+	if _, loaded := artifactSeen.LoadOrStore(artifactURL, true); loaded {
+		return
+	}
+	u, err := url.Parse(artifactURL)
+	if err != nil {
+		return
+	}
+	saveDir := hostOutputDir(u, "artifacts")
+	st, err := ScanArtifact(artifactURL, saveDir, GetSpiderDB())
+	if err != nil {
+		Warning("artifact %s: %v", artifactURL, err)
+		return
+	}
+	Success("artifact %s: %s", artifactURL, st)
+}
+
+// ScanArtifact downloads an exposed heap dump or backup archive and greps it with the secret
+// rules; hits are stored with the artifact url (plus "!/member" inside archives) as the source.
+func ScanArtifact(artifactURL, saveDir string, db *sql.DB) (LocalScanStats, error) {
+	resp, err := fetchGet(artifactURL)
+	if err != nil {
+		return LocalScanStats{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return LocalScanStats{}, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	br := bufio.NewReaderSize(resp.Body, 64*1024)
+	head, _ := br.Peek(512)
+	kind := artifactKind(artifactURL, head)
+	if kind == "" {
+		return LocalScanStats{}, fmt.Errorf("not a heap dump or archive (content-type %q)", resp.Header.Get("Content-Type"))
+	}
+	s := &localScanner{
+		root:     artifactURL,
+		saveDir:  saveDir,
+		maxBytes: DefaultLocalMaxBytes,
+		db:       db,
+		maps:     make(map[string]int),
+	}
+	s.stats.RootURL = artifactURL
+	if kind == "hprof" {
+		s.scanHeapDump(artifactURL, br)
+		return s.stats, nil
+	}
+
+	// zip needs random access, so archives are kept on disk next to the other host output
+	if err := os.MkdirAll(saveDir, 0o755); err != nil {
+		return s.stats, err
+	}
+	u, _ := url.Parse(artifactURL)
+	name := strings.NewReplacer(":", "_", "/", "_").Replace(u.Host) + "_" + path.Base(u.Path)
+	if archiveKind(name) != kind {
+		name += map[string]string{"zip": ".zip", "tgz": ".tar.gz", "tar": ".tar"}[kind]
+	}
+	local := filepath.Join(saveDir, name)
+	f, err := os.Create(local)
+	if err != nil {
+		return s.stats, err
+	}
+	n, err := io.Copy(f, io.LimitReader(br, maxArtifactBytes+1))
+	f.Close()
+	if err != nil {
+		return s.stats, err
+	}
+	if n > maxArtifactBytes {
+		return s.stats, fmt.Errorf("larger than %d bytes, kept the truncated copy at %s", maxArtifactBytes, local)
+	}
+	Info("artifact %s saved to %s (%d bytes)", artifactURL, local, n)
+	s.scanArchive(local, artifactURL+"!/", n)
+	s.saveSourceMapRefs()
+	return s.stats, nil
+}
+
+// artifactKind tells "hprof" or the archiveKind of a download from its magic bytes, so that
+// soft-404 pages served with status 200 are not mistaken for artifacts.
+func artifactKind(artifactURL string, head []byte) string {
+	p := artifactURL
+	if u, err := url.Parse(artifactURL); err == nil {
+		p = u.Path
+	}
+	gzipped := bytes.HasPrefix(head, []byte{0x1f, 0x8b})
+	switch {
+	case bytes.HasPrefix(head, hprofMagic), IsHeapDumpName(p) && gzipped:
+		return "hprof"
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return "zip"
+	case gzipped && archiveKind(p) == "tgz":
+		return "tgz"
+	case len(head) >= 262 && string(head[257:262]) == "ustar" && archiveKind(p) == "tar":
+		return "tar"
+	}
+	return ""
+}
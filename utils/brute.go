@@ -0,0 +1,367 @@
+package utils
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godspeedcurry/godscan/common"
+)
+
+var (
+	// errBruteAuth is a plain wrong username/password: try the next pair.
+	errBruteAuth = errors.New("authentication failed")
+	// errBruteLocked means the account or our address got blocked: stop the target.
+	errBruteLocked = errors.New("locked out")
+	// errBruteUnsupported means the service cannot be brute forced this way: stop the target.
+	errBruteUnsupported = errors.New("unsupported")
+)
+
+// bruteLogin tries one pair over a fresh connection. A nil error is a valid login, note
+// optionally describes it (e.g. "no password set").
+type bruteLogin func(conn net.Conn, user, pass string) (note string, err error)
+
+type bruteProtocol struct {
+	port int
+	// users is the common.Userdict key, passwordOnly services (redis) only try passwords
+	users        string
+	passwordOnly bool
+	login        bruteLogin
+}
+
+var bruteProtocols = map[string]bruteProtocol{
+	"ftp":        {port: 21, users: "ftp", login: bruteFTP},
+	"ssh":        {port: 22, users: "ssh", login: bruteSSH},
+	"mysql":      {port: 3306, users: "mysql", login: bruteMySQL},
+	"postgresql": {port: 5432, users: "postgresql", login: brutePostgres},
+	"redis":      {port: 6379, passwordOnly: true, login: bruteRedis},
+	"mongodb":    {port: 27017, users: "mongodb", login: bruteMongo},
+	"mssql":      {port: 1433, users: "mssql", login: bruteMSSQL},
+}
+
+// bruteServiceAlias maps nmap service names and url schemes onto bruteProtocols keys.
+var bruteServiceAlias = map[string]string{
+	"postgres":  "postgresql",
+	"pgsql":     "postgresql",
+	"mongo":     "mongodb",
+	"ms-sql-s":  "mssql",
+	"sqlserver": "mssql",
+}
+
+// BruteServices lists the supported service names.
+func BruteServices() []string {
+	var out []string
+	for k := range bruteProtocols {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func bruteServiceName(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if alias, ok := bruteServiceAlias[s]; ok {
+		return alias
+	}
+	if _, ok := bruteProtocols[s]; ok {
+		return s
+	}
+	return ""
+}
+
+func bruteServiceByPort(port int) string {
+	for name, p := range bruteProtocols {
+		if p.port == port {
+			return name
+		}
+	}
+	return ""
+}
+
+type BruteTarget struct {
+	Host    string
+	Port    int
+	Service string
+}
+
+func (t BruteTarget) Addr() string {
+	return net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+}
+
+// ParseBruteTargets reads "host:port", "service://host:port" or "host" lines. service, when
+// set, overrides the port based guess and gives the default port of bare hosts.
+func ParseBruteTargets(lines []string, service string) ([]BruteTarget, error) {
+	service = bruteServiceName(service)
+	var out []BruteTarget
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		svc := service
+		if scheme, rest, ok := strings.Cut(line, "://"); ok {
+			if svc == "" {
+				svc = bruteServiceName(scheme)
+			}
+			line = strings.TrimSuffix(rest, "/")
+		}
+		host, portStr, err := net.SplitHostPort(line)
+		if err != nil {
+			host, portStr = strings.Trim(line, "[]"), ""
+		}
+		port, _ := strconv.Atoi(portStr)
+		if svc == "" {
+			svc = bruteServiceByPort(port)
+		}
+		if svc == "" {
+			return nil, fmt.Errorf("cannot tell the service of %q, use service://host:port", line)
+		}
+		if port == 0 {
+			port = bruteProtocols[svc].port
+		}
+		out = append(out, BruteTarget{Host: host, Port: port, Service: svc})
+	}
+	return out, nil
+}
+
+// BruteTargetsFromServices picks the brute-forceable services out of port scan results.
+func BruteTargetsFromServices(rows []ServiceResultRow) []BruteTarget {
+	seen := map[string]bool{}
+	var out []BruteTarget
+	for _, r := range rows {
+		if r.Protocol != "" && r.Protocol != "tcp" {
+			continue
+		}
+		svc := bruteServiceName(r.Service)
+		if svc == "" && r.Service == "" {
+			svc = bruteServiceByPort(r.Port)
+		}
+		if svc == "" {
+			continue
+		}
+		t := BruteTarget{Host: r.IP, Port: r.Port, Service: svc}
+		if !seen[t.Addr()] {
+			seen[t.Addr()] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+type BruteOptions struct {
+	// Users and Passwords replace common.Userdict and common.Passwords when set.
+	Users     []string
+	Passwords []string
+	Threads   int
+	// Rate caps attempts per second over all targets, Delay waits between attempts on one target.
+	Rate  int
+	Delay time.Duration
+	// MaxPerUser caps attempts per account to stay under lockout policies, 0 for no cap.
+	MaxPerUser int
+	// MaxErrors consecutive connection/protocol errors give up a target.
+	MaxErrors int
+	Timeout   time.Duration
+	// StopOnSuccess stops a target at its first valid pair instead of trying the other users.
+	StopOnSuccess bool
+}
+
+type BruteResult struct {
+	Target   string
+	Service  string
+	Username string
+	Password string
+	Note     string
+}
+
+// RunBrute tries username x password pairs against every target and saves valid ones to db.
+func RunBrute(targets []BruteTarget, opts BruteOptions, db *sql.DB) []BruteResult {
+	if opts.Threads <= 0 {
+		opts.Threads = 10
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.MaxErrors <= 0 {
+		opts.MaxErrors = 5
+	}
+	var tick <-chan time.Time
+	if opts.Rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(opts.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	var (
+		mu  sync.Mutex
+		out []BruteResult
+		wg  sync.WaitGroup
+	)
+	ch := make(chan BruteTarget)
+	for i := 0; i < opts.Threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range ch {
+				res := bruteTarget(t, opts, tick)
+				if err := SaveBruteResults(db, res); err != nil {
+					Error("save brute results failed: %v", err)
+				}
+				mu.Lock()
+				out = append(out, res...)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, t := range targets {
+		ch <- t
+	}
+	close(ch)
+	wg.Wait()
+	return out
+}
+
+func bruteTarget(t BruteTarget, opts BruteOptions, tick <-chan time.Time) []BruteResult {
+	proto, ok := bruteProtocols[t.Service]
+	if !ok {
+		Warning("brute %s: unsupported service %q", t.Addr(), t.Service)
+		return nil
+	}
+	users := opts.Users
+	if proto.passwordOnly {
+		users = []string{""}
+	} else if len(users) == 0 {
+		users = common.Userdict[proto.users]
+	}
+	passwords := opts.Passwords
+	if len(passwords) == 0 {
+		passwords = common.Passwords
+	}
+	dialer := buildPortDialer(opts.Timeout)
+	var out []BruteResult
+	errs := 0
+	for _, user := range users {
+		// empty and username-as-password go first, they are the most common misconfigurations
+		candidates := bruteCandidates(user, passwords)
+		tries := 0
+		for i := 0; i < len(candidates); i++ {
+			if opts.MaxPerUser > 0 && tries >= opts.MaxPerUser {
+				break
+			}
+			if tick != nil {
+				<-tick
+			}
+			if opts.Delay > 0 && (tries > 0 || errs > 0) {
+				time.Sleep(opts.Delay)
+			}
+			pass := candidates[i]
+			note, err := bruteAttempt(t, proto, dialer, opts.Timeout, user, pass)
+			switch {
+			case err == nil:
+				errs = 0
+				r := BruteResult{Target: t.Addr(), Service: t.Service, Username: user, Password: pass, Note: note}
+				Success("[brute] %s %s %s:%s %s", t.Service, t.Addr(), user, pass, note)
+				out = append(out, r)
+				if opts.StopOnSuccess || proto.passwordOnly || note != "" {
+					return out
+				}
+				i = len(candidates)
+			case errors.Is(err, errBruteAuth):
+				errs = 0
+				tries++
+			case errors.Is(err, errBruteLocked), errors.Is(err, errBruteUnsupported):
+				Warning("brute %s %s: %v, giving up", t.Service, t.Addr(), err)
+				return out
+			default:
+				// retry the same pair, servers throttle new connections (ssh MaxStartups, ftp 421)
+				errs++
+				Debug("brute %s %s %s: %v", t.Service, t.Addr(), user, err)
+				if errs >= opts.MaxErrors {
+					Warning("brute %s %s: %d errors in a row (%v), giving up", t.Service, t.Addr(), errs, err)
+					return out
+				}
+				i--
+				time.Sleep(time.Duration(errs) * time.Second)
+			}
+		}
+	}
+	return out
+}
+
+// bruteCandidates keeps the password order and whitespace, unlike RemoveDuplicatesString.
+func bruteCandidates(user string, passwords []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, p := range append([]string{"", user}, passwords...) {
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+type bruteDialer interface {
+	Dial(network, addr string) (net.Conn, error)
+}
+
+func bruteAttempt(t BruteTarget, proto bruteProtocol, dialer bruteDialer, timeout time.Duration, user, pass string) (string, error) {
+	var conn net.Conn
+	var err error
+	if dialer != nil {
+		conn, err = dialer.Dial("tcp", t.Addr())
+	} else {
+		conn, err = net.DialTimeout("tcp", t.Addr(), timeout)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * timeout))
+	return proto.login(conn, user, pass)
+}
+
+// SaveBruteResults keeps one row per target/service/username.
+func SaveBruteResults(db *sql.DB, results []BruteResult) error {
+	if db == nil || len(results) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO brute_results (target, service, username, password, note, created_at) VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range results {
+		if _, err := stmt.Exec(r.Target, r.Service, r.Username, r.Password, r.Note); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func LoadBruteResults(db *sql.DB) ([]BruteResult, error) {
+	rows, err := db.Query(`SELECT target, service, username, password, note FROM brute_results ORDER BY service, target, username`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []BruteResult
+	for rows.Next() {
+		var r BruteResult
+		if err := rows.Scan(&r.Target, &r.Service, &r.Username, &r.Password, &r.Note); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
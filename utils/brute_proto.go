@@ -0,0 +1,1092 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ---- ftp ----
+
+func bruteFTP(conn net.Conn, user, pass string) (string, error) {
+	r := bufio.NewReader(conn)
+	code, msg, err := ftpReply(r)
+	if err != nil {
+		return "", err
+	}
+	if code != 220 {
+		return "", ftpError(code, msg)
+	}
+	fmt.Fprintf(conn, "USER %s\r\n", user)
+	if code, msg, err = ftpReply(r); err != nil {
+		return "", err
+	}
+	switch code {
+	case 230:
+		return "no password required", nil
+	case 331, 332:
+	default:
+		return "", ftpError(code, msg)
+	}
+	fmt.Fprintf(conn, "PASS %s\r\n", pass)
+	if code, msg, err = ftpReply(r); err != nil {
+		return "", err
+	}
+	if code == 230 || code == 202 {
+		return "", nil
+	}
+	return "", ftpError(code, msg)
+}
+
+// ftpReply reads one possibly multi-line ("230-...\r\n230 ...") reply.
+func ftpReply(r *bufio.Reader) (int, string, error) {
+	var code int
+	var msg []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return 0, "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if len(line) < 3 {
+			continue
+		}
+		c, err := strconv.Atoi(line[:3])
+		if err != nil {
+			msg = append(msg, line)
+			continue
+		}
+		if code == 0 {
+			code = c
+		}
+		msg = append(msg, strings.TrimSpace(line[3:]))
+		if c == code && (len(line) == 3 || line[3] == ' ') {
+			return code, strings.Join(msg, " "), nil
+		}
+	}
+}
+
+func ftpError(code int, msg string) error {
+	lower := strings.ToLower(msg)
+	switch {
+	case code == 530:
+		return fmt.Errorf("%w: %s", errBruteAuth, msg)
+	case strings.Contains(lower, "ban") || strings.Contains(lower, "block"):
+		return fmt.Errorf("%w: %d %s", errBruteLocked, code, msg)
+	}
+	return fmt.Errorf("ftp %d %s", code, msg)
+}
+
+// ---- redis ----
+
+func bruteRedis(conn net.Conn, user, pass string) (string, error) {
+	r := bufio.NewReader(conn)
+	if pass == "" {
+		// the empty candidate checks whether auth is needed at all
+		conn.Write(redisCommand("PING"))
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(line, "+PONG") {
+			return "no password set", nil
+		}
+		return "", redisError(line)
+	}
+	if user != "" {
+		conn.Write(redisCommand("AUTH", user, pass))
+	} else {
+		conn.Write(redisCommand("AUTH", pass))
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(line, "+OK") {
+		return "", nil
+	}
+	lower := strings.ToLower(line)
+	if strings.Contains(lower, "no password is set") || strings.Contains(lower, "without any password configured") {
+		return "no password set", nil
+	}
+	return "", redisError(line)
+}
+
+func redisCommand(args ...string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	return b.Bytes()
+}
+
+func redisError(line string) error {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, "-NOAUTH"), strings.HasPrefix(line, "-WRONGPASS"), strings.Contains(strings.ToLower(line), "invalid password"):
+		return fmt.Errorf("%w: %s", errBruteAuth, line)
+	case strings.HasPrefix(line, "-DENIED"):
+		// protected mode: only loopback clients are accepted
+		return fmt.Errorf("%w: %s", errBruteUnsupported, line)
+	}
+	return fmt.Errorf("redis: %s", line)
+}
+
+// ---- mysql ----
+
+const (
+	mysqlClientLongPassword     = 0x00000001
+	mysqlClientProtocol41       = 0x00000200
+	mysqlClientTransactions     = 0x00002000
+	mysqlClientSecureConnection = 0x00008000
+	mysqlClientPluginAuth       = 0x00080000
+)
+
+type mysqlConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	seq  byte
+}
+
+func (c *mysqlConn) read() ([]byte, error) {
+	var head [4]byte
+	if _, err := io.ReadFull(c.r, head[:]); err != nil {
+		return nil, err
+	}
+	n := int(head[0]) | int(head[1])<<8 | int(head[2])<<16
+	c.seq = head[3] + 1
+	buf := make([]byte, n)
+	_, err := io.ReadFull(c.r, buf)
+	return buf, err
+}
+
+func (c *mysqlConn) write(payload []byte) error {
+	head := []byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), c.seq}
+	c.seq++
+	_, err := c.conn.Write(append(head, payload...))
+	return err
+}
+
+func bruteMySQL(conn net.Conn, user, pass string) (string, error) {
+	c := &mysqlConn{conn: conn, r: bufio.NewReader(conn)}
+	pkt, err := c.read()
+	if err != nil {
+		return "", err
+	}
+	if len(pkt) > 0 && pkt[0] == 0xff {
+		return "", mysqlError(pkt)
+	}
+	scramble, plugin, err := parseMySQLHandshake(pkt)
+	if err != nil {
+		return "", err
+	}
+	auth, err := mysqlAuthData(plugin, pass, scramble)
+	if err != nil {
+		return "", err
+	}
+	var resp bytes.Buffer
+	binary.Write(&resp, binary.LittleEndian, uint32(mysqlClientLongPassword|mysqlClientProtocol41|mysqlClientTransactions|mysqlClientSecureConnection|mysqlClientPluginAuth))
+	binary.Write(&resp, binary.LittleEndian, uint32(1<<24))
+	resp.WriteByte(45) // utf8mb4_general_ci
+	resp.Write(make([]byte, 23))
+	resp.WriteString(user + "\x00")
+	resp.WriteByte(byte(len(auth)))
+	resp.Write(auth)
+	resp.WriteString(plugin + "\x00")
+	if err := c.write(resp.Bytes()); err != nil {
+		return "", err
+	}
+	for {
+		pkt, err := c.read()
+		if err != nil {
+			return "", err
+		}
+		if len(pkt) == 0 {
+			return "", fmt.Errorf("mysql: empty packet")
+		}
+		switch pkt[0] {
+		case 0x00:
+			return "", nil
+		case 0xff:
+			return "", mysqlError(pkt)
+		case 0xfe:
+			// auth switch request: plugin name, then a new scramble
+			name, data, _ := bytes.Cut(pkt[1:], []byte{0})
+			plugin = string(name)
+			scramble = bytes.TrimSuffix(data, []byte{0})
+			auth, err := mysqlAuthData(plugin, pass, scramble)
+			if err != nil {
+				return "", err
+			}
+			if err := c.write(auth); err != nil {
+				return "", err
+			}
+		case 0x01:
+			if len(pkt) < 2 {
+				return "", fmt.Errorf("mysql: short auth more data")
+			}
+			switch pkt[1] {
+			case 3: // caching_sha2 fast auth ok, OK packet follows
+			case 4: // full auth: without tls the password goes rsa encrypted with the server key
+				if err := c.write([]byte{2}); err != nil {
+					return "", err
+				}
+				keyPkt, err := c.read()
+				if err != nil {
+					return "", err
+				}
+				if len(keyPkt) == 0 || keyPkt[0] != 0x01 {
+					return "", fmt.Errorf("mysql: no public key")
+				}
+				enc, err := mysqlRSAPassword(keyPkt[1:], pass, scramble)
+				if err != nil {
+					return "", err
+				}
+				if err := c.write(enc); err != nil {
+					return "", err
+				}
+			default:
+				return "", fmt.Errorf("mysql: unexpected auth data %x", pkt[1])
+			}
+		default:
+			return "", fmt.Errorf("mysql: unexpected packet %x", pkt[0])
+		}
+	}
+}
+
+func parseMySQLHandshake(pkt []byte) ([]byte, string, error) {
+	if len(pkt) < 1 || pkt[0] != 10 {
+		return nil, "", fmt.Errorf("mysql: unsupported handshake")
+	}
+	i := bytes.IndexByte(pkt[1:], 0)
+	if i < 0 {
+		return nil, "", fmt.Errorf("mysql: bad handshake")
+	}
+	p := pkt[1+i+1:]
+	// conn id(4) scramble1(8) filler(1) caps(2) charset(1) status(2) caps(2) authlen(1) reserved(10)
+	if len(p) < 31 {
+		return nil, "", fmt.Errorf("mysql: short handshake")
+	}
+	scramble := append([]byte(nil), p[4:12]...)
+	authLen := int(p[20])
+	rest := p[31:]
+	n := authLen - 8
+	if n < 13 {
+		n = 13
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	scramble = append(scramble, bytes.TrimSuffix(rest[:n], []byte{0})...)
+	plugin := "mysql_native_password"
+	if name, _, ok := bytes.Cut(rest[n:], []byte{0}); ok && len(name) > 0 {
+		plugin = string(name)
+	}
+	return scramble, plugin, nil
+}
+
+func mysqlAuthData(plugin, pass string, scramble []byte) ([]byte, error) {
+	if pass == "" {
+		return nil, nil
+	}
+	if len(scramble) < 20 {
+		return nil, fmt.Errorf("mysql: short scramble")
+	}
+	switch plugin {
+	case "mysql_native_password":
+		// SHA1(pass) XOR SHA1(scramble + SHA1(SHA1(pass)))
+		h1 := sha1.Sum([]byte(pass))
+		h2 := sha1.Sum(h1[:])
+		h3 := sha1.Sum(append(append([]byte(nil), scramble[:20]...), h2[:]...))
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		return h1[:], nil
+	case "caching_sha2_password":
+		// SHA256(pass) XOR SHA256(SHA256(SHA256(pass)) + scramble)
+		h1 := sha256.Sum256([]byte(pass))
+		h2 := sha256.Sum256(h1[:])
+		h3 := sha256.Sum256(append(h2[:], scramble[:20]...))
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		return h1[:], nil
+	}
+	return nil, fmt.Errorf("%w: mysql auth plugin %s", errBruteUnsupported, plugin)
+}
+
+func mysqlRSAPassword(pemKey []byte, pass string, scramble []byte) ([]byte, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, fmt.Errorf("mysql: bad public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("mysql: public key is not rsa")
+	}
+	plain := []byte(pass + "\x00")
+	for i := range plain {
+		plain[i] ^= scramble[i%len(scramble)]
+	}
+	return rsa.EncryptOAEP(sha1.New(), rand.Reader, pub, plain, nil)
+}
+
+func mysqlError(pkt []byte) error {
+	if len(pkt) < 3 {
+		return fmt.Errorf("mysql: bad error packet")
+	}
+	code := binary.LittleEndian.Uint16(pkt[1:])
+	msg := string(pkt[3:])
+	if strings.HasPrefix(msg, "#") && len(msg) > 6 {
+		msg = msg[6:]
+	}
+	switch code {
+	case 1045:
+		return fmt.Errorf("%w: %s", errBruteAuth, msg)
+	case 1129, 3118:
+		// host blocked by max_connect_errors, account locked
+		return fmt.Errorf("%w: %s", errBruteLocked, msg)
+	case 1130:
+		return fmt.Errorf("%w: %s", errBruteUnsupported, msg)
+	}
+	return fmt.Errorf("mysql %d: %s", code, msg)
+}
+
+// ---- scram (postgresql, mongodb) ----
+
+type scramClient struct {
+	hash     func() hash.Hash
+	user     string
+	pass     string
+	nonce    string
+	first    string
+	auth     string
+	saltedPw []byte
+}
+
+func newScram(h func() hash.Hash, user, pass string) *scramClient {
+	b := make([]byte, 18)
+	rand.Read(b)
+	user = strings.NewReplacer("=", "=3D", ",", "=2C").Replace(user)
+	s := &scramClient{hash: h, user: user, pass: pass, nonce: base64.StdEncoding.EncodeToString(b)}
+	s.first = "n=" + user + ",r=" + s.nonce
+	return s
+}
+
+func (s *scramClient) clientFirst() string {
+	return "n,," + s.first
+}
+
+func (s *scramClient) hmac(key []byte, msg string) []byte {
+	m := hmac.New(s.hash, key)
+	m.Write([]byte(msg))
+	return m.Sum(nil)
+}
+
+// clientFinal answers server-first ("r=..,s=..,i=..") with the proof.
+func (s *scramClient) clientFinal(serverFirst string) (string, error) {
+	attrs := scramAttrs(serverFirst)
+	nonce, salt64, iter := attrs["r"], attrs["s"], attrs["i"]
+	if !strings.HasPrefix(nonce, s.nonce) {
+		return "", fmt.Errorf("scram: bad server nonce")
+	}
+	salt, err := base64.StdEncoding.DecodeString(salt64)
+	if err != nil {
+		return "", fmt.Errorf("scram: bad salt")
+	}
+	n, err := strconv.Atoi(iter)
+	if err != nil || n <= 0 {
+		return "", fmt.Errorf("scram: bad iteration count")
+	}
+	s.saltedPw, err = pbkdf2.Key(s.hash, s.pass, salt, n, s.hash().Size())
+	if err != nil {
+		return "", err
+	}
+	withoutProof := "c=biws,r=" + nonce
+	s.auth = s.first + "," + serverFirst + "," + withoutProof
+	clientKey := s.hmac(s.saltedPw, "Client Key")
+	h := s.hash()
+	h.Write(clientKey)
+	sig := s.hmac(h.Sum(nil), s.auth)
+	for i := range clientKey {
+		clientKey[i] ^= sig[i]
+	}
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(clientKey), nil
+}
+
+// verify checks server-final ("v=..."); "e=..." is a rejected proof.
+func (s *scramClient) verify(serverFinal string) error {
+	attrs := scramAttrs(serverFinal)
+	if e, ok := attrs["e"]; ok {
+		return fmt.Errorf("%w: %s", errBruteAuth, e)
+	}
+	want := s.hmac(s.hmac(s.saltedPw, "Server Key"), s.auth)
+	if attrs["v"] != base64.StdEncoding.EncodeToString(want) {
+		return fmt.Errorf("scram: bad server signature")
+	}
+	return nil
+}
+
+func scramAttrs(msg string) map[string]string {
+	out := map[string]string{}
+	for _, part := range strings.Split(msg, ",") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// ---- postgresql ----
+
+func pgMessage(typ byte, body []byte) []byte {
+	out := []byte{typ, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(out[1:], uint32(len(body)+4))
+	return append(out, body...)
+}
+
+func pgRead(r *bufio.Reader) (byte, []byte, error) {
+	var head [5]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	n := int(binary.BigEndian.Uint32(head[1:])) - 4
+	if n < 0 || n > 1<<20 {
+		return 0, nil, fmt.Errorf("postgresql: bad message length")
+	}
+	body := make([]byte, n)
+	_, err := io.ReadFull(r, body)
+	return head[0], body, err
+}
+
+func brutePostgres(conn net.Conn, user, pass string) (string, error) {
+	var startup bytes.Buffer
+	binary.Write(&startup, binary.BigEndian, uint32(196608)) // protocol 3.0
+	startup.WriteString("user\x00" + user + "\x00database\x00postgres\x00\x00")
+	msg := make([]byte, 4, startup.Len()+4)
+	binary.BigEndian.PutUint32(msg, uint32(startup.Len()+4))
+	if _, err := conn.Write(append(msg, startup.Bytes()...)); err != nil {
+		return "", err
+	}
+	r := bufio.NewReader(conn)
+	var scram *scramClient
+	for {
+		typ, body, err := pgRead(r)
+		if err != nil {
+			return "", err
+		}
+		switch typ {
+		case 'E':
+			return pgError(body)
+		case 'R':
+			if len(body) < 4 {
+				return "", fmt.Errorf("postgresql: short auth message")
+			}
+			switch code := binary.BigEndian.Uint32(body); code {
+			case 0:
+				if pass == "" {
+					return "trust auth", nil
+				}
+				return "", nil
+			case 3:
+				conn.Write(pgMessage('p', []byte(pass+"\x00")))
+			case 5:
+				// md5(md5(pass + user) + salt)
+				inner := md5.Sum([]byte(pass + user))
+				outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), body[4:8]...))
+				conn.Write(pgMessage('p', []byte("md5"+hex.EncodeToString(outer[:])+"\x00")))
+			case 10:
+				if !bytes.Contains(body[4:], []byte("SCRAM-SHA-256\x00")) {
+					return "", fmt.Errorf("%w: postgresql sasl %q", errBruteUnsupported, body[4:])
+				}
+				scram = newScram(sha256.New, "", pass)
+				first := scram.clientFirst()
+				var b bytes.Buffer
+				b.WriteString("SCRAM-SHA-256\x00")
+				binary.Write(&b, binary.BigEndian, uint32(len(first)))
+				b.WriteString(first)
+				conn.Write(pgMessage('p', b.Bytes()))
+			case 11:
+				if scram == nil {
+					return "", fmt.Errorf("postgresql: unexpected sasl continue")
+				}
+				final, err := scram.clientFinal(string(body[4:]))
+				if err != nil {
+					return "", err
+				}
+				conn.Write(pgMessage('p', []byte(final)))
+			case 12:
+				if scram == nil {
+					return "", fmt.Errorf("postgresql: unexpected sasl final")
+				}
+				if err := scram.verify(string(body[4:])); err != nil {
+					return "", err
+				}
+			default:
+				return "", fmt.Errorf("%w: postgresql auth method %d", errBruteUnsupported, code)
+			}
+		}
+	}
+}
+
+func pgError(body []byte) (string, error) {
+	fields := map[byte]string{}
+	for _, f := range bytes.Split(body, []byte{0}) {
+		if len(f) > 1 {
+			fields[f[0]] = string(f[1:])
+		}
+	}
+	switch fields['C'] {
+	case "3D000":
+		// the database is checked after the password
+		return "database postgres missing", nil
+	case "28P01":
+		return "", fmt.Errorf("%w: %s", errBruteAuth, fields['M'])
+	case "28000":
+		// pg_hba.conf rejects this host/user/ssl combination
+		return "", fmt.Errorf("%w: %s", errBruteUnsupported, fields['M'])
+	}
+	return "", fmt.Errorf("postgresql %s: %s", fields['C'], fields['M'])
+}
+
+// ---- mongodb ----
+
+// bsonD is an ordered BSON document, the command name has to come first.
+type bsonD []bsonE
+
+type bsonE struct {
+	K string
+	V any
+}
+
+func (d bsonD) marshal() []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0, 0, 0, 0})
+	for _, e := range d {
+		switch v := e.V.(type) {
+		case float64:
+			b.WriteByte(0x01)
+			b.WriteString(e.K + "\x00")
+			binary.Write(&b, binary.LittleEndian, math.Float64bits(v))
+		case string:
+			b.WriteByte(0x02)
+			b.WriteString(e.K + "\x00")
+			binary.Write(&b, binary.LittleEndian, int32(len(v)+1))
+			b.WriteString(v + "\x00")
+		case bsonD:
+			b.WriteByte(0x03)
+			b.WriteString(e.K + "\x00")
+			b.Write(v.marshal())
+		case []byte:
+			b.WriteByte(0x05)
+			b.WriteString(e.K + "\x00")
+			binary.Write(&b, binary.LittleEndian, int32(len(v)))
+			b.WriteByte(0)
+			b.Write(v)
+		case bool:
+			b.WriteByte(0x08)
+			b.WriteString(e.K + "\x00")
+			if v {
+				b.WriteByte(1)
+			} else {
+				b.WriteByte(0)
+			}
+		case int:
+			b.WriteByte(0x10)
+			b.WriteString(e.K + "\x00")
+			binary.Write(&b, binary.LittleEndian, int32(v))
+		case int32:
+			b.WriteByte(0x10)
+			b.WriteString(e.K + "\x00")
+			binary.Write(&b, binary.LittleEndian, v)
+		}
+	}
+	b.WriteByte(0)
+	out := b.Bytes()
+	binary.LittleEndian.PutUint32(out, uint32(len(out)))
+	return out
+}
+
+// bsonUnmarshal decodes a document; arrays and sub-documents become maps too.
+func bsonUnmarshal(data []byte) (map[string]any, error) {
+	if len(data) < 5 || int(binary.LittleEndian.Uint32(data)) != len(data) {
+		return nil, fmt.Errorf("bson: bad document length")
+	}
+	out := map[string]any{}
+	p := data[4 : len(data)-1]
+	for len(p) > 0 {
+		typ := p[0]
+		i := bytes.IndexByte(p[1:], 0)
+		if i < 0 {
+			return nil, fmt.Errorf("bson: bad key")
+		}
+		key := string(p[1 : 1+i])
+		p = p[2+i:]
+		need := func(n int) error {
+			if n < 0 || len(p) < n {
+				return fmt.Errorf("bson: truncated %s", key)
+			}
+			return nil
+		}
+		var n int
+		switch typ {
+		case 0x01:
+			n = 8
+			if err := need(n); err != nil {
+				return nil, err
+			}
+			out[key] = math.Float64frombits(binary.LittleEndian.Uint64(p))
+		case 0x02:
+			if err := need(4); err != nil {
+				return nil, err
+			}
+			n = 4 + int(binary.LittleEndian.Uint32(p))
+			if err := need(n); err != nil || n < 5 {
+				return nil, fmt.Errorf("bson: bad string %s", key)
+			}
+			out[key] = string(p[4 : n-1])
+		case 0x03, 0x04:
+			if err := need(4); err != nil {
+				return nil, err
+			}
+			n = int(binary.LittleEndian.Uint32(p))
+			if err := need(n); err != nil {
+				return nil, err
+			}
+			sub, err := bsonUnmarshal(p[:n])
+			if err != nil {
+				return nil, err
+			}
+			out[key] = sub
+		case 0x05:
+			if err := need(5); err != nil {
+				return nil, err
+			}
+			n = 5 + int(binary.LittleEndian.Uint32(p))
+			if err := need(n); err != nil {
+				return nil, err
+			}
+			out[key] = append([]byte(nil), p[5:n]...)
+		case 0x07:
+			n = 12
+		case 0x08:
+			n = 1
+			if err := need(n); err != nil {
+				return nil, err
+			}
+			out[key] = p[0] != 0
+		case 0x09, 0x11:
+			n = 8
+		case 0x0a:
+			out[key] = nil
+		case 0x10:
+			n = 4
+			if err := need(n); err != nil {
+				return nil, err
+			}
+			out[key] = int32(binary.LittleEndian.Uint32(p))
+		case 0x12:
+			n = 8
+			if err := need(n); err != nil {
+				return nil, err
+			}
+			out[key] = int64(binary.LittleEndian.Uint64(p))
+		case 0x13:
+			n = 16
+		default:
+			return nil, fmt.Errorf("bson: unsupported type %#x", typ)
+		}
+		if err := need(n); err != nil {
+			return nil, err
+		}
+		p = p[n:]
+	}
+	return out, nil
+}
+
+func bsonNumber(v any) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case bool:
+		if n {
+			return 1
+		}
+	}
+	return 0
+}
+
+// mongoCommand runs cmd through OP_MSG (MongoDB 3.6+).
+func mongoCommand(conn net.Conn, cmd bsonD) (map[string]any, error) {
+	doc := cmd.marshal()
+	msg := make([]byte, 21, 21+len(doc))
+	binary.LittleEndian.PutUint32(msg[0:], uint32(21+len(doc)))
+	binary.LittleEndian.PutUint32(msg[4:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], 2013)
+	msg = append(msg, doc...)
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	var head [16]byte
+	if _, err := io.ReadFull(conn, head[:]); err != nil {
+		return nil, err
+	}
+	n := int(binary.LittleEndian.Uint32(head[:])) - 16
+	if n < 5 || n > 16<<20 || binary.LittleEndian.Uint32(head[12:]) != 2013 {
+		return nil, fmt.Errorf("mongodb: unexpected reply")
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		return nil, err
+	}
+	// flagBits(4), section kind 0, document
+	if body[4] != 0 {
+		return nil, fmt.Errorf("mongodb: unexpected section kind %d", body[4])
+	}
+	if len(body) < 9 {
+		return nil, fmt.Errorf("mongodb: short reply")
+	}
+	docLen := uint64(binary.LittleEndian.Uint32(body[5:]))
+	if docLen > uint64(len(body)-5) {
+		return nil, fmt.Errorf("mongodb: reply document overruns the message")
+	}
+	return bsonUnmarshal(body[5 : 5+docLen])
+}
+
+func mongoError(reply map[string]any) error {
+	msg, _ := reply["errmsg"].(string)
+	switch int(bsonNumber(reply["code"])) {
+	case 18, 13:
+		// AuthenticationFailed, Unauthorized
+		return fmt.Errorf("%w: %s", errBruteAuth, msg)
+	case 334:
+		return fmt.Errorf("%w: %s", errBruteUnsupported, msg)
+	}
+	return fmt.Errorf("mongodb %v: %s", reply["code"], msg)
+}
+
+func bruteMongo(conn net.Conn, user, pass string) (string, error) {
+	if pass == "" {
+		// mongodb has no empty passwords, the empty candidate checks for auth being disabled
+		reply, err := mongoCommand(conn, bsonD{{"listDatabases", 1}, {"nameOnly", true}, {"$db", "admin"}})
+		if err != nil {
+			return "", err
+		}
+		if bsonNumber(reply["ok"]) == 1 {
+			return "no auth required", nil
+		}
+		return "", mongoError(reply)
+	}
+	err := mongoSCRAM(conn, "SCRAM-SHA-256", sha256.New, user, pass)
+	if errors.Is(err, errBruteUnsupported) {
+		// users created before 4.0 only have SCRAM-SHA-1 credentials
+		sum := md5.Sum([]byte(user + ":mongo:" + pass))
+		err = mongoSCRAM(conn, "SCRAM-SHA-1", sha1.New, user, hex.EncodeToString(sum[:]))
+	}
+	return "", err
+}
+
+func mongoSCRAM(conn net.Conn, mech string, h func() hash.Hash, user, pass string) error {
+	s := newScram(h, user, pass)
+	reply, err := mongoCommand(conn, bsonD{{"saslStart", 1}, {"mechanism", mech}, {"payload", []byte(s.clientFirst())}, {"autoAuthorize", 1}, {"$db", "admin"}})
+	if err != nil {
+		return err
+	}
+	if bsonNumber(reply["ok"]) != 1 {
+		return mongoError(reply)
+	}
+	payload, _ := reply["payload"].([]byte)
+	final, err := s.clientFinal(string(payload))
+	if err != nil {
+		return err
+	}
+	reply, err = mongoCommand(conn, bsonD{{"saslContinue", 1}, {"conversationId", reply["conversationId"]}, {"payload", []byte(final)}, {"$db", "admin"}})
+	if err != nil {
+		return err
+	}
+	if bsonNumber(reply["ok"]) != 1 {
+		return mongoError(reply)
+	}
+	payload, _ = reply["payload"].([]byte)
+	if err := s.verify(string(payload)); err != nil {
+		return err
+	}
+	for i := 0; i < 3 && !(reply["done"] == true); i++ {
+		reply, err = mongoCommand(conn, bsonD{{"saslContinue", 1}, {"conversationId", reply["conversationId"]}, {"payload", []byte{}}, {"$db", "admin"}})
+		if err != nil {
+			return err
+		}
+		if bsonNumber(reply["ok"]) != 1 {
+			return mongoError(reply)
+		}
+	}
+	return nil
+}
+
+// ---- mssql ----
+
+const (
+	tdsPrelogin = 0x12
+	tdsLogin7   = 0x10
+	tdsReply    = 0x04
+
+	tdsEncryptOff    = 0x00
+	tdsEncryptNotSup = 0x02
+)
+
+func tdsPacket(typ byte, payload []byte) []byte {
+	out := []byte{typ, 0x01, 0, 0, 0, 0, 1, 0}
+	binary.BigEndian.PutUint16(out[2:], uint16(len(payload)+8))
+	return append(out, payload...)
+}
+
+// tdsReadMessage concatenates packets up to the end-of-message flag.
+func tdsReadMessage(r io.Reader) (byte, []byte, error) {
+	var out []byte
+	for {
+		var head [8]byte
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			return 0, nil, err
+		}
+		n := int(binary.BigEndian.Uint16(head[2:])) - 8
+		if n < 0 {
+			return 0, nil, fmt.Errorf("mssql: bad packet length")
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return 0, nil, err
+		}
+		out = append(out, buf...)
+		if head[1]&0x01 != 0 {
+			return head[0], out, nil
+		}
+	}
+}
+
+// tdsTLSConn carries the TLS handshake inside prelogin packets, then gets out of the way.
+type tdsTLSConn struct {
+	net.Conn
+	handshake bool
+	buf       []byte
+}
+
+func (c *tdsTLSConn) Read(p []byte) (int, error) {
+	if !c.handshake {
+		return c.Conn.Read(p)
+	}
+	if len(c.buf) == 0 {
+		_, payload, err := tdsReadMessage(c.Conn)
+		if err != nil {
+			return 0, err
+		}
+		c.buf = payload
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+func (c *tdsTLSConn) Write(p []byte) (int, error) {
+	if !c.handshake {
+		return c.Conn.Write(p)
+	}
+	if _, err := c.Conn.Write(tdsPacket(tdsPrelogin, p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func bruteMSSQL(conn net.Conn, user, pass string) (string, error) {
+	// prelogin: VERSION and ENCRYPTION options, then their data
+	pre := []byte{
+		0x00, 0, 11, 0, 6,
+		0x01, 0, 17, 0, 1,
+		0xff,
+		0x0f, 0, 0, 0, 0, 0,
+		tdsEncryptOff,
+	}
+	if _, err := conn.Write(tdsPacket(tdsPrelogin, pre)); err != nil {
+		return "", err
+	}
+	_, resp, err := tdsReadMessage(conn)
+	if err != nil {
+		return "", err
+	}
+	encrypt := byte(tdsEncryptNotSup)
+	for i := 0; i+5 <= len(resp) && resp[i] != 0xff; i += 5 {
+		off := int(binary.BigEndian.Uint16(resp[i+1:]))
+		if resp[i] == 0x01 && off < len(resp) {
+			encrypt = resp[off]
+		}
+	}
+
+	login := tdsLogin7Packet(user, pass)
+	var reader io.Reader = conn
+	if encrypt == tdsEncryptNotSup {
+		if _, err := conn.Write(login); err != nil {
+			return "", err
+		}
+	} else {
+		tc := &tdsTLSConn{Conn: conn, handshake: true}
+		tlsConn := tls.Client(tc, &tls.Config{InsecureSkipVerify: true, MaxVersion: tls.VersionTLS12})
+		if err := tlsConn.Handshake(); err != nil {
+			return "", fmt.Errorf("mssql tls: %w", err)
+		}
+		tc.handshake = false
+		if _, err := tlsConn.Write(login); err != nil {
+			return "", err
+		}
+		// ENCRYPT_OFF only protects the login packet
+		if encrypt != tdsEncryptOff {
+			reader = tlsConn
+		}
+	}
+	typ, reply, err := tdsReadMessage(reader)
+	if err != nil {
+		return "", err
+	}
+	if typ != tdsReply {
+		return "", fmt.Errorf("mssql: unexpected reply type %#x", typ)
+	}
+	return tdsLoginResult(reply)
+}
+
+func tdsUCS2(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+	return b
+}
+
+func tdsLogin7Packet(user, pass string) []byte {
+	host, _ := os.Hostname()
+	pw := tdsUCS2(pass)
+	for i, b := range pw {
+		pw[i] = (b<<4 | b>>4) ^ 0xa5
+	}
+	// HostName, UserName, Password, AppName, ServerName, Extension, CltIntName, Language, Database
+	fields := [][]byte{tdsUCS2(host), tdsUCS2(user), pw, tdsUCS2("godscan"), nil, nil, tdsUCS2("godscan"), nil, nil}
+	const fixed = 94
+	var b bytes.Buffer
+	b.Write(make([]byte, 4)) // length, patched below
+	binary.Write(&b, binary.LittleEndian, uint32(0x74000004))
+	binary.Write(&b, binary.LittleEndian, uint32(4096))
+	binary.Write(&b, binary.LittleEndian, uint32(0x07000000))
+	binary.Write(&b, binary.LittleEndian, uint32(os.Getpid()))
+	binary.Write(&b, binary.LittleEndian, uint32(0))
+	b.Write([]byte{0xe0, 0x03, 0x00, 0x00})
+	binary.Write(&b, binary.LittleEndian, int32(0))
+	binary.Write(&b, binary.LittleEndian, uint32(0x0409))
+	off := fixed
+	var data bytes.Buffer
+	for _, f := range fields {
+		binary.Write(&b, binary.LittleEndian, uint16(off))
+		binary.Write(&b, binary.LittleEndian, uint16(len(f)/2))
+		data.Write(f)
+		off += len(f)
+	}
+	b.Write(make([]byte, 6)) // ClientID
+	for i := 0; i < 3; i++ {
+		// SSPI, AtchDBFile, ChangePassword
+		binary.Write(&b, binary.LittleEndian, uint16(off))
+		binary.Write(&b, binary.LittleEndian, uint16(0))
+	}
+	binary.Write(&b, binary.LittleEndian, uint32(0)) // cbSSPILong
+	b.Write(data.Bytes())
+	payload := b.Bytes()
+	binary.LittleEndian.PutUint32(payload, uint32(len(payload)))
+	return tdsPacket(tdsLogin7, payload)
+}
+
+// tdsLoginResult walks the reply tokens up to LOGINACK or ERROR.
+func tdsLoginResult(p []byte) (string, error) {
+	for len(p) > 0 {
+		tok := p[0]
+		p = p[1:]
+		switch tok {
+		case 0xad: // LOGINACK
+			return "", nil
+		case 0xaa: // ERROR
+			if len(p) < 8 {
+				return "", fmt.Errorf("mssql: short error token")
+			}
+			num := binary.LittleEndian.Uint32(p[2:])
+			msg := ""
+			if len(p) >= 10 {
+				n := int(binary.LittleEndian.Uint16(p[8:])) * 2
+				if 10+n <= len(p) {
+					msg = tdsString(p[10 : 10+n])
+				}
+			}
+			switch num {
+			case 18456:
+				return "", fmt.Errorf("%w: %s", errBruteAuth, msg)
+			case 18486:
+				return "", fmt.Errorf("%w: %s", errBruteLocked, msg)
+			case 18487, 18488:
+				return "password expired", nil
+			case 4060, 4064:
+				return "default database unavailable", nil
+			}
+			return "", fmt.Errorf("mssql %d: %s", num, msg)
+		case 0xab, 0xe3: // INFO, ENVCHANGE
+			if len(p) < 2 {
+				return "", fmt.Errorf("mssql: short token")
+			}
+			n := 2 + int(binary.LittleEndian.Uint16(p))
+			if n > len(p) {
+				return "", fmt.Errorf("mssql: short token")
+			}
+			p = p[n:]
+		case 0xfd, 0xfe, 0xff: // DONE
+			if len(p) < 12 {
+				return "", fmt.Errorf("mssql: short done token")
+			}
+			p = p[12:]
+		default:
+			return "", fmt.Errorf("mssql: unexpected token %#x", tok)
+		}
+	}
+	return "", fmt.Errorf("mssql: no login ack")
+}
+
+func tdsString(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const sshClientVersion = "SSH-2.0-OpenSSH_8.9"
+
+// bruteSSH tries password, then keyboard-interactive auth answering every prompt with the
+// password (PAM setups often only offer the latter). The host key is not verified, nothing
+// but the auth result is ever trusted over this connection.
+func bruteSSH(conn net.Conn, user, pass string) (string, error) {
+	cfg := &ssh.ClientConfig{
+		User: user,
+		Auth: []ssh.AuthMethod{
+			ssh.Password(pass),
+			ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = pass
+				}
+				return answers, nil
+			}),
+		},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		ClientVersion:   sshClientVersion,
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, conn.RemoteAddr().String(), cfg)
+	if err == nil {
+		ssh.NewClient(c, chans, reqs).Close()
+		return "", nil
+	}
+	// SSH_MSG_USERAUTH_PASSWD_CHANGEREQ: the password is right but expired
+	if strings.Contains(err.Error(), "unexpected message type 60") {
+		return "password expired", nil
+	}
+	return "", sshAuthError(err)
+}
+
+// sshAuthError maps x/crypto/ssh handshake errors, which are plain strings, onto the brute errors.
+func sshAuthError(err error) error {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "unable to authenticate"):
+		// "attempted methods [none password], no supported methods remain"
+		if strings.Contains(msg, "password") || strings.Contains(msg, "keyboard-interactive") {
+			return fmt.Errorf("%w: ssh", errBruteAuth)
+		}
+		return fmt.Errorf("%w: %s", errBruteUnsupported, msg)
+	case strings.Contains(msg, "no common algorithm"):
+		return fmt.Errorf("%w: %s", errBruteUnsupported, msg)
+	}
+	return err
+}
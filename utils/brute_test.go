@@ -0,0 +1,619 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestScramVectors(t *testing.T) {
+	cases := []struct {
+		name        string
+		h           func() hash.Hash
+		nonce       string
+		serverFirst string
+		final       string
+		serverFinal string
+	}{
+		// RFC 5802 section 5
+		{"sha1", sha1.New, "fyko+d2lbbFgONRv9qkxdawL", "r=fyko+d2lbbFgONRv9qkxdawL3rfcNHYJY1ZVvWVs7j,s=QSXCR+Q6sek8bf92,i=4096",
+			"c=biws,r=fyko+d2lbbFgONRv9qkxdawL3rfcNHYJY1ZVvWVs7j,p=v0X8v3Bz2T0CJGbJQyF0X+HI4Ts=", "v=rmF9pqV8S7suAoZWja4dJRkFsKQ="},
+		// RFC 7677 section 3
+		{"sha256", sha256.New, "rOprNGfwEbeRWgbNEkqO", "r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096",
+			"c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=", "v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="},
+	}
+	for _, c := range cases {
+		s := newScram(c.h, "user", "pencil")
+		s.nonce, s.first = c.nonce, "n=user,r="+c.nonce
+		final, err := s.clientFinal(c.serverFirst)
+		if err != nil || final != c.final {
+			t.Errorf("%s: client final %q %v", c.name, final, err)
+		}
+		if err := s.verify(c.serverFinal); err != nil {
+			t.Errorf("%s: server final: %v", c.name, err)
+		}
+		if err := s.verify("e=invalid-proof"); err == nil || !strings.Contains(err.Error(), errBruteAuth.Error()) {
+			t.Errorf("%s: rejected proof not an auth failure: %v", c.name, err)
+		}
+	}
+}
+
+func TestBSONRoundTrip(t *testing.T) {
+	doc := bsonD{{"saslStart", 1}, {"mechanism", "SCRAM-SHA-256"}, {"payload", []byte("n,,n=a")}, {"ok", 1.0}, {"done", true}, {"sub", bsonD{{"x", int32(7)}}}}
+	m, err := bsonUnmarshal(doc.marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["mechanism"] != "SCRAM-SHA-256" || !bytes.Equal(m["payload"].([]byte), []byte("n,,n=a")) || bsonNumber(m["ok"]) != 1 || m["done"] != true ||
+		m["sub"].(map[string]any)["x"] != int32(7) || bsonNumber(m["saslStart"]) != 1 {
+		t.Fatalf("round trip: %#v", m)
+	}
+}
+
+// fakeServer runs handle for every connection until the test ends.
+func fakeServer(t *testing.T, handle func(net.Conn)) BruteTarget {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				handle(c)
+			}()
+		}
+	}()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return BruteTarget{Host: host, Port: p}
+}
+
+func fakeFTP(valid map[string]string, attempts *int32, banned func() bool) func(net.Conn) {
+	return func(c net.Conn) {
+		r := bufio.NewReader(c)
+		if banned != nil && banned() {
+			fmt.Fprint(c, "421 Temporarily banned for too many failed login attempts\r\n")
+			return
+		}
+		fmt.Fprint(c, "220-welcome\r\n220 FTP ready\r\n")
+		var user string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+			switch cmd {
+			case "USER":
+				user = arg
+				fmt.Fprint(c, "331 Password required\r\n")
+			case "PASS":
+				atomic.AddInt32(attempts, 1)
+				if pw, ok := valid[user]; ok && pw == arg {
+					fmt.Fprint(c, "230 Login successful\r\n")
+				} else {
+					fmt.Fprint(c, "530 Login incorrect\r\n")
+				}
+			}
+		}
+	}
+}
+
+func TestRunBruteFTPAndRedis(t *testing.T) {
+	var attempts int32
+	ftp := fakeServer(t, fakeFTP(map[string]string{"www": "www", "admin": "P@ssw0rd"}, &attempts, nil))
+	ftp.Service = "ftp"
+	redis := fakeServer(t, func(c net.Conn) {
+		r := bufio.NewReader(c)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if strings.HasPrefix(line, "*") {
+				n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+				var args []string
+				for i := 0; i < n; i++ {
+					r.ReadString('\n')
+					a, _ := r.ReadString('\n')
+					args = append(args, strings.TrimSpace(a))
+				}
+				switch {
+				case args[0] == "PING":
+					fmt.Fprint(c, "-NOAUTH Authentication required.\r\n")
+				case args[0] == "AUTH" && args[len(args)-1] == "123456":
+					fmt.Fprint(c, "+OK\r\n")
+				default:
+					fmt.Fprint(c, "-WRONGPASS invalid username-password pair or user is disabled.\r\n")
+				}
+			}
+		}
+	})
+	redis.Service = "redis"
+
+	db, err := InitSpiderDB(filepath.Join(t.TempDir(), "spider.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	opts := BruteOptions{Users: []string{"www", "admin"}, Passwords: []string{"123456", "P@ssw0rd"}, Timeout: 2 * time.Second, StopOnSuccess: true}
+	res := RunBrute([]BruteTarget{ftp, redis}, opts, db)
+	got := map[string]string{}
+	for _, r := range res {
+		got[r.Service+"/"+r.Username] = r.Password
+	}
+	// stop on success: admin is never tried once www:www (username as password) worked
+	want := map[string]string{"ftp/www": "www", "redis/": "123456"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	saved, err := LoadBruteResults(db)
+	if err != nil || len(saved) != 2 {
+		t.Fatalf("saved %v %+v", err, saved)
+	}
+
+	opts.StopOnSuccess = false
+	if res := RunBrute([]BruteTarget{ftp}, opts, nil); len(res) != 2 {
+		t.Fatalf("--all should find both ftp accounts: %+v", res)
+	}
+	atomic.StoreInt32(&attempts, 0)
+	opts.MaxPerUser = 1
+	opts.Users = []string{"nobody"}
+	RunBrute([]BruteTarget{ftp}, opts, nil)
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Fatalf("max-per-user 1 made %d attempts", n)
+	}
+}
+
+func TestRunBruteStopsOnLockout(t *testing.T) {
+	var attempts int32
+	ftp := fakeServer(t, fakeFTP(nil, &attempts, func() bool { return atomic.LoadInt32(&attempts) >= 3 }))
+	ftp.Service = "ftp"
+	res := RunBrute([]BruteTarget{ftp}, BruteOptions{Users: []string{"a", "b"}, Passwords: []string{"1", "2", "3", "4", "5"}, Timeout: 2 * time.Second}, nil)
+	if len(res) != 0 || atomic.LoadInt32(&attempts) != 3 {
+		t.Fatalf("kept going after the ban: %d attempts %+v", attempts, res)
+	}
+}
+
+// fakeMySQL speaks just enough of the handshake to check mysql_native_password responses.
+func fakeMySQL(user, pass string) func(net.Conn) {
+	scramble := []byte("abcdefghijklmnopqrst")
+	stage1 := sha1.Sum([]byte(pass))
+	stored := sha1.Sum(stage1[:])
+	return func(c net.Conn) {
+		var hs bytes.Buffer
+		hs.WriteByte(10)
+		hs.WriteString("8.0.36\x00")
+		binary.Write(&hs, binary.LittleEndian, uint32(1))
+		hs.Write(scramble[:8])
+		hs.WriteByte(0)
+		hs.Write([]byte{0xff, 0xf7, 45, 2, 0, 0xff, 0x81, 21})
+		hs.Write(make([]byte, 10))
+		hs.Write(scramble[8:])
+		hs.WriteByte(0)
+		hs.WriteString("mysql_native_password\x00")
+		writePkt := func(seq byte, p []byte) {
+			c.Write(append([]byte{byte(len(p)), byte(len(p) >> 8), byte(len(p) >> 16), seq}, p...))
+		}
+		writePkt(0, hs.Bytes())
+		var head [4]byte
+		if _, err := io.ReadFull(c, head[:]); err != nil {
+			return
+		}
+		body := make([]byte, int(head[0])|int(head[1])<<8)
+		io.ReadFull(c, body)
+		p := body[32:]
+		name, p, _ := bytes.Cut(p, []byte{0})
+		auth := p[1 : 1+int(p[0])]
+		h := sha1.Sum(append(append([]byte(nil), scramble...), stored[:]...))
+		ok := len(auth) == 20
+		if ok {
+			for i := range auth {
+				h[i] ^= auth[i]
+			}
+			ok = sha1.Sum(h[:]) == stored
+		}
+		if string(name) == user && ok {
+			writePkt(2, []byte{0, 0, 0, 2, 0, 0, 0})
+			return
+		}
+		writePkt(2, append([]byte{0xff, 0x15, 0x04}, "#28000Access denied for user"...))
+	}
+}
+
+func TestBruteMySQL(t *testing.T) {
+	target := fakeServer(t, fakeMySQL("root", "Aa123456"))
+	target.Service = "mysql"
+	res := RunBrute([]BruteTarget{target}, BruteOptions{Passwords: []string{"123456", "Aa123456"}, Timeout: 2 * time.Second}, nil)
+	if len(res) != 1 || res[0].Username != "root" || res[0].Password != "Aa123456" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
+
+func TestParseBruteTargets(t *testing.T) {
+	got, err := ParseBruteTargets([]string{"10.0.0.1:22", "postgres://10.0.0.2", "# comment", "10.0.0.3:2222", "[::1]:6379"}, "")
+	if err == nil {
+		t.Fatalf("10.0.0.3:2222 has no guessable service: %+v", got)
+	}
+	got, err = ParseBruteTargets([]string{"10.0.0.1:22", "postgres://10.0.0.2", "ssh://10.0.0.3:2222", "[::1]:6379"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BruteTarget{{"10.0.0.1", 22, "ssh"}, {"10.0.0.2", 5432, "postgresql"}, {"10.0.0.3", 2222, "ssh"}, {"::1", 6379, "redis"}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	rows := []ServiceResultRow{{IP: "10.0.0.5", Port: 1433, Protocol: "tcp", Service: "ms-sql-s"}, {IP: "10.0.0.5", Port: 80, Protocol: "tcp", Service: "http"},
+		{IP: "10.0.0.6", Port: 27017, Protocol: "tcp"}, {IP: "10.0.0.7", Port: 53, Protocol: "udp", Service: "domain"}}
+	if got := BruteTargetsFromServices(rows); fmt.Sprint(got) != fmt.Sprint([]BruteTarget{{"10.0.0.5", 1433, "mssql"}, {"10.0.0.6", 27017, "mongodb"}}) {
+		t.Fatalf("from services: %v", got)
+	}
+}
+
+// scramServer is the server half of RFC 5802, checking proofs against pass.
+type scramServer struct {
+	h      func() hash.Hash
+	pass   string
+	salted []byte
+	auth   string
+}
+
+func (s *scramServer) mac(key []byte, msg string) []byte {
+	m := hmac.New(s.h, key)
+	m.Write([]byte(msg))
+	return m.Sum(nil)
+}
+
+// serverFirst answers the client first message ("n,,n=..,r=..").
+func (s *scramServer) serverFirst(clientFirst string) string {
+	bare := strings.TrimPrefix(clientFirst, "n,,")
+	salt := []byte("0123456789abcdef")
+	s.salted, _ = pbkdf2.Key(s.h, s.pass, salt, 4096, s.h().Size())
+	first := "r=" + scramAttrs(bare)["r"] + "srv,s=" + base64.StdEncoding.EncodeToString(salt) + ",i=4096"
+	s.auth = bare + "," + first
+	return first
+}
+
+// serverFinal checks the proof in the client final message and returns the "v=" answer.
+func (s *scramServer) serverFinal(clientFinal string) (string, bool) {
+	withoutProof, proof64, _ := strings.Cut(clientFinal, ",p=")
+	s.auth += "," + withoutProof
+	h := s.h()
+	h.Write(s.mac(s.salted, "Client Key"))
+	stored := h.Sum(nil)
+	sig := s.mac(stored, s.auth)
+	proof, err := base64.StdEncoding.DecodeString(proof64)
+	if err != nil || len(proof) != len(sig) {
+		return "", false
+	}
+	for i := range proof {
+		proof[i] ^= sig[i]
+	}
+	h = s.h()
+	h.Write(proof)
+	if !hmac.Equal(h.Sum(nil), stored) {
+		return "", false
+	}
+	return "v=" + base64.StdEncoding.EncodeToString(s.mac(s.mac(s.salted, "Server Key"), s.auth)), true
+}
+
+// fakePostgres asks for md5 or SCRAM-SHA-256 auth after the startup message.
+func fakePostgres(user, pass string, scram bool) func(net.Conn) {
+	return func(c net.Conn) {
+		r := bufio.NewReader(c)
+		var n uint32
+		if binary.Read(r, binary.BigEndian, &n) != nil || n < 8 || n > 1<<16 {
+			return
+		}
+		startup := make([]byte, n-4)
+		if _, err := io.ReadFull(r, startup); err != nil {
+			return
+		}
+		login := ""
+		params := bytes.Split(startup[4:], []byte{0})
+		for i := 0; i+1 < len(params); i += 2 {
+			if string(params[i]) == "user" {
+				login = string(params[i+1])
+			}
+		}
+		auth := func(code uint32, data []byte) {
+			c.Write(pgMessage('R', append(binary.BigEndian.AppendUint32(nil, code), data...)))
+		}
+		ok := false
+		if scram {
+			auth(10, []byte("SCRAM-SHA-256\x00\x00"))
+			_, body, err := pgRead(r)
+			if err != nil {
+				return
+			}
+			mech, rest, _ := bytes.Cut(body, []byte{0})
+			if string(mech) != "SCRAM-SHA-256" || len(rest) < 4 {
+				return
+			}
+			s := &scramServer{h: sha256.New, pass: pass}
+			auth(11, []byte(s.serverFirst(string(rest[4:]))))
+			if _, body, err = pgRead(r); err != nil {
+				return
+			}
+			var final string
+			if final, ok = s.serverFinal(string(body)); ok {
+				auth(12, []byte(final))
+			}
+		} else {
+			salt := []byte{1, 2, 3, 4}
+			auth(5, salt)
+			_, body, err := pgRead(r)
+			if err != nil {
+				return
+			}
+			inner := md5.Sum([]byte(pass + user))
+			outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+			ok = string(body) == "md5"+hex.EncodeToString(outer[:])+"\x00"
+		}
+		if !ok || login != user {
+			c.Write(pgMessage('E', []byte("SFATAL\x00C28P01\x00Mpassword authentication failed for user \""+login+"\"\x00\x00")))
+			return
+		}
+		auth(0, nil)
+		c.Write(pgMessage('Z', []byte{'I'}))
+	}
+}
+
+// fakeMongo runs SCRAM-SHA-256 saslStart / saslContinue over OP_MSG.
+func fakeMongo(user, pass string) func(net.Conn) {
+	return func(c net.Conn) {
+		var s *scramServer
+		login := ""
+		for {
+			var head [16]byte
+			if _, err := io.ReadFull(c, head[:]); err != nil {
+				return
+			}
+			n := int(binary.LittleEndian.Uint32(head[:])) - 16
+			if n < 5 || n > 1<<16 {
+				return
+			}
+			body := make([]byte, n)
+			if _, err := io.ReadFull(c, body); err != nil {
+				return
+			}
+			cmd, err := bsonUnmarshal(body[5:])
+			if err != nil {
+				return
+			}
+			payload, _ := cmd["payload"].([]byte)
+			var reply bsonD
+			switch {
+			case cmd["saslStart"] != nil && cmd["mechanism"] == "SCRAM-SHA-256":
+				s = &scramServer{h: sha256.New, pass: pass}
+				login = scramAttrs(strings.TrimPrefix(string(payload), "n,,"))["n"]
+				reply = bsonD{{"conversationId", 1}, {"done", false}, {"payload", []byte(s.serverFirst(string(payload)))}, {"ok", 1.0}}
+			case cmd["saslContinue"] != nil && s != nil:
+				if final, ok := s.serverFinal(string(payload)); ok && login == user {
+					reply = bsonD{{"conversationId", 1}, {"done", true}, {"payload", []byte(final)}, {"ok", 1.0}}
+				} else {
+					reply = bsonD{{"ok", 0.0}, {"errmsg", "Authentication failed."}, {"code", 18}, {"codeName", "AuthenticationFailed"}}
+				}
+				s = nil
+			default:
+				reply = bsonD{{"ok", 0.0}, {"errmsg", "command requires authentication"}, {"code", 13}}
+			}
+			doc := reply.marshal()
+			msg := make([]byte, 21, 21+len(doc))
+			binary.LittleEndian.PutUint32(msg, uint32(21+len(doc)))
+			copy(msg[8:], head[4:8])
+			binary.LittleEndian.PutUint32(msg[12:], 2013)
+			c.Write(append(msg, doc...))
+		}
+	}
+}
+
+func testCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "fake"}, NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// fakeMSSQL answers prelogin with encrypt, then checks the LOGIN7 credentials.
+func fakeMSSQL(user, pass string, encrypt byte, cert tls.Certificate) func(net.Conn) {
+	return func(c net.Conn) {
+		if _, _, err := tdsReadMessage(c); err != nil {
+			return
+		}
+		// VERSION and ENCRYPTION options, then their data
+		c.Write(tdsPacket(tdsReply, []byte{0x00, 0, 11, 0, 6, 0x01, 0, 17, 0, 1, 0xff, 16, 0, 0x10, 0, 0, 0, encrypt}))
+		var r io.Reader = c
+		var w io.Writer = c
+		if encrypt != tdsEncryptNotSup {
+			// the tls handshake travels inside prelogin packets, from both sides
+			tc := &tdsTLSConn{Conn: c, handshake: true}
+			tlsConn := tls.Server(tc, &tls.Config{Certificates: []tls.Certificate{cert}})
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			tc.handshake = false
+			r = tlsConn
+			if encrypt != tdsEncryptOff {
+				w = tlsConn
+			}
+		}
+		_, p, err := tdsReadMessage(r)
+		if err != nil || len(p) < 48 {
+			return
+		}
+		// UserName and Password offset/length pairs follow HostName at 36
+		field := func(i int) []byte {
+			off, n := int(binary.LittleEndian.Uint16(p[36+4*i:])), 2*int(binary.LittleEndian.Uint16(p[38+4*i:]))
+			if off+n > len(p) {
+				return nil
+			}
+			return p[off : off+n]
+		}
+		pw := field(2)
+		for i, b := range pw {
+			b ^= 0xa5
+			pw[i] = b<<4 | b>>4
+		}
+		var reply bytes.Buffer
+		if tdsString(field(1)) == user && tdsString(pw) == pass {
+			reply.Write([]byte{0xad, 10, 0})
+			reply.Write(make([]byte, 10))
+		} else {
+			msg := tdsUCS2("Login failed for user '" + tdsString(field(1)) + "'.")
+			var tok bytes.Buffer
+			binary.Write(&tok, binary.LittleEndian, uint32(18456))
+			tok.Write([]byte{1, 14})
+			binary.Write(&tok, binary.LittleEndian, uint16(len(msg)/2))
+			tok.Write(msg)
+			tok.Write([]byte{0, 0})
+			binary.Write(&tok, binary.LittleEndian, uint32(1))
+			reply.WriteByte(0xaa)
+			binary.Write(&reply, binary.LittleEndian, uint16(tok.Len()))
+			reply.Write(tok.Bytes())
+		}
+		reply.Write(append([]byte{0xfd, 0x02}, make([]byte, 11)...))
+		w.Write(tdsPacket(tdsReply, reply.Bytes()))
+	}
+}
+
+// fakeSSH only offers method, accepting user/pass through it.
+func fakeSSH(t *testing.T, user, pass, method string) func(net.Conn) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(c ssh.ConnMetadata, pw string) (*ssh.Permissions, error) {
+		if c.User() == user && pw == pass {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("denied")
+	}
+	cfg := &ssh.ServerConfig{}
+	switch method {
+	case "password":
+		cfg.PasswordCallback = func(c ssh.ConnMetadata, pw []byte) (*ssh.Permissions, error) { return check(c, string(pw)) }
+	case "keyboard-interactive":
+		cfg.KeyboardInteractiveCallback = func(c ssh.ConnMetadata, ask ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			answers, err := ask("", "", []string{"Password: "}, []bool{false})
+			if err != nil || len(answers) != 1 {
+				return nil, fmt.Errorf("no answer")
+			}
+			return check(c, answers[0])
+		}
+	case "publickey":
+		cfg.PublicKeyCallback = func(ssh.ConnMetadata, ssh.PublicKey) (*ssh.Permissions, error) { return nil, fmt.Errorf("denied") }
+	}
+	cfg.AddHostKey(signer)
+	return func(c net.Conn) {
+		conn, chans, reqs, err := ssh.NewServerConn(c, cfg)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go ssh.DiscardRequests(reqs)
+		for ch := range chans {
+			ch.Reject(ssh.Prohibited, "no sessions")
+		}
+	}
+}
+
+func TestBruteLoginHandshakes(t *testing.T) {
+	cert := testCertificate(t)
+	cases := []struct {
+		name, service string
+		handle        func(net.Conn)
+	}{
+		{"ssh password", "ssh", fakeSSH(t, "root", "Tr0ub4dor&3", "password")},
+		{"ssh keyboard-interactive", "ssh", fakeSSH(t, "root", "Tr0ub4dor&3", "keyboard-interactive")},
+		{"postgresql md5", "postgresql", fakePostgres("root", "Tr0ub4dor&3", false)},
+		{"postgresql scram", "postgresql", fakePostgres("root", "Tr0ub4dor&3", true)},
+		{"mongodb scram", "mongodb", fakeMongo("root", "Tr0ub4dor&3")},
+		{"mssql plain", "mssql", fakeMSSQL("root", "Tr0ub4dor&3", tdsEncryptNotSup, cert)},
+		{"mssql login encrypted", "mssql", fakeMSSQL("root", "Tr0ub4dor&3", tdsEncryptOff, cert)},
+		{"mssql encrypted", "mssql", fakeMSSQL("root", "Tr0ub4dor&3", 0x01, cert)},
+	}
+	for _, c := range cases {
+		target := fakeServer(t, c.handle)
+		proto := bruteProtocols[c.service]
+		if _, err := bruteAttempt(target, proto, nil, 2*time.Second, "root", "123456"); !errors.Is(err, errBruteAuth) {
+			t.Errorf("%s: wrong password gave %v", c.name, err)
+		}
+		if note, err := bruteAttempt(target, proto, nil, 2*time.Second, "root", "Tr0ub4dor&3"); err != nil || note != "" {
+			t.Errorf("%s: right password gave %q %v", c.name, note, err)
+		}
+	}
+	// a server without password auth stops the target instead of burning the wordlist
+	target := fakeServer(t, fakeSSH(t, "root", "Tr0ub4dor&3", "publickey"))
+	if _, err := bruteAttempt(target, bruteProtocols["ssh"], nil, 2*time.Second, "root", "Tr0ub4dor&3"); !errors.Is(err, errBruteUnsupported) {
+		t.Errorf("publickey only ssh gave %v", err)
+	}
+}
+
+func TestBruteMongoMalformedReplies(t *testing.T) {
+	bodies := [][]byte{
+		{0, 0, 0, 0, 0, 1},
+		{0, 0, 0, 0, 0, 0xe8, 0x03, 0, 0, 0},
+		{0, 0, 0, 0, 0, 12, 0, 0, 0, 0x02, 'a', 0, 0xff, 0xff, 0xff, 0x7f, 0},
+		{0, 0, 0, 0, 0, 12, 0, 0, 0, 0x03, 'a', 0, 2, 0, 0, 0, 0},
+	}
+	for _, body := range bodies {
+		target := fakeServer(t, func(c net.Conn) {
+			var head [16]byte
+			if _, err := io.ReadFull(c, head[:]); err != nil {
+				return
+			}
+			io.CopyN(io.Discard, c, int64(binary.LittleEndian.Uint32(head[:]))-16)
+			reply := make([]byte, 16, 16+len(body))
+			binary.LittleEndian.PutUint32(reply, uint32(16+len(body)))
+			binary.LittleEndian.PutUint32(reply[12:], 2013)
+			c.Write(append(reply, body...))
+		})
+		if _, err := bruteAttempt(target, bruteProtocols["mongodb"], nil, 2*time.Second, "root", "123456"); err == nil || errors.Is(err, errBruteAuth) {
+			t.Errorf("reply %x: got %v", body, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+const (
+	checkpointFlushSize     = 50
+	checkpointFlushInterval = 2 * time.Second
+)
+
+// Checkpoint is a per-command journal of finished (target, task) units stored in spider.db.
+// A fresh run clears the journal of its scope; with resume the finished units are loaded
+// so callers can skip them. Done/Data only reflect previous runs; marks of the current run
+// are buffered and flushed in small batches.
+type Checkpoint struct {
+	db        *sql.DB
+	scope     string
+	mu        sync.Mutex
+	done      map[string]string
+	pending   []checkpointUnit
+	lastFlush time.Time
+	skipped   int
+}
+
+type checkpointUnit struct {
+	target string
+	task   string
+	data   string
+}
+
+func checkpointKey(target, task string) string {
+	return target + "\x00" + task
+}
+
+// NewCheckpoint opens the journal for scope. A nil db yields a checkpoint that never skips.
+func NewCheckpoint(db *sql.DB, scope string, resume bool) *Checkpoint {
+	c := &Checkpoint{db: db, scope: scope, done: make(map[string]string), lastFlush: time.Now()}
+	if db == nil {
+		return c
+	}
+	if !resume {
+		if _, err := db.Exec(`DELETE FROM checkpoints WHERE scope = ?`, scope); err != nil {
+			Debug("reset checkpoint %s failed: %v", scope, err)
+		}
+		return c
+	}
+	rows, err := db.Query(`SELECT target, task, data FROM checkpoints WHERE scope = ?`, scope)
+	if err != nil {
+		Warning("load checkpoint %s failed: %v", scope, err)
+		return c
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var target, task, data string
+		if err := rows.Scan(&target, &task, &data); err != nil {
+			Warning("load checkpoint %s failed: %v", scope, err)
+			return c
+		}
+		c.done[checkpointKey(target, task)] = data
+	}
+	if len(c.done) > 0 {
+		Info("resume %s: %d finished unit(s) in journal", scope, len(c.done))
+	}
+	return c
+}
+
+// Done reports whether the unit finished in a previous run and counts it as skipped.
+func (c *Checkpoint) Done(target, task string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.done[checkpointKey(target, task)]
+	if ok {
+		c.skipped++
+	}
+	return ok
+}
+
+// Data returns the payload stored with a finished unit.
+func (c *Checkpoint) Data(target, task string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.done[checkpointKey(target, task)]
+	return data, ok
+}
+
+// Mark records a finished unit with an optional payload needed to rebuild results on resume.
+func (c *Checkpoint) Mark(target, task, data string) {
+	if c == nil || c.db == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = append(c.pending, checkpointUnit{target: target, task: task, data: data})
+	if len(c.pending) >= checkpointFlushSize || time.Since(c.lastFlush) >= checkpointFlushInterval {
+		c.flushLocked()
+	}
+}
+
+// Skipped returns how many units were skipped via Done.
+func (c *Checkpoint) Skipped() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.skipped
+}
+
+// Close flushes pending marks.
+func (c *Checkpoint) Close() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushLocked()
+}
+
+func (c *Checkpoint) flushLocked() {
+	c.lastFlush = time.Now()
+	if c.db == nil || len(c.pending) == 0 {
+		return
+	}
+	tx, err := c.db.Begin()
+	if err != nil {
+		Debug("checkpoint flush failed: %v", err)
+		return
+	}
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO checkpoints (scope, target, task, data) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		Debug("checkpoint flush failed: %v", err)
+		return
+	}
+	defer stmt.Close()
+	for _, u := range c.pending {
+		if _, err := stmt.Exec(c.scope, u.target, u.task, u.data); err != nil {
+			tx.Rollback()
+			Debug("checkpoint flush failed: %v", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		Debug("checkpoint flush failed: %v", err)
+		return
+	}
+	c.pending = c.pending[:0]
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	db, err := InitSpiderDB(filepath.Join(t.TempDir(), "spider.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	defer db.Close()
+
+	ck := NewCheckpoint(db, "dirbrute", false)
+	ck.Mark("http://a", "/admin", "")
+	ck.Mark("http://a", "/login", "200")
+	if ck.Done("http://a", "/admin") {
+		t.Fatalf("units marked in the current run must not count as previous")
+	}
+	ck.Close()
+
+	other := NewCheckpoint(db, "spider", false)
+	other.Mark("http://a", "", "")
+	other.Close()
+
+	resumed := NewCheckpoint(db, "dirbrute", true)
+	if !resumed.Done("http://a", "/admin") || !resumed.Done("http://a", "/login") {
+		t.Fatalf("finished units not loaded on resume")
+	}
+	if resumed.Done("http://a", "/backup") || resumed.Done("http://a", "") {
+		t.Fatalf("unfinished or foreign-scope unit reported as done")
+	}
+	if data, ok := resumed.Data("http://a", "/login"); !ok || data != "200" {
+		t.Fatalf("data = %q ok=%v", data, ok)
+	}
+	if resumed.Skipped() != 2 {
+		t.Fatalf("skipped = %d, want 2", resumed.Skipped())
+	}
+
+	fresh := NewCheckpoint(db, "dirbrute", false)
+	fresh.Close()
+	again := NewCheckpoint(db, "dirbrute", true)
+	if again.Done("http://a", "/admin") {
+		t.Fatalf("fresh run should reset the scope journal")
+	}
+	if !NewCheckpoint(db, "spider", true).Done("http://a", "") {
+		t.Fatalf("resetting one scope must not touch another")
+	}
+}
+
+func TestPortListKey(t *testing.T) {
+	a := portListKey([]int{443, 80, 8080})
+	if a != portListKey([]int{80, 443, 8080}) {
+		t.Fatalf("equivalent port lists got different keys")
+	}
+	if a == portListKey([]int{80, 443}) {
+		t.Fatalf("different port lists share a key")
+	}
+	ports := make([]int, 65535)
+	for i := range ports {
+		ports[i] = i + 1
+	}
+	if k := portListKey(ports); len(k) > 32 {
+		t.Fatalf("key %q is too long", k)
+	}
+}
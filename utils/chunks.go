@@ -0,0 +1,322 @@
+package utils
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Lazy chunks of webpack/Vite/Next.js builds are never referenced by a <script> tag; their names
+// only exist in the runtime chunk map, in dynamic import() calls or in the Next.js build manifest.
+
+var (
+	// webpack publicPath assignment: __webpack_require__.p = "/static/" (minified as a.p="...")
+	webpackPublicPathRe = regexp.MustCompile(`\b[\w$]+\.p\s*=\s*["']([^"']*)["']`)
+	nextBuildIDRe       = regexp.MustCompile(`"buildId"\s*:\s*"([\w\-]+)"`)
+	chunkFileRe         = regexp.MustCompile(`^(\.{0,2}/)?[\w\-@~.+/]+\.m?js$`)
+)
+
+const maxChunksPerBundle = 2000
+
+// chunkOperand is one "+" operand of a webpack chunk url expression.
+type chunkOperand struct {
+	lit      string
+	ident    string
+	table    map[string]string
+	fallback string // ident used in `{..}[e] || e`
+	member   bool
+}
+
+// DiscoverJSChunks returns the absolute urls of lazily loaded chunks referenced by a bundle.
+// Chunks on another host than the bundle (absolute names or publicPath) are left out.
+func DiscoverJSChunks(jsURL, body string) []string {
+	return discoverChunks(jsURL, body, tokenizeJS(body))
+}
+
+func discoverChunks(jsURL, body string, toks []jsToken) []string {
+	rels := webpackChunkPaths(toks)
+	rels = append(rels, importedChunkPaths(toks)...)
+	publicPath := ""
+	if m := webpackPublicPathRe.FindStringSubmatch(body); m != nil {
+		publicPath = m[1]
+	}
+	bundle, err := url.Parse(jsURL)
+	if err != nil {
+		return nil
+	}
+	seen := make(map[string]struct{})
+	var out []string
+	for _, rel := range rels {
+		abs := resolveChunkURL(jsURL, publicPath, rel)
+		if abs == "" || abs == jsURL {
+			continue
+		}
+		if u, err := url.Parse(abs); err != nil || !strings.EqualFold(u.Host, bundle.Host) {
+			continue
+		}
+		if _, ok := seen[abs]; ok {
+			continue
+		}
+		seen[abs] = struct{}{}
+		out = append(out, abs)
+		if len(out) >= maxChunksPerBundle {
+			break
+		}
+	}
+	return out
+}
+
+// NextBuildManifestURL returns the _buildManifest.js url of a Next.js page, or "".
+func NextBuildManifestURL(pageURL, body string) string {
+	if !strings.Contains(body, "__NEXT_DATA__") && !strings.Contains(body, "/_next/") {
+		return ""
+	}
+	m := nextBuildIDRe.FindStringSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	return Normalize("/_next/static/"+m[1]+"/_buildManifest.js", pageURL)
+}
+
+// webpackChunkPaths evaluates runtime expressions like
+// "js/" + ({1:"about"}[e] || e) + "." + {1:"3f2a"}[e] + ".js" for every chunk id in the maps.
+func webpackChunkPaths(toks []jsToken) []string {
+	var out []string
+	for i := 0; i < len(toks); i++ {
+		if i > 0 && toks[i-1].kind == jsTokPunct && (toks[i-1].text == "+" || toks[i-1].text == ".") {
+			continue
+		}
+		ops, end := parseChunkChain(toks, i)
+		if end == i {
+			continue
+		}
+		out = append(out, expandChunkChain(ops)...)
+		i = end - 1
+	}
+	return out
+}
+
+func parseChunkChain(toks []jsToken, i int) ([]chunkOperand, int) {
+	var ops []chunkOperand
+	j := i
+	for {
+		op, next, ok := parseChunkOperand(toks, j)
+		if !ok {
+			return nil, i
+		}
+		ops = append(ops, op)
+		j = next
+		if j < len(toks) && toks[j].kind == jsTokPunct && toks[j].text == "+" {
+			j++
+			continue
+		}
+		break
+	}
+	hasTable := false
+	for _, op := range ops {
+		hasTable = hasTable || len(op.table) > 0
+	}
+	last := ops[len(ops)-1]
+	if !hasTable || len(ops) < 2 || !strings.HasSuffix(last.lit, ".js") {
+		return nil, i
+	}
+	return ops, j
+}
+
+func parseChunkOperand(toks []jsToken, i int) (chunkOperand, int, bool) {
+	if i >= len(toks) {
+		return chunkOperand{}, i, false
+	}
+	t := toks[i]
+	switch {
+	case t.kind == jsTokString:
+		return chunkOperand{lit: t.text}, i + 1, true
+	case t.kind == jsTokIdent:
+		j := i + 1
+		member := false
+		for j+1 < len(toks) && toks[j].kind == jsTokPunct && toks[j].text == "." && toks[j+1].kind == jsTokIdent {
+			member = true
+			j += 2
+		}
+		if member {
+			// publicPath (__webpack_require__.p) at the start of the expression
+			return chunkOperand{member: true}, j, toks[j-1].text == "p"
+		}
+		return chunkOperand{ident: t.text}, j, true
+	case t.kind == jsTokPunct && t.text == "{":
+		table, j, ok := parseChunkTable(toks, i)
+		if !ok || j+2 >= len(toks) || toks[j].text != "[" || toks[j+1].kind != jsTokIdent || toks[j+2].text != "]" {
+			return chunkOperand{}, i, false
+		}
+		return chunkOperand{table: table, ident: toks[j+1].text}, j + 3, true
+	case t.kind == jsTokPunct && t.text == "(":
+		op, j, ok := parseChunkOperand(toks, i+1)
+		if !ok || op.table == nil {
+			return chunkOperand{}, i, false
+		}
+		if j+2 < len(toks) && toks[j].text == "||" && toks[j+1].kind == jsTokIdent && toks[j+2].text == ")" {
+			op.fallback = toks[j+1].text
+			return op, j + 3, true
+		}
+		if j < len(toks) && toks[j].text == ")" {
+			return op, j + 1, true
+		}
+	}
+	return chunkOperand{}, i, false
+}
+
+// parseChunkTable reads an object literal whose keys are chunk ids and whose values are strings or numbers.
+func parseChunkTable(toks []jsToken, i int) (map[string]string, int, bool) {
+	table := make(map[string]string)
+	j := i + 1
+	for j < len(toks) {
+		if toks[j].kind == jsTokPunct && toks[j].text == "}" {
+			return table, j + 1, true
+		}
+		if j+2 >= len(toks) || toks[j+1].text != ":" {
+			return nil, i, false
+		}
+		key, value := toks[j], toks[j+2]
+		if key.kind != jsTokNumber && key.kind != jsTokString && key.kind != jsTokIdent ||
+			value.kind != jsTokString && value.kind != jsTokNumber {
+			return nil, i, false
+		}
+		table[key.text] = value.text
+		j += 3
+		if j < len(toks) && toks[j].kind == jsTokPunct && toks[j].text == "," {
+			j++
+		}
+	}
+	return nil, i, false
+}
+
+func expandChunkChain(ops []chunkOperand) []string {
+	idVar := ""
+	ids := make(map[string]struct{})
+	for _, op := range ops {
+		if len(op.table) == 0 {
+			continue
+		}
+		idVar = op.ident
+		for id := range op.table {
+			ids[id] = struct{}{}
+		}
+	}
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+	var out []string
+	for _, id := range sorted {
+		var b strings.Builder
+		ok := true
+		for _, op := range ops {
+			switch {
+			case op.member:
+			case op.table != nil:
+				v, found := op.table[id]
+				if !found && op.fallback == "" {
+					ok = false
+				} else if !found {
+					v = id
+				}
+				b.WriteString(v)
+			case op.ident != "":
+				if op.ident != idVar {
+					ok = false
+				}
+				b.WriteString(id)
+			default:
+				b.WriteString(op.lit)
+			}
+		}
+		if ok && chunkFileRe.MatchString(b.String()) {
+			out = append(out, b.String())
+		}
+	}
+	return out
+}
+
+// importedChunkPaths collects chunk files named in import("./x.js"), `from "./x.js"` and string
+// arrays such as Vite's __vite__mapDeps list or the Next.js build manifest.
+func importedChunkPaths(toks []jsToken) []string {
+	var out []string
+	for i, t := range toks {
+		if t.kind != jsTokString || !chunkFileRe.MatchString(t.text) {
+			continue
+		}
+		if i == 0 {
+			continue
+		}
+		prev := toks[i-1]
+		switch {
+		case prev.kind == jsTokIdent && (prev.text == "from" || prev.text == "import"):
+		case prev.kind == jsTokPunct && prev.text == "(" && i >= 2 && toks[i-2].kind == jsTokIdent && toks[i-2].text == "import":
+		case prev.kind == jsTokPunct && (prev.text == "[" || prev.text == ",") && strings.Contains(t.text, "/") && inStringArray(toks, i):
+		case strings.HasPrefix(t.text, "static/chunks/") || strings.HasPrefix(t.text, "static/js/"):
+		default:
+			continue
+		}
+		out = append(out, t.text)
+	}
+	return out
+}
+
+// inStringArray reports whether the string at i sits in an array literal made only of strings.
+func inStringArray(toks []jsToken, i int) bool {
+	j := i
+	for j > 0 && toks[j-1].kind == jsTokPunct && toks[j-1].text == "," && j >= 2 && toks[j-2].kind == jsTokString {
+		j -= 2
+	}
+	if j == 0 || toks[j-1].kind != jsTokPunct || toks[j-1].text != "[" {
+		return false
+	}
+	k := i + 1
+	for k+1 < len(toks) && toks[k].kind == jsTokPunct && toks[k].text == "," && toks[k+1].kind == jsTokString {
+		k += 2
+	}
+	return k < len(toks) && toks[k].kind == jsTokPunct && toks[k].text == "]"
+}
+
+// resolveChunkURL resolves a chunk path against the bundle url. Paths relative to the build
+// root ("static/js/1.js") are anchored where the bundle url contains their directory.
+func resolveChunkURL(jsURL, publicPath, rel string) string {
+	base, err := url.Parse(jsURL)
+	if err != nil {
+		return ""
+	}
+	if strings.HasPrefix(rel, "http://") || strings.HasPrefix(rel, "https://") {
+		return rel
+	}
+	if publicPath != "" && publicPath != "auto" && !strings.HasPrefix(publicPath, ".") {
+		ref, err := url.Parse(strings.TrimRight(publicPath, "/") + "/" + strings.TrimLeft(rel, "/"))
+		if err != nil {
+			return ""
+		}
+		return base.ResolveReference(ref).String()
+	}
+	if strings.HasPrefix(rel, ".") || strings.HasPrefix(rel, "/") {
+		ref, err := url.Parse(rel)
+		if err != nil {
+			return ""
+		}
+		return base.ResolveReference(ref).String()
+	}
+	dir := base.Path[:strings.LastIndex(base.Path, "/")+1]
+	if d := path.Dir(rel); d != "." {
+		if idx := strings.LastIndex(base.Path, "/"+d+"/"); idx >= 0 {
+			dir = base.Path[:idx+1]
+		} else if idx := strings.LastIndex(base.Path, "/"+strings.SplitN(d, "/", 2)[0]+"/"); idx >= 0 {
+			dir = base.Path[:idx+1]
+		}
+	}
+	u := *base
+	u.Path = dir + rel
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
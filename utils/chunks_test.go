@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestDiscoverJSChunks(t *testing.T) {
+	cases := []struct {
+		name string
+		url  string
+		body string
+		want []string
+	}{
+		{
+			name: "webpack4 runtime",
+			url:  "http://h/app/js/runtime.js",
+			body: `function u(e){return a.p+"js/"+({12:"about"}[e]||e)+"."+{12:"3f2a",34:"9b1c"}[e]+".js"}a.p="/app/";`,
+			want: []string{"http://h/app/js/34.9b1c.js", "http://h/app/js/about.3f2a.js"},
+		},
+		{
+			name: "webpack5 without publicPath",
+			url:  "http://h/console/static/js/runtime.5e1.js",
+			body: `r.u=e=>"static/js/"+e+"."+{85:"a1b2",117:"c3d4"}[e]+".chunk.js";`,
+			want: []string{"http://h/console/static/js/117.c3d4.chunk.js", "http://h/console/static/js/85.a1b2.chunk.js"},
+		},
+		{
+			name: "chunk map next to the runtime",
+			url:  "http://h/js/app.js",
+			body: `s.src = "chunk-" + {12:"abc123"}[e] + ".js";`,
+			want: []string{"http://h/js/chunk-abc123.js"},
+		},
+		{
+			name: "vite",
+			url:  "http://h/assets/index-x1.js",
+			body: `const __vite__mapDeps=(i,m=__vite__mapDeps,d=(m.f||(m.f=["assets/Login-a1.js","assets/Login-a1.css"])))=>i.map(i=>d[i]);
+import{v as o}from"./vendor-b2.js";const r=()=>import("./Home-c3.js");var x=["a","b"];`,
+			want: []string{"http://h/assets/Home-c3.js", "http://h/assets/Login-a1.js", "http://h/assets/vendor-b2.js"},
+		},
+		{
+			name: "next build manifest",
+			url:  "http://h/_next/static/Bld1/_buildManifest.js",
+			body: `self.__BUILD_MANIFEST=function(s){return{"/":[s,"static/chunks/pages/index-1a.js"],"/login":["static/chunks/pages/login-2b.js"]}}("static/chunks/29107295-3c.js");`,
+			want: []string{"http://h/_next/static/chunks/29107295-3c.js", "http://h/_next/static/chunks/pages/index-1a.js", "http://h/_next/static/chunks/pages/login-2b.js"},
+		},
+		{
+			name: "absolute publicPath on the bundle host",
+			url:  "http://h/js/runtime.js",
+			body: `a.p="http://H/static/";function u(e){return a.p+"js/"+e+"."+{1:"ab"}[e]+".js"}`,
+			want: []string{"http://H/static/js/1.ab.js"},
+		},
+		{
+			name: "off-host publicPath",
+			url:  "http://h/js/runtime.js",
+			body: `a.p="https://cdn.example/static/";function u(e){return a.p+"js/"+e+"."+{1:"ab"}[e]+".js"}`,
+			want: nil,
+		},
+	}
+	for _, tc := range cases {
+		got := DiscoverJSChunks(tc.url, tc.body)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	if got := NextBuildManifestURL("http://h/", `<script id="__NEXT_DATA__">{"buildId":"Bld1"}</script>`); got != "http://h/_next/static/Bld1/_buildManifest.js" {
+		t.Errorf("build manifest url = %s", got)
+	}
+}
+
+func TestSpiderCrawlsLazyChunks(t *testing.T) {
+	var chunkHits int32
+	server := mustTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><script src="/js/runtime.js"></script></body></html>`))
+		case "/js/runtime.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			_, _ = w.Write([]byte(`a.p="/";function u(e){return a.p+"js/"+({7:"admin"}[e]||e)+"."+{7:"ff01"}[e]+".js"}`))
+		case "/js/admin.ff01.js":
+			atomic.AddInt32(&chunkHits, 1)
+			w.Header().Set("Content-Type", "application/javascript")
+			_, _ = w.Write([]byte(`axios.post("/api/admin/user/delete",{id:1})`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	oldClient, oldNoRedirect := Client, ClientNoRedirect
+	Client, ClientNoRedirect = server.Client(), server.Client()
+	t.Cleanup(func() {
+		Client, ClientNoRedirect = oldClient, oldNoRedirect
+	})
+	tmpDir := t.TempDir()
+	cwd, _ := os.Getwd()
+	defer os.Chdir(cwd)
+	_ = os.Chdir(tmpDir)
+	db, err := InitSpiderDB(filepath.Join(tmpDir, "spider.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	defer db.Close()
+	SetSpiderDB(db)
+	viper.Set("max-body-bytes", 4096)
+
+	FingerSummary(server.URL+"/", 2, db)
+	if hits := atomic.LoadInt32(&chunkHits); hits != 1 {
+		t.Fatalf("chunk hits = %d, want 1", hits)
+	}
+	rows, err := LoadAPIPaths(db)
+	if err != nil {
+		t.Fatalf("load api paths: %v", err)
+	}
+	found := false
+	for _, r := range rows {
+		found = found || r.Path == "/api/admin/user/delete" && r.Method == "POST"
+	}
+	if !found {
+		t.Fatalf("chunk api not recorded: %+v", rows)
+	}
+}
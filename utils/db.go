@@ -2,8 +2,10 @@ package utils
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -12,6 +14,7 @@ import (
 type SpiderRecord struct {
 	Url        string `json:"Url"`
 	IconHash   string `json:"IconHash"`
+	IconFinger string `json:"IconFinger"`
 	IconBase64 string `json:"IconBase64"`
 	ApiCount   int    `json:"ApiCount"`
 	UrlCount   int    `json:"UrlCount"`
@@ -19,6 +22,9 @@ type SpiderRecord struct {
 	CDNHosts   string `json:"CDNHosts"`
 	SaveDir    string `json:"SaveDir"`
 	Status     int    `json:"Status"`
+	// ServiceIP/ServicePort link a row back to the port scan result it was chained from.
+	ServiceIP   string `json:"ServiceIP"`
+	ServicePort int    `json:"ServicePort"`
 }
 
 func InitSpiderDB(path string) (*sql.DB, error) {
@@ -39,6 +45,7 @@ func createSpiderTable(db *sql.DB) error {
 CREATE TABLE IF NOT EXISTS spider_summary (
 	url TEXT PRIMARY KEY,
 	icon_hash TEXT,
+	icon_finger TEXT DEFAULT '',
 	icon_data TEXT,
 	api_count INTEGER,
 	url_count INTEGER,
@@ -46,6 +53,8 @@ CREATE TABLE IF NOT EXISTS spider_summary (
 	cdn_hosts TEXT,
 	save_dir TEXT,
 	status INTEGER,
+	service_ip TEXT DEFAULT '',
+	service_port INTEGER DEFAULT 0,
 	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -55,6 +64,8 @@ CREATE TABLE IF NOT EXISTS api_paths (
 	source_url TEXT,
 	path TEXT,
 	save_dir TEXT,
+	method TEXT DEFAULT '',
+	params TEXT DEFAULT '',
 	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -120,6 +131,45 @@ CREATE INDEX IF NOT EXISTS idx_services_root ON services(root_url);
 CREATE INDEX IF NOT EXISTS idx_services_category ON services(category);
 CREATE UNIQUE INDEX IF NOT EXISTS idx_source_maps_unique ON source_maps(root_url, map_url);
 
+CREATE TABLE IF NOT EXISTS open_ports (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	ip TEXT,
+	port INTEGER,
+	protocol TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS service_results (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	ip TEXT,
+	port INTEGER,
+	protocol TEXT,
+	service TEXT,
+	product TEXT,
+	version TEXT,
+	info TEXT,
+	hostname TEXT,
+	os TEXT,
+	device_type TEXT,
+	cpe TEXT,
+	soft_match INTEGER,
+	probe TEXT,
+	banner TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS checkpoints (
+	scope TEXT,
+	target TEXT,
+	task TEXT,
+	data TEXT DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_checkpoints_unique ON checkpoints(scope, target, task);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_open_ports_unique ON open_ports(ip, port, protocol);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_service_results_unique ON service_results(ip, port, protocol);
+
 CREATE TABLE IF NOT EXISTS page_snapshots (
 	root_url TEXT PRIMARY KEY,
 	url TEXT,
@@ -139,6 +189,11 @@ CREATE TABLE IF NOT EXISTS page_snapshots (
 	_, _ = db.Exec(`ALTER TABLE spider_summary ADD COLUMN cdn_count INTEGER DEFAULT 0`)
 	_, _ = db.Exec(`ALTER TABLE spider_summary ADD COLUMN cdn_hosts TEXT DEFAULT ''`)
 	_, _ = db.Exec(`ALTER TABLE spider_summary ADD COLUMN icon_data TEXT DEFAULT ''`)
+	_, _ = db.Exec(`ALTER TABLE spider_summary ADD COLUMN service_ip TEXT DEFAULT ''`)
+	_, _ = db.Exec(`ALTER TABLE spider_summary ADD COLUMN service_port INTEGER DEFAULT 0`)
+	_, _ = db.Exec(`ALTER TABLE spider_summary ADD COLUMN icon_finger TEXT DEFAULT ''`)
+	_, _ = db.Exec(`ALTER TABLE api_paths ADD COLUMN method TEXT DEFAULT ''`)
+	_, _ = db.Exec(`ALTER TABLE api_paths ADD COLUMN params TEXT DEFAULT ''`)
 	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS cdn_hosts (id INTEGER PRIMARY KEY AUTOINCREMENT, root_url TEXT, host TEXT, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`)
 	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_api_paths_root ON api_paths(root_url)`)
 	_, _ = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_api_paths_root_path ON api_paths(root_url, path)`)
@@ -149,6 +204,10 @@ CREATE TABLE IF NOT EXISTS page_snapshots (
 	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_services_root ON services(root_url)`)
 	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_services_category ON services(category)`)
 	_, _ = db.Exec(`ALTER TABLE sensitive_hits ADD COLUMN entropy REAL DEFAULT 0`)
+	_, _ = db.Exec(`ALTER TABLE sensitive_hits ADD COLUMN rule_id TEXT DEFAULT ''`)
+	_, _ = db.Exec(`ALTER TABLE sensitive_hits ADD COLUMN confidence TEXT DEFAULT ''`)
+	_, _ = db.Exec(`ALTER TABLE sensitive_hits ADD COLUMN status TEXT DEFAULT ''`)
+	_, _ = db.Exec(`ALTER TABLE sensitive_hits ADD COLUMN detail TEXT DEFAULT ''`)
 	_, _ = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_sensitive_unique ON sensitive_hits(source_url, category, content)`)
 	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS source_maps (id INTEGER PRIMARY KEY AUTOINCREMENT, root_url TEXT, js_url TEXT, map_url TEXT, status INTEGER, length INTEGER, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`)
 	_, _ = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_source_maps_unique ON source_maps(root_url, map_url)`)
@@ -156,6 +215,26 @@ CREATE TABLE IF NOT EXISTS page_snapshots (
 	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS graph_edges (id INTEGER PRIMARY KEY AUTOINCREMENT, root_url TEXT, from_url TEXT, to_url TEXT, depth INTEGER, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`)
 	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_graph_root ON graph_edges(root_url)`)
 	_, _ = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_graph_unique ON graph_edges(root_url, from_url, to_url)`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS open_ports (id INTEGER PRIMARY KEY AUTOINCREMENT, ip TEXT, port INTEGER, protocol TEXT, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`)
+	_, _ = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_open_ports_unique ON open_ports(ip, port, protocol)`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS service_results (id INTEGER PRIMARY KEY AUTOINCREMENT, ip TEXT, port INTEGER, protocol TEXT, service TEXT, product TEXT, version TEXT, info TEXT, hostname TEXT, os TEXT, device_type TEXT, cpe TEXT, soft_match INTEGER, probe TEXT, banner TEXT, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`)
+	_, _ = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_service_results_unique ON service_results(ip, port, protocol)`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS checkpoints (scope TEXT, target TEXT, task TEXT, data TEXT DEFAULT '', created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`)
+	_, _ = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_checkpoints_unique ON checkpoints(scope, target, task)`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS api_endpoints (id INTEGER PRIMARY KEY AUTOINCREMENT, root_url TEXT, doc_url TEXT, method TEXT, path TEXT, summary TEXT, params TEXT, security TEXT, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`)
+	_, _ = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_api_endpoints_unique ON api_endpoints(root_url, method, path)`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS api_probes (id INTEGER PRIMARY KEY AUTOINCREMENT, root_url TEXT, path TEXT, method TEXT, auth INTEGER, status INTEGER, length INTEGER, content_type TEXT, location TEXT, class TEXT, unauth INTEGER, snippet TEXT, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`)
+	_, _ = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_api_probes_unique ON api_probes(root_url, path, method, auth)`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS frontend_routes (id INTEGER PRIMARY KEY AUTOINCREMENT, root_url TEXT, source_url TEXT, path TEXT, name TEXT, component TEXT, title TEXT, requires_auth INTEGER DEFAULT 0, hidden INTEGER DEFAULT 0, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`)
+	_, _ = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_frontend_routes_unique ON frontend_routes(root_url, path)`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS actuator_findings (id INTEGER PRIMARY KEY AUTOINCREMENT, root_url TEXT, base_url TEXT, version TEXT, endpoints TEXT, mappings INTEGER, properties INTEGER, unmasked INTEGER, heapdump INTEGER, jolokia INTEGER, gateway_routes INTEGER, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`)
+	_, _ = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_actuator_findings_unique ON actuator_findings(base_url)`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS findings (id INTEGER PRIMARY KEY AUTOINCREMENT, root_url TEXT, url TEXT, module TEXT, title TEXT, severity TEXT, detail TEXT, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`)
+	_, _ = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_findings_unique ON findings(url, module, title)`)
+	_, _ = db.Exec(`ALTER TABLE findings ADD COLUMN template_id TEXT DEFAULT ''`)
+	_, _ = db.Exec(`ALTER TABLE findings ADD COLUMN evidence TEXT DEFAULT ''`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS brute_results (id INTEGER PRIMARY KEY AUTOINCREMENT, target TEXT, service TEXT, username TEXT, password TEXT, note TEXT, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`)
+	_, _ = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_brute_results_unique ON brute_results(target, service, username)`)
 	return nil
 }
 
@@ -163,10 +242,11 @@ func SaveSpiderSummary(db *sql.DB, rec SpiderRecord) error {
 	if db == nil {
 		return fmt.Errorf("db is nil")
 	}
-	_, err := db.Exec(`INSERT INTO spider_summary (url, icon_hash, icon_data, api_count, url_count, cdn_count, cdn_hosts, save_dir, status, updated_at)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	_, err := db.Exec(`INSERT INTO spider_summary (url, icon_hash, icon_finger, icon_data, api_count, url_count, cdn_count, cdn_hosts, save_dir, status, service_ip, service_port, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 ON CONFLICT(url) DO UPDATE SET
   icon_hash=excluded.icon_hash,
+  icon_finger=excluded.icon_finger,
   icon_data=excluded.icon_data,
   api_count=excluded.api_count,
   url_count=excluded.url_count,
@@ -174,11 +254,15 @@ ON CONFLICT(url) DO UPDATE SET
   cdn_hosts=excluded.cdn_hosts,
   save_dir=excluded.save_dir,
   status=excluded.status,
+  service_ip=CASE WHEN excluded.service_ip != '' THEN excluded.service_ip ELSE spider_summary.service_ip END,
+  service_port=CASE WHEN excluded.service_port > 0 THEN excluded.service_port ELSE spider_summary.service_port END,
   updated_at=excluded.updated_at
-`, rec.Url, rec.IconHash, rec.IconBase64, rec.ApiCount, rec.UrlCount, rec.CDNCount, rec.CDNHosts, rec.SaveDir, rec.Status, time.Now().UTC())
+`, rec.Url, rec.IconHash, rec.IconFinger, rec.IconBase64, rec.ApiCount, rec.UrlCount, rec.CDNCount, rec.CDNHosts, rec.SaveDir, rec.Status, rec.ServiceIP, rec.ServicePort, time.Now().UTC())
 	return err
 }
 
+// SaveAPIPaths stores regex-found paths. (root_url, path) is unique, a path some earlier script
+// or call site already recorded is skipped instead of failing the whole batch.
 func SaveAPIPaths(db *sql.DB, rootURL, sourceURL string, paths []string, saveDir string) error {
 	if db == nil || len(paths) == 0 {
 		return nil
@@ -187,7 +271,7 @@ func SaveAPIPaths(db *sql.DB, rootURL, sourceURL string, paths []string, saveDir
 	if err != nil {
 		return err
 	}
-	stmt, err := tx.Prepare(`INSERT INTO api_paths (root_url, source_url, path, save_dir) VALUES (?, ?, ?, ?)`)
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO api_paths (root_url, source_url, path, save_dir) VALUES (?, ?, ?, ?)`)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -202,6 +286,46 @@ func SaveAPIPaths(db *sql.DB, rootURL, sourceURL string, paths []string, saveDir
 	return tx.Commit()
 }
 
+// SaveAPICalls stores paths recovered from JavaScript call sites. Methods and parameter names
+// are kept comma separated and merged with what earlier scripts recorded for the same path.
+func SaveAPICalls(db *sql.DB, rootURL, sourceURL string, calls []JSAPICall, saveDir string) error {
+	if db == nil || len(calls) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO api_paths (root_url, source_url, path, save_dir, method, params) VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT(root_url, path) DO UPDATE SET method=excluded.method, params=excluded.params`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, c := range calls {
+		var methods, params string
+		_ = tx.QueryRow(`SELECT COALESCE(method, ''), COALESCE(params, '') FROM api_paths WHERE root_url = ? AND path = ?`, rootURL, c.Path).Scan(&methods, &params)
+		methods = mergeCommaList(methods, []string{c.Method})
+		params = mergeCommaList(params, c.Params)
+		if _, err := stmt.Exec(rootURL, sourceURL, c.Path, saveDir, methods, params); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func mergeCommaList(list string, add []string) string {
+	var out []string
+	for _, v := range append(strings.Split(list, ","), add...) {
+		if v = strings.TrimSpace(v); v != "" && !containsString(out, v) {
+			out = append(out, v)
+		}
+	}
+	return strings.Join(out, ",")
+}
+
 func SaveCDNHosts(db *sql.DB, rootURL string, hosts []string) error {
 	if db == nil || len(hosts) == 0 {
 		return nil
@@ -226,7 +350,7 @@ func SaveCDNHosts(db *sql.DB, rootURL string, hosts []string) error {
 }
 
 func LoadSpiderSummaries(db *sql.DB) ([]SpiderRecord, error) {
-	rows, err := db.Query(`SELECT url, icon_hash, icon_data, api_count, url_count, cdn_count, cdn_hosts, save_dir, status FROM spider_summary ORDER BY updated_at DESC`)
+	rows, err := db.Query(`SELECT url, icon_hash, COALESCE(icon_finger, ''), icon_data, api_count, url_count, cdn_count, cdn_hosts, save_dir, status, COALESCE(service_ip, ''), COALESCE(service_port, 0) FROM spider_summary ORDER BY updated_at DESC`)
 	if err != nil {
 		return nil, err
 	}
@@ -234,7 +358,7 @@ func LoadSpiderSummaries(db *sql.DB) ([]SpiderRecord, error) {
 	var out []SpiderRecord
 	for rows.Next() {
 		var r SpiderRecord
-		if err := rows.Scan(&r.Url, &r.IconHash, &r.IconBase64, &r.ApiCount, &r.UrlCount, &r.CDNCount, &r.CDNHosts, &r.SaveDir, &r.Status); err != nil {
+		if err := rows.Scan(&r.Url, &r.IconHash, &r.IconFinger, &r.IconBase64, &r.ApiCount, &r.UrlCount, &r.CDNCount, &r.CDNHosts, &r.SaveDir, &r.Status, &r.ServiceIP, &r.ServicePort); err != nil {
 			return nil, err
 		}
 		out = append(out, r)
@@ -317,11 +441,16 @@ type EntropyHit struct {
 }
 
 type SensitiveHit struct {
-	SourceURL string  `json:"source_url"`
-	Category  string  `json:"category"`
-	Content   string  `json:"content"`
-	Entropy   float64 `json:"entropy"`
-	SaveDir   string  `json:"save_dir"`
+	SourceURL  string  `json:"source_url"`
+	Category   string  `json:"category"`
+	RuleID     string  `json:"rule_id"`
+	Confidence string  `json:"confidence"`
+	Content    string  `json:"content"`
+	Entropy    float64 `json:"entropy"`
+	// Status is the offline validation result (validated/likely_fake/unverified) of secret hits.
+	Status  string `json:"status"`
+	Detail  string `json:"detail"`
+	SaveDir string `json:"save_dir"`
 }
 
 func SaveSensitiveHits(db *sql.DB, hits []SensitiveHit) error {
@@ -332,14 +461,14 @@ func SaveSensitiveHits(db *sql.DB, hits []SensitiveHit) error {
 	if err != nil {
 		return err
 	}
-	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO sensitive_hits (source_url, category, content, entropy, save_dir) VALUES (?, ?, ?, ?, ?)`)
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO sensitive_hits (source_url, category, rule_id, confidence, content, entropy, status, detail, save_dir) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 	defer stmt.Close()
 	for _, h := range hits {
-		if _, err := stmt.Exec(h.SourceURL, h.Category, h.Content, h.Entropy, h.SaveDir); err != nil {
+		if _, err := stmt.Exec(h.SourceURL, h.Category, h.RuleID, h.Confidence, h.Content, h.Entropy, h.Status, h.Detail, h.SaveDir); err != nil {
 			tx.Rollback()
 			return err
 		}
@@ -365,7 +494,7 @@ func LoadEntropyHits(db *sql.DB) ([]EntropyHit, error) {
 }
 
 func LoadSensitiveHits(db *sql.DB) ([]SensitiveHit, error) {
-	rows, err := db.Query(`SELECT source_url, category, content, entropy, save_dir FROM sensitive_hits ORDER BY created_at DESC`)
+	rows, err := db.Query(`SELECT source_url, category, COALESCE(rule_id, ''), COALESCE(confidence, ''), content, entropy, COALESCE(status, ''), COALESCE(detail, ''), save_dir FROM sensitive_hits ORDER BY created_at DESC`)
 	if err != nil {
 		return nil, err
 	}
@@ -373,7 +502,7 @@ func LoadSensitiveHits(db *sql.DB) ([]SensitiveHit, error) {
 	var out []SensitiveHit
 	for rows.Next() {
 		var s SensitiveHit
-		if err := rows.Scan(&s.SourceURL, &s.Category, &s.Content, &s.Entropy, &s.SaveDir); err != nil {
+		if err := rows.Scan(&s.SourceURL, &s.Category, &s.RuleID, &s.Confidence, &s.Content, &s.Entropy, &s.Status, &s.Detail, &s.SaveDir); err != nil {
 			return nil, err
 		}
 		out = append(out, s)
@@ -386,10 +515,13 @@ type APIPathRow struct {
 	SourceURL string `json:"source_url"`
 	Path      string `json:"path"`
 	SaveDir   string `json:"save_dir"`
+	// Method and Params are comma separated and only set for paths seen at a JavaScript call site.
+	Method string `json:"method"`
+	Params string `json:"params"`
 }
 
 func LoadAPIPaths(db *sql.DB) ([]APIPathRow, error) {
-	rows, err := db.Query(`SELECT root_url, source_url, path, save_dir FROM api_paths ORDER BY root_url, path`)
+	rows, err := db.Query(`SELECT root_url, source_url, path, save_dir, COALESCE(method, ''), COALESCE(params, '') FROM api_paths ORDER BY root_url, path`)
 	if err != nil {
 		return nil, err
 	}
@@ -397,7 +529,7 @@ func LoadAPIPaths(db *sql.DB) ([]APIPathRow, error) {
 	var out []APIPathRow
 	for rows.Next() {
 		var r APIPathRow
-		if err := rows.Scan(&r.RootURL, &r.SourceURL, &r.Path, &r.SaveDir); err != nil {
+		if err := rows.Scan(&r.RootURL, &r.SourceURL, &r.Path, &r.SaveDir, &r.Method, &r.Params); err != nil {
 			return nil, err
 		}
 		out = append(out, r)
@@ -550,3 +682,166 @@ func SaveService(category string, row []string) {
 		return
 	}
 }
+
+type OpenPortRow struct {
+	IP       string `json:"ip"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+// ServiceResultRow is the persisted form of a vscan Result.
+type ServiceResultRow struct {
+	IP         string `json:"ip"`
+	Port       int    `json:"port"`
+	Protocol   string `json:"protocol"`
+	Service    string `json:"service"`
+	Product    string `json:"product"`
+	Version    string `json:"version"`
+	Info       string `json:"info"`
+	Hostname   string `json:"hostname"`
+	OS         string `json:"os"`
+	DeviceType string `json:"device_type"`
+	CPE        string `json:"cpe"`
+	SoftMatch  bool   `json:"soft_match"`
+	Probe      string `json:"probe"`
+	Banner     string `json:"banner"`
+	SpiderURL  string `json:"spider_url"`
+}
+
+func SaveOpenPorts(db *sql.DB, ports []OpenPortRow) error {
+	if db == nil || len(ports) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO open_ports (ip, port, protocol) VALUES (?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, p := range ports {
+		if _, err := stmt.Exec(p.IP, p.Port, p.Protocol); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SaveServiceResult keeps the latest identification per ip/port/protocol.
+func SaveServiceResult(db *sql.DB, r ServiceResultRow) error {
+	if db == nil {
+		return nil
+	}
+	_, err := db.Exec(`INSERT OR REPLACE INTO service_results (ip, port, protocol, service, product, version, info, hostname, os, device_type, cpe, soft_match, probe, banner, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+		r.IP, r.Port, r.Protocol, r.Service, r.Product, r.Version, r.Info, r.Hostname, r.OS, r.DeviceType, r.CPE, r.SoftMatch, r.Probe, r.Banner)
+	return err
+}
+
+func LoadOpenPorts(db *sql.DB) ([]OpenPortRow, error) {
+	rows, err := db.Query(`SELECT ip, port, protocol FROM open_ports ORDER BY ip, port`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []OpenPortRow
+	for rows.Next() {
+		var r OpenPortRow
+		if err := rows.Scan(&r.IP, &r.Port, &r.Protocol); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func LoadServiceResults(db *sql.DB) ([]ServiceResultRow, error) {
+	rows, err := db.Query(`SELECT ip, port, protocol, service, product, version, info, hostname, os, device_type, cpe, soft_match, probe, banner FROM service_results ORDER BY ip, port`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ServiceResultRow
+	for rows.Next() {
+		var r ServiceResultRow
+		if err := rows.Scan(&r.IP, &r.Port, &r.Protocol, &r.Service, &r.Product, &r.Version, &r.Info, &r.Hostname, &r.OS, &r.DeviceType, &r.CPE, &r.SoftMatch, &r.Probe, &r.Banner); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// LoadPortServices returns every open port with its service identification (if any)
+// and the spider_summary url chained from it.
+func LoadPortServices(db *sql.DB) ([]ServiceResultRow, error) {
+	rows, err := db.Query(`SELECT x.*, COALESCE((SELECT sp.url FROM spider_summary sp WHERE sp.service_ip = x.ip AND sp.service_port = x.port ORDER BY sp.updated_at DESC LIMIT 1), '') FROM (
+SELECT o.ip, o.port, o.protocol, COALESCE(s.service, ''), COALESCE(s.product, ''), COALESCE(s.version, ''), COALESCE(s.info, ''), COALESCE(s.hostname, ''), COALESCE(s.os, ''), COALESCE(s.device_type, ''), COALESCE(s.cpe, ''), COALESCE(s.soft_match, 0), COALESCE(s.probe, ''), COALESCE(s.banner, '')
+FROM open_ports o LEFT JOIN service_results s ON s.ip = o.ip AND s.port = o.port AND s.protocol = o.protocol
+UNION
+SELECT s.ip, s.port, s.protocol, s.service, s.product, s.version, s.info, s.hostname, s.os, s.device_type, s.cpe, s.soft_match, s.probe, s.banner
+FROM service_results s WHERE NOT EXISTS (SELECT 1 FROM open_ports o WHERE o.ip = s.ip AND o.port = s.port AND o.protocol = s.protocol)
+) x ORDER BY 1, 2`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ServiceResultRow
+	for rows.Next() {
+		var r ServiceResultRow
+		if err := rows.Scan(&r.IP, &r.Port, &r.Protocol, &r.Service, &r.Product, &r.Version, &r.Info, &r.Hostname, &r.OS, &r.DeviceType, &r.CPE, &r.SoftMatch, &r.Probe, &r.Banner, &r.SpiderURL); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// SaveAPIEndpoints stores Swagger/OpenAPI operations; params are kept as a json array.
+func SaveAPIEndpoints(db *sql.DB, rows []APIEndpointRow) error {
+	if db == nil || len(rows) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO api_endpoints (root_url, doc_url, method, path, summary, params, security) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range rows {
+		params, _ := json.Marshal(r.Params)
+		if _, err := stmt.Exec(r.RootURL, r.DocURL, r.Method, r.Path, r.Summary, string(params), r.Security); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func LoadAPIEndpoints(db *sql.DB) ([]APIEndpointRow, error) {
+	rows, err := db.Query(`SELECT root_url, doc_url, method, path, COALESCE(summary, ''), COALESCE(params, ''), COALESCE(security, '') FROM api_endpoints ORDER BY root_url, path, method`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []APIEndpointRow
+	for rows.Next() {
+		var r APIEndpointRow
+		var params string
+		if err := rows.Scan(&r.RootURL, &r.DocURL, &r.Method, &r.Path, &r.Summary, &params, &r.Security); err != nil {
+			return nil, err
+		}
+		if params != "" {
+			_ = json.Unmarshal([]byte(params), &r.Params)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -28,11 +28,18 @@ func formatUrl(raw string) string {
 }
 
 func DirBrute(baseUrl string, dir string) []string {
+	result, _ := DirBruteWithLeads(baseUrl, dir)
+	return result
+}
+
+// DirBruteWithLeads is DirBrute that also returns the new candidate paths listed by an exposed
+// .DS_Store or .svn hit, relative to baseUrl like dir.
+func DirBruteWithLeads(baseUrl string, dir string) ([]string, []string) {
 	result := []string{}
 	baseURL, err := url.Parse(formatUrl(baseUrl))
 	if err != nil {
 		Error("%s", err)
-		return []string{}
+		return []string{}, nil
 	}
 	fullURL := baseURL.ResolveReference(&url.URL{Path: path.Join(baseURL.Path, dir)})
 	if strings.HasSuffix(dir, "/") && dir != "/" {
@@ -45,6 +52,22 @@ func DirBrute(baseUrl string, dir string) []string {
 	location := fres.Location
 	respBody := fres.Body
 	statusCode := fres.Status
+	if statusCode == 200 && looksLikeJSON(respBody) {
+		IngestAPIDocs(GetSpiderDB(), apiDocRoot(fullURL.String()), fullURL.String(), respBody)
+	}
+	if statusCode == 200 && (strings.HasSuffix(dir, ".git/HEAD") || strings.HasSuffix(dir, ".git/config")) {
+		if !strings.HasSuffix(dir, "HEAD") || IsGitHead(respBody) {
+			MaybeDumpGit(strings.TrimSuffix(strings.TrimSuffix(fullURL.String(), "HEAD"), "config"))
+		}
+	}
+	if statusCode == 200 {
+		RunMiddlewareModules(fullURL.String(), fres, GetSpiderDB())
+		MaybeInspectActuator(fullURL.String(), respBody)
+		if IsArtifactPath(dir) {
+			MaybeScanArtifact(fullURL.String())
+		}
+	}
+	leads := exposureLeads(fullURL.String(), dir, statusCode, respBody)
 	if statusCode == 200 || statusCode == 500 || statusCode == 302 {
 		result = CheckFinger(finger, title, fullURL.String(), contentType, location, respBody, statusCode)
 	}
@@ -60,5 +83,5 @@ func DirBrute(baseUrl string, dir string) []string {
 			result[5] = color.GreenString(result[5])
 		}
 	}
-	return result
+	return result, leads
 }
@@ -0,0 +1,350 @@
+package utils
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxLeadDepth bounds how many directory levels .DS_Store / .svn leads may descend.
+const maxLeadDepth = 6
+
+// dsStoreMaxRecords caps the records (and so the names) read from one .DS_Store file.
+const dsStoreMaxRecords = 1 << 16
+
+var dsStoreMagic = []byte{0, 0, 0, 1, 'B', 'u', 'd', '1'}
+
+// IsDSStore reports whether body is a .DS_Store file.
+func IsDSStore(body []byte) bool {
+	return bytes.HasPrefix(body, dsStoreMagic)
+}
+
+// dsStore reads the buddy allocator of a .DS_Store file; every offset is relative to byte 4.
+type dsStore struct {
+	data   []byte
+	blocks []uint32
+	// visited and records keep crafted trees sharing blocks from blowing up the walk
+	visited map[uint32]bool
+	records int
+}
+
+func (d *dsStore) u32(off int) (uint32, error) {
+	off += 4
+	if off < 4 || off+4 > len(d.data) {
+		return 0, fmt.Errorf("offset %d out of range", off)
+	}
+	return binary.BigEndian.Uint32(d.data[off:]), nil
+}
+
+func (d *dsStore) block(id uint32) (int, error) {
+	if int(id) >= len(d.blocks) {
+		return 0, fmt.Errorf("block %d out of range", id)
+	}
+	return int(d.blocks[id] &^ 0x1f), nil
+}
+
+// ParseDSStore lists the file names recorded in a .DS_Store (Bud1) file.
+func ParseDSStore(data []byte) ([]string, error) {
+	if !IsDSStore(data) {
+		return nil, fmt.Errorf("not a .DS_Store file")
+	}
+	d := &dsStore{data: data, visited: make(map[uint32]bool)}
+	root, err := d.u32(4)
+	if err != nil {
+		return nil, err
+	}
+	count, err := d.u32(int(root))
+	if err != nil {
+		return nil, err
+	}
+	pos := int(root) + 8
+	if count > 1<<16 {
+		return nil, fmt.Errorf("bad block count %d", count)
+	}
+	for i := uint32(0); i < count; i++ {
+		addr, err := d.u32(pos)
+		if err != nil {
+			return nil, err
+		}
+		d.blocks = append(d.blocks, addr)
+		pos += 4
+	}
+	// block addresses are padded to a multiple of 256 entries
+	pos = int(root) + 8 + int((count+255)/256*256)*4
+	tocCount, err := d.u32(pos)
+	if err != nil {
+		return nil, err
+	}
+	pos += 4
+	dsdb := uint32(0)
+	found := false
+	for i := uint32(0); i < tocCount; i++ {
+		if pos+4 >= len(d.data) {
+			return nil, fmt.Errorf("toc truncated")
+		}
+		n := int(d.data[pos+4])
+		name := string(d.data[pos+5 : min(pos+5+n, len(d.data))])
+		id, err := d.u32(pos + 1 + n)
+		if err != nil {
+			return nil, err
+		}
+		pos += 1 + n + 4
+		if name == "DSDB" {
+			dsdb, found = id, true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no DSDB entry")
+	}
+	off, err := d.block(dsdb)
+	if err != nil {
+		return nil, err
+	}
+	rootNode, err := d.u32(off)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]struct{})
+	if err := d.walk(rootNode, names, 0); err != nil && len(names) == 0 {
+		return nil, err
+	}
+	out := make([]string, 0, len(names))
+	for n := range names {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (d *dsStore) walk(node uint32, names map[string]struct{}, depth int) error {
+	if depth > 32 {
+		return fmt.Errorf("tree too deep")
+	}
+	if d.visited[node] {
+		return nil
+	}
+	d.visited[node] = true
+	pos, err := d.block(node)
+	if err != nil {
+		return err
+	}
+	next, err := d.u32(pos)
+	if err != nil {
+		return err
+	}
+	count, err := d.u32(pos + 4)
+	if err != nil {
+		return err
+	}
+	pos += 8
+	for i := uint32(0); i < count; i++ {
+		if next != 0 {
+			child, err := d.u32(pos)
+			if err != nil {
+				return err
+			}
+			if err := d.walk(child, names, depth+1); err != nil {
+				return err
+			}
+			pos += 4
+		}
+		if d.records++; d.records > dsStoreMaxRecords {
+			return fmt.Errorf("too many records")
+		}
+		name, n, err := d.record(pos)
+		if err != nil {
+			return err
+		}
+		if name != "." && name != "" {
+			names[name] = struct{}{}
+		}
+		pos = n
+	}
+	if next != 0 {
+		return d.walk(next, names, depth+1)
+	}
+	return nil
+}
+
+// record decodes one (filename, code, type, value) record and returns the offset after it.
+func (d *dsStore) record(pos int) (string, int, error) {
+	n, err := d.u32(pos)
+	if err != nil || n > 1024 {
+		return "", 0, fmt.Errorf("bad record name length")
+	}
+	start := pos + 4 + 4
+	end := start + int(n)*2
+	if end+8 > len(d.data) {
+		return "", 0, fmt.Errorf("record truncated")
+	}
+	u := make([]uint16, n)
+	for i := range u {
+		u[i] = binary.BigEndian.Uint16(d.data[start+i*2:])
+	}
+	name := string(utf16.Decode(u))
+	pos = pos + 4 + int(n)*2 + 4
+	kind := string(d.data[pos+4 : pos+8])
+	pos += 4
+	switch kind {
+	case "bool":
+		pos++
+	case "long", "shor", "type":
+		pos += 4
+	case "comp", "dutc":
+		pos += 8
+	case "blob":
+		l, err := d.u32(pos)
+		if err != nil {
+			return "", 0, err
+		}
+		pos += 4 + int(l)
+	case "ustr":
+		l, err := d.u32(pos)
+		if err != nil {
+			return "", 0, err
+		}
+		pos += 4 + int(l)*2
+	default:
+		return "", 0, fmt.Errorf("unknown record type %q", kind)
+	}
+	return name, pos, nil
+}
+
+// ParseSVNEntries lists the names of a pre-1.7 .svn/entries file; dirs end with "/".
+func ParseSVNEntries(data []byte) ([]string, error) {
+	text := string(data)
+	first, _, _ := strings.Cut(text, "\n")
+	if _, err := strconv.Atoi(strings.TrimSpace(first)); err != nil {
+		return nil, fmt.Errorf("not an svn entries file")
+	}
+	var out []string
+	for _, block := range strings.Split(text, "\f\n")[1:] {
+		lines := strings.SplitN(block, "\n", 3)
+		if len(lines) < 2 || lines[0] == "" {
+			continue
+		}
+		switch lines[1] {
+		case "dir":
+			out = append(out, lines[0]+"/")
+		case "file":
+			out = append(out, lines[0])
+		}
+	}
+	return out, nil
+}
+
+// ParseSVNWCDB lists the tracked paths of an SVN 1.7+ wc.db; dirs end with "/".
+func ParseSVNWCDB(data []byte) ([]string, error) {
+	if !bytes.HasPrefix(data, []byte("SQLite format 3\x00")) {
+		return nil, fmt.Errorf("not a sqlite database")
+	}
+	tmp, err := os.CreateTemp("", "wc-*.db")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	tmp.Close()
+	db, err := sql.Open("sqlite", tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT DISTINCT local_relpath, kind FROM NODES WHERE local_relpath != '' ORDER BY local_relpath`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var p, kind string
+		if err := rows.Scan(&p, &kind); err != nil {
+			return out, err
+		}
+		if kind == "dir" {
+			p += "/"
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// exposureLeads turns a dirbrute hit on .DS_Store / .svn metadata into new candidate paths,
+// relative to the same base url. Directories get their own metadata file queued, so the
+// listing recurses through dirbrute itself.
+func exposureLeads(fullURL, dir string, status int, body []byte) []string {
+	var names []string
+	var err error
+	// meta is the listing file itself, queued again under every directory found
+	var meta, kind string
+	switch {
+	case strings.HasSuffix(dir, ".DS_Store") && status == 200 && IsDSStore(body):
+		kind, meta = ".DS_Store", ".DS_Store"
+		names, err = ParseDSStore(body)
+		for i, n := range names {
+			// .DS_Store does not say which names are folders: queue a listing for extension-less ones
+			if !strings.Contains(n, ".") {
+				names[i] = n + "/"
+			}
+		}
+	case (strings.HasSuffix(dir, ".svn") || strings.HasSuffix(dir, ".svn/")) && (status == 200 || status == 301 || status == 302 || status == 403):
+		base := strings.TrimSuffix(strings.TrimSuffix(dir, "/"), ".svn")
+		return []string{base + ".svn/wc.db", base + ".svn/entries"}
+	case strings.HasSuffix(dir, ".svn/wc.db") && status == 200:
+		kind, meta = "svn wc.db", ".svn/wc.db"
+		if data, derr := fetchFull(fullURL); derr == nil {
+			body = data
+		}
+		names, err = ParseSVNWCDB(body)
+	case strings.HasSuffix(dir, ".svn/entries") && status == 200:
+		kind, meta = "svn entries", ".svn/entries"
+		names, err = ParseSVNEntries(body)
+	default:
+		return nil
+	}
+	if err != nil {
+		Debug("%s %s: %v", kind, fullURL, err)
+		return nil
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	Success("[%s] %s lists %d path(s): %s", kind, fullURL, len(names), strings.Join(names, ", "))
+	prefix := strings.TrimSuffix(dir, meta)
+	var leads []string
+	for _, n := range names {
+		p := prefix + n
+		if strings.Count(p, "/") > maxLeadDepth {
+			continue
+		}
+		leads = append(leads, p)
+		// wc.db already holds the whole working copy, no need to recurse
+		if strings.HasSuffix(n, "/") && meta != ".svn/wc.db" {
+			leads = append(leads, p+meta)
+		}
+	}
+	return leads
+}
+
+func fetchFull(rawURL string) ([]byte, error) {
+	resp, err := fetchGet(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 64*1024*1024))
+}
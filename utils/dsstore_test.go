@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+type dsTestRecord struct {
+	name, code, kind string
+	value            []byte
+}
+
+// buildDSStore writes a minimal Bud1 file: one DSDB header block, one leaf node and the root block.
+func buildDSStore(records []dsTestRecord) []byte {
+	be := binary.BigEndian
+	rel := make([]byte, 8192)
+	const dsdbOff, leafOff, rootOff = 32, 64, 4096
+
+	copy(rel[0:], "Bud1")
+	be.PutUint32(rel[4:], rootOff)
+	be.PutUint32(rel[8:], 2048)
+	be.PutUint32(rel[12:], rootOff)
+
+	be.PutUint32(rel[dsdbOff:], 2) // root node is block 2
+	be.PutUint32(rel[dsdbOff+8:], uint32(len(records)))
+	be.PutUint32(rel[dsdbOff+12:], 1)
+	be.PutUint32(rel[dsdbOff+16:], 0x1000)
+
+	pos := leafOff
+	be.PutUint32(rel[pos+4:], uint32(len(records)))
+	pos += 8
+	for _, r := range records {
+		u := utf16.Encode([]rune(r.name))
+		be.PutUint32(rel[pos:], uint32(len(u)))
+		pos += 4
+		for _, c := range u {
+			be.PutUint16(rel[pos:], c)
+			pos += 2
+		}
+		copy(rel[pos:], r.code)
+		copy(rel[pos+4:], r.kind)
+		pos += 8
+		pos += copy(rel[pos:], r.value)
+	}
+
+	pos = rootOff
+	be.PutUint32(rel[pos:], 3)
+	pos += 8
+	be.PutUint32(rel[pos:], dsdbOff|5)
+	be.PutUint32(rel[pos+4:], rootOff|11)
+	be.PutUint32(rel[pos+8:], leafOff|11)
+	pos += 256 * 4
+	be.PutUint32(rel[pos:], 1)
+	rel[pos+4] = 4
+	copy(rel[pos+5:], "DSDB")
+	be.PutUint32(rel[pos+9:], 0)
+	return append([]byte{0, 0, 0, 1}, rel...)
+}
+
+func TestParseDSStore(t *testing.T) {
+	blob := append([]byte{0, 0, 0, 16}, make([]byte, 16)...)
+	ustr := []byte{0, 0, 0, 2, 0, 'h', 0, 'i'}
+	data := buildDSStore([]dsTestRecord{
+		{".", "vSrn", "long", []byte{0, 0, 0, 1}},
+		{"admin", "lg1S", "comp", make([]byte, 8)},
+		{"backup", "BKGD", "blob", blob},
+		{"config.php.bak", "cmmt", "ustr", ustr},
+		{"config.php.bak", "Iloc", "blob", blob},
+		{"数据库.sql", "dscl", "bool", []byte{1}},
+	})
+	names, err := ParseDSStore(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []string{"admin", "backup", "config.php.bak", "数据库.sql"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("got %v, want %v", names, want)
+	}
+	if _, err := ParseDSStore([]byte("<html></html>")); err == nil {
+		t.Fatal("expected error for html")
+	}
+
+	leads := exposureLeads("http://h/static/.DS_Store", "static/.DS_Store", 200, data)
+	wantLeads := []string{"static/admin/", "static/admin/.DS_Store", "static/backup/", "static/backup/.DS_Store", "static/config.php.bak", "static/数据库.sql"}
+	if !reflect.DeepEqual(leads, wantLeads) {
+		t.Fatalf("leads %v, want %v", leads, wantLeads)
+	}
+}
+
+func TestParseDSStoreSharedBlocks(t *testing.T) {
+	// a chain of internal nodes whose child and next pointers name the same block:
+	// walked naively that is 2^30 visits of the leaf
+	be := binary.BigEndian
+	rel := make([]byte, 16384)
+	const depth, dsdbOff, rootOff, nodeOff = 30, 32, 4096, 8192
+	copy(rel[0:], "Bud1")
+	be.PutUint32(rel[4:], rootOff)
+	be.PutUint32(rel[8:], 2048)
+	be.PutUint32(rel[12:], rootOff)
+	be.PutUint32(rel[dsdbOff:], 2)
+
+	pos := rootOff
+	be.PutUint32(rel[pos:], depth+3)
+	pos += 8
+	be.PutUint32(rel[pos:], dsdbOff|5)
+	be.PutUint32(rel[pos+4:], rootOff|11)
+	for k := 0; k <= depth; k++ {
+		off := nodeOff + k*64
+		be.PutUint32(rel[pos+8+k*4:], uint32(off)|6)
+		p := off
+		if k < depth {
+			be.PutUint32(rel[p:], uint32(k+3))
+		}
+		be.PutUint32(rel[p+4:], 1)
+		p += 8
+		if k < depth {
+			be.PutUint32(rel[p:], uint32(k+3))
+			p += 4
+		}
+		be.PutUint32(rel[p:], 1)
+		be.PutUint16(rel[p+4:], uint16('a'+k%26))
+		copy(rel[p+6:], "dsclbool")
+	}
+	pos = rootOff + 8 + 256*4
+	be.PutUint32(rel[pos:], 1)
+	rel[pos+4] = 4
+	copy(rel[pos+5:], "DSDB")
+
+	names, err := ParseDSStore(append([]byte{0, 0, 0, 1}, rel...))
+	if err != nil || len(names) != 26 {
+		t.Fatalf("got %v %v", names, err)
+	}
+}
+
+func TestParseSVN(t *testing.T) {
+	entries := "10\n\ndir\n42\nhttp://svn/trunk\n\f\nlogin.php\nfile\n\n\f\nupload\ndir\n\f\n"
+	names, err := ParseSVNEntries([]byte(entries))
+	if err != nil || !reflect.DeepEqual(names, []string{"login.php", "upload/"}) {
+		t.Fatalf("entries: %v %v", names, err)
+	}
+	if leads := exposureLeads("http://h/a/.svn/entries", "a/.svn/entries", 200, []byte(entries)); !reflect.DeepEqual(leads, []string{"a/login.php", "a/upload/", "a/upload/.svn/entries"}) {
+		t.Fatalf("entries leads: %v", leads)
+	}
+	if leads := exposureLeads("http://h/.svn", ".svn", 403, nil); !reflect.DeepEqual(leads, []string{".svn/wc.db", ".svn/entries"}) {
+		t.Fatalf(".svn leads: %v", leads)
+	}
+
+	path := filepath.Join(t.TempDir(), "wc.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		`CREATE TABLE NODES (wc_id INTEGER, local_relpath TEXT, op_depth INTEGER, kind TEXT)`,
+		`INSERT INTO NODES VALUES (1, '', 0, 'dir'), (1, 'WEB-INF', 0, 'dir'), (1, 'WEB-INF/web.xml', 0, 'file'), (1, 'WEB-INF/web.xml', 1, 'file')`,
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err = ParseSVNWCDB(raw)
+	if err != nil || !reflect.DeepEqual(names, []string{"WEB-INF/", "WEB-INF/web.xml"}) {
+		t.Fatalf("wc.db: %v %v", names, err)
+	}
+}
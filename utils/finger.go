@@ -51,6 +51,7 @@ type SpiderSummary struct {
 	Keyword     string
 	SimHash     string
 	IconHash    string
+	IconFinger  string
 	IconBase64  string
 	ApiCount    int
 	UrlCount    int
@@ -222,7 +223,7 @@ func sourceMapFromContent(jsURL string, body string) string {
 	return base.ResolveReference(u).String()
 }
 
-func probeSourceMap(rootURL, jsURL, saveDir string, seen mapset.Set, db *sql.DB) {
+func probeSourceMap(rootURL, jsURL, saveDir string, seen mapset.Set, apiCounter *int, db *sql.DB) {
 	mapURL := buildSourceMapURL(jsURL)
 	if mapURL == "" {
 		return
@@ -249,6 +250,9 @@ func probeSourceMap(rootURL, jsURL, saveDir string, seen mapset.Set, db *sql.DB)
 		Status:  status,
 		Length:  length,
 	}})
+	if viper.GetBool("spider-sourcemap-download") {
+		unpackSourceMap(rootURL, mapURL, saveDir, apiCounter, db)
+	}
 }
 
 func parseDir(fullPath string, MaxDepth int) []string {
@@ -342,6 +346,11 @@ func isUsefulAPIPath(path string) bool {
 }
 
 func ParseJavaScriptUrl(Url string, RootPath string, doc string, directory string, apiCounter *int, db *sql.DB) {
+	parseJavaScript(Url, RootPath, newJSAnalyzer(doc), directory, apiCounter, db)
+}
+
+func parseJavaScript(Url string, RootPath string, js *jsAnalyzer, directory string, apiCounter *int, db *sql.DB) {
+	doc := js.src
 	Debug("Parsing JavaScript for API paths: %s", Url)
 	quote := "['\"`]"
 	ApiReg := regexp.MustCompile(quote + `[\w\$\{\}]*(?P<path>/[\w/\-\|_=@\?\:.]+?)` + quote)
@@ -357,14 +366,61 @@ func ParseJavaScriptUrl(Url string, RootPath string, doc string, directory strin
 		ApiResult = append(ApiResult, viper.GetString("ApiPrefix")+apiPath)
 	}
 	ApiResult = RemoveDuplicatesString(ApiResult)
+
+	// call sites add the method and parameter names; their paths replace the regex matches
+	apiCalls := js.apiCalls()
+	callPaths := make(map[string]struct{})
+	callLines := []string{}
+	for i := range apiCalls {
+		apiCalls[i].Path = viper.GetString("ApiPrefix") + apiCalls[i].Path
+		callPaths[apiCalls[i].Path] = struct{}{}
+		line := apiCalls[i].Path
+		if apiCalls[i].Method != "" {
+			line = apiCalls[i].Method + " " + line
+		}
+		if len(apiCalls[i].Params) > 0 {
+			line += " [" + strings.Join(apiCalls[i].Params, ",") + "]"
+		}
+		callLines = append(callLines, line)
+	}
+	regexOnly := []string{}
+	for _, apiPath := range ApiResult {
+		if _, ok := callPaths[apiPath]; !ok {
+			regexOnly = append(regexOnly, apiPath)
+		}
+	}
+	for _, call := range apiCalls {
+		ApiResult = append(ApiResult, call.Path)
+	}
+	ApiResult = RemoveDuplicatesString(ApiResult)
 	ApiResultLen := len(ApiResult)
 
 	if ApiResultLen > 0 {
 		*apiCounter += ApiResultLen
-		var totalResult = strings.Join(ApiResult, "\n")
-		ImportantApiJudge(totalResult, Url)
+		var totalResult = strings.Join(append(regexOnly, callLines...), "\n")
+		ImportantApiJudge(strings.Join(ApiResult, "\n"), Url)
 		FileWrite(directory+"api_trace.log", "==== %s ====\n# total: %d\n%s\n", Url, ApiResultLen, totalResult)
-		SaveAPIPaths(db, RootPath, Url, ApiResult, directory)
+		SaveAPIPaths(db, RootPath, Url, regexOnly, directory)
+		SaveAPICalls(db, RootPath, Url, apiCalls, directory)
+	}
+
+	if routes := js.frontendRoutes(); len(routes) > 0 {
+		lines := make([]string, 0, len(routes))
+		for _, r := range routes {
+			line := r.Path
+			if r.Component != "" {
+				line += " -> " + r.Component
+			}
+			if r.RequiresAuth {
+				line += " [auth]"
+			}
+			if r.Hidden {
+				line += " [hidden]"
+			}
+			lines = append(lines, line)
+		}
+		FileWrite(directory+"frontend_routes.log", "==== %s ====\n# total: %d\n%s\n", Url, len(routes), strings.Join(lines, "\n"))
+		SaveFrontendRoutes(db, RootPath, Url, routes)
 	}
 
 	subdirs := []string{}
@@ -401,7 +457,7 @@ func ParseJavaScriptUrl(Url string, RootPath string, doc string, directory strin
 		cnt := 0
 
 		for _, line := range subdirs {
-			for _, dir := range []string{".git/config", "swagger-resources", "v2/api-docs", ""} {
+			for _, dir := range []string{".git/config", "swagger-resources", "v2/api-docs", "v3/api-docs", ""} {
 				cnt += 1
 				if cnt >= 50 {
 					continue
@@ -457,7 +513,7 @@ func Spider(RootPath string, Url string, depth int, directory string, myMap maps
 			return err
 		}
 		defer resp.Body.Close()
-		return handleJSAsset(RootPath, Url, u.Path, resp, directory, sourceMapSeen, apiCounter, db)
+		return handleJSAsset(RootPath, Url, u.Path, resp, directory, myMap, sourceMapSeen, apiCounter, db)
 	}
 	if cached != nil {
 		return handleHTMLContent(RootPath, Url, depth, directory, myMap, sourceMapSeen, apiCounter, db, cached.body)
@@ -480,22 +536,52 @@ func fetchGet(Url string) (*http.Response, error) {
 	return Client.Do(req)
 }
 
-func handleJSAsset(rootPath, fullURL, path string, resp *http.Response, directory string, sourceMapSeen mapset.Set, apiCounter *int, db *sql.DB) error {
-	probeSourceMap(rootPath, fullURL, directory, sourceMapSeen, db)
+func handleJSAsset(rootPath, fullURL, path string, resp *http.Response, directory string, myMap mapset.Set, sourceMapSeen mapset.Set, apiCounter *int, db *sql.DB) error {
+	probeSourceMap(rootPath, fullURL, directory, sourceMapSeen, apiCounter, db)
 	bodyStr := readBodyString(resp)
 	if sm := sourceMapFromContent(fullURL, bodyStr); sm != "" {
-		probeSourceMap(rootPath, sm, directory, sourceMapSeen, db)
+		probeSourceMap(rootPath, sm, directory, sourceMapSeen, apiCounter, db)
 	}
 	if _, ok := sensitiveUrl.Load(fullURL); !ok {
 		sensitiveUrl.Store(fullURL, true)
 		SensitiveInfoCollect(db, fullURL, bodyStr, directory)
 	}
+	js := newJSAnalyzer(bodyStr)
 	if IsJavaScriptPath(path) {
-		ParseJavaScriptUrl(fullURL, rootPath, bodyStr, directory, apiCounter, db)
+		parseJavaScript(fullURL, rootPath, js, directory, apiCounter, db)
 	}
+	crawlChunks(rootPath, fullURL, js, directory, myMap, sourceMapSeen, apiCounter, db)
 	return nil
 }
 
+// crawlChunks fetches the lazy chunks named in a webpack/Vite/Next.js bundle, which no page links to.
+func crawlChunks(rootPath, fullURL string, js *jsAnalyzer, directory string, myMap mapset.Set, sourceMapSeen mapset.Set, apiCounter *int, db *sql.DB) {
+	maxURLs := viper.GetInt("spider-max-urls-per-host")
+	for _, chunk := range discoverChunks(fullURL, js.src, js.toks) {
+		if maxURLs > 0 && myMap.Cardinality() >= maxURLs {
+			Debug("quota reached (%d urls) for %s, skip remaining chunks of %s", maxURLs, rootPath, fullURL)
+			return
+		}
+		if filterOutUrl(chunk) || !myMap.Add(chunk) {
+			continue
+		}
+		u, err := url.Parse(chunk)
+		if err != nil {
+			continue
+		}
+		GetGraphCollector().AddEdge(rootPath, fullURL, chunk, 0)
+		resp, err := fetchGet(chunk)
+		if err != nil {
+			Debug("fetch chunk %s: %v", chunk, err)
+			continue
+		}
+		if resp.StatusCode == http.StatusOK {
+			handleJSAsset(rootPath, chunk, u.Path, resp, directory, myMap, sourceMapSeen, apiCounter, db)
+		}
+		resp.Body.Close()
+	}
+}
+
 func readBodyString(resp *http.Response) string {
 	maxBody := viper.GetInt("max-body-bytes")
 	if maxBody <= 0 || maxBody > 4*1024*1024 {
@@ -525,7 +611,7 @@ func crawlLinks(doc *goquery.Document, rootPath string, currentURL string, depth
 		}
 		normalizeUrl := Normalize(src, currentURL)
 		if goquery.NodeName(selector) == "script" {
-			probeSourceMap(rootPath, normalizeUrl, directory, sourceMapSeen, db)
+			probeSourceMap(rootPath, normalizeUrl, directory, sourceMapSeen, apiCounter, db)
 		}
 		if normalizeUrl != "" && !myMap.Contains(normalizeUrl) {
 			GetGraphCollector().AddEdge(rootPath, currentURL, normalizeUrl, depth)
@@ -544,13 +630,16 @@ func handleHTMLContent(rootPath, Url string, depth int, directory string, myMap
 	doc.Find("script").Each(func(i int, selector *goquery.Selection) {
 		if src, ok := selector.Attr("src"); ok {
 			normalizeUrl := Normalize(src, Url)
-			probeSourceMap(rootPath, normalizeUrl, directory, sourceMapSeen, db)
+			probeSourceMap(rootPath, normalizeUrl, directory, sourceMapSeen, apiCounter, db)
 		}
 	})
 	if _, ok := sensitiveUrl.Load(Url); !ok {
 		sensitiveUrl.Store(Url, true)
 		SensitiveInfoCollect(db, Url, bodyStr, directory)
 	}
+	if manifest := NextBuildManifestURL(Url, bodyStr); manifest != "" && !myMap.Contains(manifest) {
+		Spider(rootPath, manifest, depth, directory, myMap, sourceMapSeen, apiCounter, db, nil)
+	}
 	crawlLinks(doc, rootPath, Url, depth, directory, myMap, sourceMapSeen, apiCounter, db)
 	return nil
 }
@@ -581,6 +670,54 @@ func StandBase64(braw []byte) []byte {
 //go:embed icon.json
 var icon_json string
 
+var (
+	iconFingerOnce sync.Once
+	iconFingerMap  map[string]string
+)
+
+// IconProduct maps a fofa icon hash to the product name from the embedded icon.json.
+func IconProduct(fofaHash string) string {
+	iconFingerOnce.Do(func() {
+		iconFingerMap = make(map[string]string)
+		if err := json.Unmarshal([]byte(icon_json), &iconFingerMap); err != nil {
+			Debug("load icon.json failed: %v", err)
+		}
+	})
+	return iconFingerMap[fofaHash]
+}
+
+type IconResult struct {
+	Target  string
+	IconURL string
+	Fofa    string
+	Hunter  string
+	Base64  string
+	Product string
+	Err     error
+}
+
+// ResolveIcon finds the favicon of a page (rel=icon, falling back to /favicon.ico) or takes
+// a direct .ico url, hashes it and looks the hash up in icon.json.
+func ResolveIcon(target string) IconResult {
+	res := IconResult{Target: target}
+	u, err := url.Parse(target)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	res.IconURL = target
+	if !strings.HasSuffix(strings.ToLower(u.Path), ".ico") {
+		if res.IconURL, err = FindFaviconURL(target); err != nil {
+			res.IconURL = u.Scheme + "://" + u.Host + "/favicon.ico"
+		}
+	}
+	res.Fofa, res.Hunter, res.Base64, res.Err = IconDetect(res.IconURL)
+	if res.Err == nil {
+		res.Product = IconProduct(res.Fofa)
+	}
+	return res
+}
+
 func IconDetect(Url string) (string, string, string, error) {
 	req, _ := http.NewRequest(http.MethodGet, Url, nil)
 	SetHeaders(req)
@@ -791,6 +928,7 @@ func runFingerSummary(firstURL, origURL, rootPath string, Depth int, db *sql.DB)
 	fres := FingerScan(firstURL, http.MethodGet, false)
 	applyFingerResult(&out, fres, origURL, rootPath, firstURL, db)
 	trySecondaryFinger(&out, origURL, rootPath)
+	RunMiddlewareModules(firstURL, fres, db)
 	handleFaviconFromBody(&out, rootPath, fres.Body)
 	runSpider(&out, rootPath, origURL, Depth, db, fres)
 	return out
@@ -815,6 +953,9 @@ func applyFingerResult(out *SpiderSummary, fres FingerResult, origURL, rootPath,
 	if fres.Status == -1 {
 		return
 	}
+	if custom := userFingerPacks(fres.FingerPacks); custom != "" {
+		Info("%s matched user finger rules: %s", origURL, custom)
+	}
 	result := CheckFinger(fres.Finger, fres.Title, origURL, fres.ContentType, "", fres.Body, fres.Status)
 	if len(result) == 0 {
 		return
@@ -858,16 +999,24 @@ func handleFaviconFromBody(out *SpiderSummary, rootPath string, body []byte) {
 	if fofaHash, hunterHash, iconB64, errHash := IconDetect(iconURL); errHash == nil {
 		out.IconHash = fmt.Sprintf("fofa: icon_hash=\"%s\"\nhunter: web.icon=\"%s\"", fofaHash, hunterHash)
 		out.IconBase64 = iconB64
-		var icon_hash_map map[string]interface{}
-		json.Unmarshal([]byte(icon_json), &icon_hash_map)
-		if tmp := icon_hash_map[fofaHash]; tmp != nil {
-			Debug("icon_url=\"%s\" icon_finger=\"%s\"", iconURL, tmp)
+		if product := IconProduct(fofaHash); product != "" {
+			out.IconFinger = product
+			Debug("icon_url=\"%s\" icon_finger=\"%s\"", iconURL, product)
 		}
 	} else {
 		Debug("%s", errHash)
 	}
 }
 
+// hostOutputDir is the per-host, per-day output directory <output-dir>/<date>/<host>_<port>/<sub>/.
+func hostOutputDir(u *url.URL, sub string) string {
+	outDir := viper.GetString("output-dir")
+	if outDir == "" {
+		outDir = "output"
+	}
+	return fmt.Sprintf("%s/%s/%s/%s/", outDir, time.Now().Format("2006-01-02"), u.Hostname()+"_"+u.Port(), sub)
+}
+
 func runSpider(out *SpiderSummary, rootPath, origURL string, depth int, db *sql.DB, fres FingerResult) {
 	myMap := mapset.NewSet()
 	sourceMapSeen := mapset.NewSet()
@@ -877,11 +1026,7 @@ func runSpider(out *SpiderSummary, rootPath, origURL string, depth int, db *sql.
 		out.Err = err
 		return
 	}
-	outDir := viper.GetString("output-dir")
-	if outDir == "" {
-		outDir = "output"
-	}
-	directory := fmt.Sprintf("%s/%s/%s/spider/", outDir, time.Now().Format("2006-01-02"), host.Hostname()+"_"+host.Port())
+	directory := hostOutputDir(host, "spider")
 	apiCounter := 0
 	err = Spider(rootPath, origURL, depth, directory, myMap, sourceMapSeen, &apiCounter, db, &prefetchedPage{
 		body:        string(fres.Body),
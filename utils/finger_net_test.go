@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -118,12 +119,22 @@ func TestFingerScanFollowRedirect(t *testing.T) {
 
 // TestFingerSummaryTimeout ensures per-host timeout cancels slow targets.
 func TestFingerSummaryTimeout(t *testing.T) {
+	var requests int32
 	srv := mustTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		time.Sleep(2 * time.Second)
+		if atomic.AddInt32(&requests, 1) == 1 {
+			time.Sleep(2 * time.Second)
+		}
 		w.WriteHeader(http.StatusOK)
 	}))
 	defer srv.Close()
 
+	// The abandoned run keeps spidering after the timeout: keep its output in a temp dir
+	// and wait for its spider.log before handing the dir back.
+	outDir := t.TempDir()
+	oldOutputDir := viper.GetString("output-dir")
+	viper.Set("output-dir", outDir)
+	t.Cleanup(func() { viper.Set("output-dir", oldOutputDir) })
+
 	// Tight timeout to force cancellation.
 	oldTimeout := viper.GetInt("spider-timeout-per-host")
 	viper.Set("spider-timeout-per-host", 1)
@@ -135,6 +146,15 @@ func TestFingerSummaryTimeout(t *testing.T) {
 		Client, ClientNoRedirect = oldClient, oldNoRedirect
 	}()
 
+	defer func() {
+		for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+			if logs, _ := filepath.Glob(filepath.Join(outDir, "*", "*", "spider", "spider.log")); len(logs) > 0 {
+				return
+			}
+		}
+		t.Errorf("abandoned run never wrote its spider.log")
+	}()
+
 	res := FingerSummary(srv.URL, 1, nil)
 	if res.Err == nil {
 		t.Fatalf("expected timeout error")
@@ -163,3 +183,40 @@ func tryNewServer(h http.Handler) (srv *httptest.Server, err error) {
 	srv = httptest.NewServer(h)
 	return srv, err
 }
+
+// TestResolveIconFallbackAndProduct falls back to /favicon.ico and maps the hash via icon.json.
+func TestResolveIconFallbackAndProduct(t *testing.T) {
+	iconBody := []byte("acme-ico")
+	srv := mustTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte(`<html><head><title>no icon link</title></head></html>`))
+		case "/favicon.ico":
+			_, _ = w.Write(iconBody)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	oldClient, oldNoRedirect := Client, ClientNoRedirect
+	Client, ClientNoRedirect = srv.Client(), srv.Client()
+	defer func() {
+		Client, ClientNoRedirect = oldClient, oldNoRedirect
+	}()
+
+	if got := IconProduct("-1010568750"); got != "phpMyAdmin" {
+		t.Fatalf("IconProduct(phpMyAdmin hash) = %q", got)
+	}
+	hash := Mmh3Hash32(StandBase64(iconBody))
+	iconFingerMap[hash] = "AcmeProduct"
+	defer delete(iconFingerMap, hash)
+
+	res := ResolveIcon(srv.URL)
+	if res.Err != nil {
+		t.Fatalf("ResolveIcon error: %v", res.Err)
+	}
+	if res.IconURL != srv.URL+"/favicon.ico" || res.Fofa != hash || res.Product != "AcmeProduct" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
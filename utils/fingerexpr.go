@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	regexp2 "github.com/dlclark/regexp2"
+	"github.com/godspeedcurry/godscan/common"
+)
+
+// ExprRule is a FOFA-style fingerprint such as `title="Nacos" && body="console-ui"`.
+// Packs carry them under "rules" next to (or instead of) the ehole "fingerprint" list.
+// `=` means contains, `==` equals and `!=` does not contain.
+type ExprRule struct {
+	Cms  string
+	Rule string
+	// Pack is the rule pack the rule was loaded from (not part of the json schema).
+	Pack string `json:"-"`
+}
+
+// exprRuleKeys are the fields an expression rule may reference.
+var exprRuleKeys = map[string]struct{}{
+	"title":     {},
+	"body":      {},
+	"header":    {},
+	"server":    {},
+	"status":    {},
+	"cert":      {},
+	"icon_hash": {},
+}
+
+var exprCondRe = regexp2.MustCompile(`(\w+)\s*(?:==|!=|=)\s*"(?:[^"]|"(?! && | \|\| |$))*"`, regexp2.None)
+
+// iconHashCache keeps the fofa icon hash per favicon url ("" when it could not be fetched).
+var iconHashCache sync.Map
+
+func validateExprRule(r ExprRule) error {
+	if strings.TrimSpace(r.Cms) == "" {
+		return fmt.Errorf("empty cms")
+	}
+	if strings.TrimSpace(r.Rule) == "" {
+		return fmt.Errorf("empty rule")
+	}
+	m, _ := exprCondRe.FindStringMatch(r.Rule)
+	if m == nil {
+		return fmt.Errorf("no key=\"value\" condition in %q", r.Rule)
+	}
+	for ; m != nil; m, _ = exprCondRe.FindNextMatch(m) {
+		key := m.Groups()[1].String()
+		if _, ok := exprRuleKeys[key]; !ok {
+			return fmt.Errorf("unknown key %q in %q", key, r.Rule)
+		}
+	}
+	if _, err := preprocessAndEvaluate(r.Rule, map[string]string{}); err != nil {
+		return fmt.Errorf("invalid expression %q: %w", r.Rule, err)
+	}
+	return nil
+}
+
+// exprRuleFields builds the evaluation context of a response; icon_hash is filled lazily.
+func exprRuleFields(resp *http.Response, title, body, headersJSON string) map[string]string {
+	return map[string]string{
+		"title":  title,
+		"body":   body,
+		"header": rawHeaders(resp) + headersJSON,
+		"server": resp.Header.Get("Server"),
+		"status": strconv.Itoa(resp.StatusCode),
+		"cert":   certText(resp.TLS),
+	}
+}
+
+func rawHeaders(resp *http.Response) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\r\n", resp.Proto, resp.Status)
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range resp.Header[name] {
+			fmt.Fprintf(&b, "%s: %s\r\n", name, v)
+		}
+	}
+	return b.String()
+}
+
+func certText(state *tls.ConnectionState) string {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return ""
+	}
+	cert := state.PeerCertificates[0]
+	return fmt.Sprintf("Subject: %s\nIssuer: %s\nOrganization: %s\nDNS Names: %s\nSerial Number: %s\n",
+		cert.Subject.String(), cert.Issuer.String(), strings.Join(cert.Subject.Organization, ","),
+		strings.Join(cert.DNSNames, ","), cert.SerialNumber.String())
+}
+
+// pageIconHash returns the fofa icon hash of the page's favicon (rel=icon, else /favicon.ico).
+func pageIconHash(pageURL, body string) string {
+	iconURL, err := FindFaviconURLFromHTML(pageURL, body)
+	if err != nil {
+		u, perr := url.Parse(pageURL)
+		if perr != nil {
+			return ""
+		}
+		iconURL = u.Scheme + "://" + u.Host + "/favicon.ico"
+	}
+	if v, ok := iconHashCache.Load(iconURL); ok {
+		return v.(string)
+	}
+	hash, _, _, err := IconDetect(iconURL)
+	if err != nil {
+		hash = ""
+	}
+	iconHashCache.Store(iconURL, hash)
+	return hash
+}
+
+func matchExprRules(rules []ExprRule, fields map[string]string, iconHash func() string) []ExprRule {
+	var hits []ExprRule
+	for _, r := range rules {
+		if _, ok := fields["icon_hash"]; !ok && strings.Contains(r.Rule, "icon_hash") {
+			fields["icon_hash"] = iconHash()
+		}
+		res, err := preprocessAndEvaluate(r.Rule, fields)
+		if err != nil {
+			Debug("expr rule %s (%s): %v", r.Cms, r.Pack, err)
+			continue
+		}
+		if res {
+			hits = append(hits, r)
+		}
+	}
+	return hits
+}
+
+// applyExprRules evaluates the expression rules against a response and merges hits into res.
+func applyExprRules(res *FingerResult, resp *http.Response, pageURL string, rules []ExprRule) {
+	if len(rules) == 0 {
+		return
+	}
+	body := string(res.Body)
+	fields := exprRuleFields(resp, res.Title, body, res.HeadersJSON)
+	hits := matchExprRules(rules, fields, func() string { return pageIconHash(pageURL, body) })
+	if len(hits) == 0 {
+		return
+	}
+	var cms []string
+	if res.Finger != "" && res.Finger != common.NoFinger {
+		cms = strings.Split(res.Finger, ",")
+	}
+	if res.FingerPacks == nil {
+		res.FingerPacks = make(map[string]string)
+	}
+	for _, r := range hits {
+		cms = append(cms, r.Cms)
+		if _, ok := res.FingerPacks[r.Cms]; !ok {
+			res.FingerPacks[r.Cms] = r.Pack
+		}
+	}
+	res.Finger = strings.Join(RemoveDuplicatesString(cms), ",")
+}
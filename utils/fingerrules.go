@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BuiltinFingerPack names the compiled-in ehole.json rules in FingerResult.FingerPacks.
+const BuiltinFingerPack = "ehole"
+
+var (
+	fingerRulesMu    sync.Mutex
+	fingerRulesPaths []string
+	fingerRules      *Packjson
+)
+
+// InitFingerRules loads the embedded pack plus user packs (files or directories of *.json)
+// and makes the merged set the one used by FingerScan.
+func InitFingerRules(paths []string) error {
+	config, err := LoadFingerRules(paths)
+	if err != nil {
+		return err
+	}
+	fingerRulesMu.Lock()
+	defer fingerRulesMu.Unlock()
+	fingerRulesPaths = append([]string(nil), paths...)
+	fingerRules = &config
+	if len(paths) > 0 {
+		Info("finger rules: %d rule(s), %d expression rule(s) loaded (%s + %d user pack path(s))", len(config.Fingerprint), len(config.Rules), BuiltinFingerPack, len(paths))
+	}
+	return nil
+}
+
+// LoadFingerRules merges the embedded ehole.json with user packs in the same Packjson schema,
+// optionally extended with FOFA-style expression rules under "rules".
+// A user pack replaces every rule (of either kind) with the same Cms name loaded before it.
+func LoadFingerRules(paths []string) (Packjson, error) {
+	var merged Packjson
+	builtin, err := parseFingerPack([]byte(eholeJson), BuiltinFingerPack, false)
+	if err != nil {
+		return merged, err
+	}
+	merged = builtin
+
+	files, err := expandRulePaths("finger rules", paths)
+	if err != nil {
+		return merged, err
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return merged, fmt.Errorf("read finger rules %s: %w", f, err)
+		}
+		pack, err := parseFingerPack(data, filepath.Base(f), true)
+		if err != nil {
+			return merged, fmt.Errorf("%s: %w", f, err)
+		}
+		merged = mergeFingerPack(merged, pack)
+	}
+	return merged, nil
+}
+
+// parseFingerPack tags every rule with its pack. Strict packs fail on the first invalid rule,
+// otherwise invalid rules are dropped so a bad built-in entry cannot disable fingerprinting.
+func parseFingerPack(data []byte, pack string, strict bool) (Packjson, error) {
+	var config Packjson
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("unmarshal finger pack %s: %w", pack, err)
+	}
+	valid := config.Fingerprint[:0]
+	for i, fp := range config.Fingerprint {
+		fp.Pack = pack
+		if err := validateFingerprint(fp); err != nil {
+			if strict {
+				return config, fmt.Errorf("finger pack %s rule #%d (%s): %w", pack, i, fp.Cms, err)
+			}
+			Debug("skip finger pack %s rule #%d (%s): %v", pack, i, fp.Cms, err)
+			continue
+		}
+		valid = append(valid, fp)
+	}
+	config.Fingerprint = valid
+	rules := config.Rules[:0]
+	for i, r := range config.Rules {
+		r.Pack = pack
+		if err := validateExprRule(r); err != nil {
+			if strict {
+				return config, fmt.Errorf("finger pack %s expression rule #%d (%s): %w", pack, i, r.Cms, err)
+			}
+			Debug("skip finger pack %s expression rule #%d (%s): %v", pack, i, r.Cms, err)
+			continue
+		}
+		rules = append(rules, r)
+	}
+	config.Rules = rules
+	return config, nil
+}
+
+func validateFingerprint(fp Fingerprint) error {
+	if strings.TrimSpace(fp.Cms) == "" {
+		return fmt.Errorf("empty cms")
+	}
+	if len(fp.Keyword) == 0 {
+		return fmt.Errorf("empty keyword")
+	}
+	switch fp.Method {
+	case "keyword":
+	case "regular":
+		for _, k := range fp.Keyword {
+			if _, err := regexp.Compile(k); err != nil {
+				return fmt.Errorf("invalid regex %q: %w", k, err)
+			}
+		}
+	default:
+		if strings.TrimSpace(fp.Keyword[0]) == "" {
+			return fmt.Errorf("empty expression")
+		}
+	}
+	return nil
+}
+
+func mergeFingerPack(base, pack Packjson) Packjson {
+	override := make(map[string]struct{}, len(pack.Fingerprint)+len(pack.Rules))
+	for _, fp := range pack.Fingerprint {
+		override[strings.ToLower(fp.Cms)] = struct{}{}
+	}
+	for _, r := range pack.Rules {
+		override[strings.ToLower(r.Cms)] = struct{}{}
+	}
+	var out Packjson
+	for _, fp := range base.Fingerprint {
+		if _, ok := override[strings.ToLower(fp.Cms)]; !ok {
+			out.Fingerprint = append(out.Fingerprint, fp)
+		}
+	}
+	for _, r := range base.Rules {
+		if _, ok := override[strings.ToLower(r.Cms)]; !ok {
+			out.Rules = append(out.Rules, r)
+		}
+	}
+	out.Fingerprint = append(out.Fingerprint, pack.Fingerprint...)
+	out.Rules = append(out.Rules, pack.Rules...)
+	return out
+}
+
+// userFingerPacks renders matches that came from user packs as "Cms@pack", sorted.
+func userFingerPacks(packs map[string]string) string {
+	var out []string
+	for cms, pack := range packs {
+		if pack != "" && pack != BuiltinFingerPack {
+			out = append(out, cms+"@"+pack)
+		}
+	}
+	sort.Strings(out)
+	return strings.Join(out, ",")
+}
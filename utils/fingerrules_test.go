@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestLoadFingerRulesUserPack(t *testing.T) {
+	dir := t.TempDir()
+	pack := `{"fingerprint":[
+		{"cms":"AcmeOA","method":"regular","location":"body","keyword":["acme-oa/v[0-9]+"]},
+		{"cms":"AcmePanel","method":"keyword","location":"title","keyword":["Acme Panel"]}
+	]}`
+	if err := os.WriteFile(filepath.Join(dir, "acme.json"), []byte(pack), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadFingerRules([]string{dir})
+	if err != nil {
+		t.Fatalf("load rules: %v", err)
+	}
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader("<html></html>"))
+	finger, packs := matchFingerprints(doc, `<script src="/static/acme-oa/v3/app.js"></script>`, "Acme Panel", "{}", config)
+	if !strings.Contains(finger, "AcmeOA") || !strings.Contains(finger, "AcmePanel") {
+		t.Fatalf("finger = %q", finger)
+	}
+	if packs["AcmeOA"] != "acme.json" {
+		t.Fatalf("pack for AcmeOA = %q", packs["AcmeOA"])
+	}
+	if got := userFingerPacks(packs); got != "AcmeOA@acme.json,AcmePanel@acme.json" {
+		t.Fatalf("userFingerPacks = %q", got)
+	}
+}
+
+func TestLoadFingerRulesRejectsBadRegex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.json")
+	pack := `{"fingerprint":[{"cms":"Broken","method":"regular","location":"body","keyword":["(unclosed"]}]}`
+	if err := os.WriteFile(path, []byte(pack), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadFingerRules([]string{path})
+	if err == nil || !strings.Contains(err.Error(), "Broken") {
+		t.Fatalf("expected regex validation error naming the rule, got %v", err)
+	}
+}
+
+func TestMergeFingerPackOverridesCms(t *testing.T) {
+	base := []Fingerprint{
+		{Cms: "Nacos", Method: "keyword", Location: "title", Keyword: []string{"Nacos"}, Pack: BuiltinFingerPack},
+		{Cms: "Shiro", Method: "keyword", Location: "header", Keyword: []string{"rememberMe"}, Pack: BuiltinFingerPack},
+	}
+	user := []Fingerprint{{Cms: "nacos", Method: "keyword", Location: "body", Keyword: []string{"/nacos/"}, Pack: "mine.json"}}
+	merged := mergeFingerPack(Packjson{Fingerprint: base}, Packjson{Fingerprint: user}).Fingerprint
+	if len(merged) != 2 {
+		t.Fatalf("merged = %+v", merged)
+	}
+	for _, fp := range merged {
+		if strings.EqualFold(fp.Cms, "nacos") && fp.Pack != "mine.json" {
+			t.Fatalf("built-in Nacos rule not overridden: %+v", fp)
+		}
+	}
+}
+
+func TestExprRulesFingerScan(t *testing.T) {
+	icon := []byte("\x00\x00\x01\x00fake-icon")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) { w.Write(icon) })
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Powered-By", "Acme/1.0")
+		fmt.Fprint(w, `<html><head><title>Nacos</title></head><body><div id="console-ui"></div></body></html>`)
+	})
+	srv := mustTestServer(t, mux)
+	defer srv.Close()
+	oldClient, oldNoRedirect := Client, ClientNoRedirect
+	Client, ClientNoRedirect = srv.Client(), srv.Client()
+	defer func() { Client, ClientNoRedirect = oldClient, oldNoRedirect }()
+
+	dir := t.TempDir()
+	pack := fmt.Sprintf(`{"rules":[
+		{"cms":"NacosConsole","rule":"title==\"Nacos\" && body=\"console-ui\""},
+		{"cms":"AcmeIcon","rule":"icon_hash=\"%s\" && status=\"200\""},
+		{"cms":"AcmeHeader","rule":"header=\"X-Powered-By: Acme\" && (body=\"nope\" || title!=\"Login\")"},
+		{"cms":"NotHere","rule":"title=\"Nacos\" && cert=\"CN=\""}
+	]}`, Mmh3Hash32(StandBase64(icon)))
+	if err := os.WriteFile(filepath.Join(dir, "fofa.json"), []byte(pack), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	oldPaths, oldRules := fingerRulesPaths, fingerRules
+	defer func() { fingerRulesPaths, fingerRules = oldPaths, oldRules }()
+	if err := InitFingerRules([]string{dir}); err != nil {
+		t.Fatalf("init rules: %v", err)
+	}
+
+	res := FingerScan(srv.URL, http.MethodGet, true)
+	for _, want := range []string{"Nacos", "NacosConsole", "AcmeIcon", "AcmeHeader"} {
+		if !strings.Contains(","+res.Finger+",", ","+want+",") {
+			t.Fatalf("finger %q missing %s", res.Finger, want)
+		}
+	}
+	if strings.Contains(res.Finger, "NotHere") {
+		t.Fatalf("cert rule matched a plain http response: %q", res.Finger)
+	}
+	if res.FingerPacks["AcmeIcon"] != "fofa.json" {
+		t.Fatalf("packs = %v", res.FingerPacks)
+	}
+}
+
+func TestExprRulesRejectUnknownKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(path, []byte(`{"rules":[{"cms":"Typo","rule":"titel=\"x\""}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadFingerRules([]string{path})
+	if err == nil || !strings.Contains(err.Error(), "titel") {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+}
@@ -0,0 +1,664 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	gitObjCommit = 1
+	gitObjTree   = 2
+	gitObjBlob   = 3
+	gitObjTag    = 4
+	gitOfsDelta  = 6
+	gitRefDelta  = 7
+	// maxGitObjects bounds the history walk of one repository.
+	maxGitObjects = 20000
+	// maxGitObjectBytes bounds one inflated object, a few KB of zlib can claim gigabytes.
+	maxGitObjectBytes = 64 << 20
+)
+
+var (
+	gitHashRe   = regexp.MustCompile(`\b[0-9a-f]{40}\b`)
+	gitRefRe    = regexp.MustCompile(`refs/[\w./-]+`)
+	gitBranchRe = regexp.MustCompile(`\[branch "([^"]+)"\]`)
+	gitPackRe   = regexp.MustCompile(`pack-[0-9a-f]{40}\.pack`)
+	gitDumped   sync.Map
+)
+
+// gitStaticFiles are fetched for every exposed repository before refs are followed.
+var gitStaticFiles = []string{"HEAD", "ORIG_HEAD", "FETCH_HEAD", "config", "description", "packed-refs", "info/refs",
+	"info/exclude", "logs/HEAD", "objects/info/packs", "refs/stash", "refs/remotes/origin/HEAD", "index"}
+
+// GitDumpResult summarises one reconstructed repository.
+type GitDumpResult struct {
+	RepoURL  string
+	WorkTree string
+	Refs     int
+	Objects  int
+	Files    int
+	Local    LocalScanStats
+}
+
+type gitObject struct {
+	kind int
+	data []byte
+}
+
+type gitPack struct {
+	data    []byte
+	offsets map[string]int64
+}
+
+type gitIndexEntry struct {
+	Path string
+	Hash string
+}
+
+type gitDumper struct {
+	base    string
+	gitDir  string
+	objects map[string]*gitObject
+	packs   []*gitPack
+	missing map[string]bool
+}
+
+// IsGitHead reports whether body looks like the content of a .git/HEAD file.
+func IsGitHead(body []byte) bool {
+	s := strings.TrimSpace(string(body))
+	return strings.HasPrefix(s, "ref: refs/") || (len(s) == 40 && gitHashRe.MatchString(s))
+}
+
+// MaybeDumpGit runs DumpGit once per repository when --git-dump is on.
+func MaybeDumpGit(repoURL string) {
+	if !viper.GetBool("git-dump") {
+		return
+	}
+	if _, loaded := gitDumped.LoadOrStore(repoURL, true); loaded {
+		return
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return
+	}
+	saveDir := hostOutputDir(u, "git")
+	res, err := DumpGit(repoURL, saveDir, GetSpiderDB())
+	if err != nil {
+		Warning("git dump %s: %v", repoURL, err)
+		return
+	}
+	Success("git dump %s: %d ref(s), %d object(s), %d file(s) -> %s", res.RepoURL, res.Refs, res.Objects, res.Files, res.WorkTree)
+}
+
+// DumpGit rebuilds an exposed repository: repoURL points at the ".git/" directory. Raw files
+// land in saveDir/repo/.git, the checked-out tree in saveDir/repo, which is then scanned like
+// `godscan local`.
+func DumpGit(repoURL, saveDir string, db *sql.DB) (res GitDumpResult, err error) {
+	// the parsers work on attacker-controlled bytes, a bug there must not end the dirbrute run
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed repository: %v", r)
+		}
+	}()
+	if !strings.HasSuffix(repoURL, "/") {
+		repoURL += "/"
+	}
+	workTree := filepath.Join(saveDir, "repo")
+	d := &gitDumper{
+		base:    repoURL,
+		gitDir:  filepath.Join(workTree, ".git"),
+		objects: make(map[string]*gitObject),
+		missing: make(map[string]bool),
+	}
+	res = GitDumpResult{RepoURL: repoURL, WorkTree: workTree}
+	head, err := d.fetch("HEAD")
+	if err != nil || !IsGitHead(head) {
+		return res, fmt.Errorf("HEAD not readable")
+	}
+
+	files := make(map[string][]byte)
+	for _, name := range gitStaticFiles {
+		if data, err := d.fetch(name); err == nil {
+			files[name] = data
+		}
+	}
+	// refs named anywhere plus the branches of config, then their reflogs
+	refs := make(map[string]bool)
+	for _, data := range files {
+		for _, r := range gitRefRe.FindAllString(string(data), -1) {
+			refs[strings.TrimRight(r, ".")] = true
+		}
+	}
+	for _, m := range gitBranchRe.FindAllStringSubmatch(string(files["config"]), -1) {
+		refs["refs/heads/"+m[1]] = true
+	}
+	for _, b := range []string{"master", "main"} {
+		refs["refs/heads/"+b] = true
+	}
+	hashes := make(map[string]bool)
+	resolved := make(map[string]bool)
+	for ref := range refs {
+		for _, name := range []string{ref, "logs/" + ref} {
+			if data, err := d.fetch(name); err == nil {
+				files[name] = data
+				if name == ref {
+					resolved[ref] = true
+				}
+			}
+		}
+	}
+	for _, line := range strings.Split(string(files["packed-refs"]), "\n") {
+		if f := strings.Fields(line); len(f) == 2 && strings.HasPrefix(f[1], "refs/") {
+			resolved[f[1]] = true
+		}
+	}
+	res.Refs = len(resolved)
+	for name, data := range files {
+		if name == "index" {
+			continue
+		}
+		for _, h := range gitHashRe.FindAllString(string(data), -1) {
+			hashes[h] = true
+		}
+	}
+
+	for _, m := range gitPackRe.FindAllString(string(files["objects/info/packs"]), -1) {
+		if err := d.loadPack(strings.TrimSuffix(m, ".pack")); err != nil {
+			Debug("git pack %s: %v", m, err)
+		}
+	}
+
+	var index []gitIndexEntry
+	if data, ok := files["index"]; ok {
+		if index, err = parseGitIndex(data); err != nil {
+			Warning("git index %sindex: %v", repoURL, err)
+		}
+	}
+
+	// walk the history reachable from every ref, then the index blobs
+	queue := make([]string, 0, len(hashes))
+	for h := range hashes {
+		queue = append(queue, h)
+	}
+	seen := make(map[string]bool)
+	for len(queue) > 0 && len(seen) < maxGitObjects {
+		h := queue[0]
+		queue = queue[1:]
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+		obj := d.object(h)
+		if obj == nil {
+			continue
+		}
+		switch obj.kind {
+		case gitObjCommit, gitObjTag:
+			for _, line := range strings.Split(string(obj.data), "\n") {
+				if line == "" {
+					break
+				}
+				f := strings.Fields(line)
+				if len(f) == 2 && (f[0] == "tree" || f[0] == "parent" || f[0] == "object") {
+					queue = append(queue, f[1])
+				}
+			}
+		case gitObjTree:
+			for _, e := range parseGitTree(obj.data) {
+				if e.mode != "160000" {
+					queue = append(queue, e.hash)
+				}
+			}
+		}
+	}
+	for _, e := range index {
+		d.object(e.Hash)
+	}
+	res.Objects = len(d.objects)
+
+	tree := make(map[string]string)
+	if c := d.object(d.resolveHead(files)); c != nil && c.kind == gitObjCommit {
+		if f := strings.Fields(strings.SplitN(string(c.data), "\n", 2)[0]); len(f) == 2 && f[0] == "tree" {
+			d.collectTree(f[1], "", tree, 0)
+		}
+	}
+	for _, e := range index {
+		tree[e.Path] = e.Hash
+	}
+	for p, h := range tree {
+		obj := d.object(h)
+		rel := sanitizeSourcePath(p)
+		if obj == nil || obj.kind != gitObjBlob || rel == "" || strings.HasPrefix(rel, ".git/") {
+			continue
+		}
+		target := filepath.Join(workTree, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			continue
+		}
+		if err := os.WriteFile(target, obj.data, 0o644); err == nil {
+			res.Files++
+		}
+	}
+	if res.Files == 0 {
+		return res, fmt.Errorf("no file recovered (%d object(s) missing)", len(d.missing))
+	}
+	res.Local, err = ScanLocal(workTree, saveDir, DefaultLocalMaxBytes, db)
+	return res, err
+}
+
+func (d *gitDumper) fetch(name string) ([]byte, error) {
+	if strings.Contains(name, "..") {
+		return nil, fmt.Errorf("refusing path %s", name)
+	}
+	req, err := http.NewRequest(http.MethodGet, d.base+name, nil)
+	if err != nil {
+		return nil, err
+	}
+	SetHeaders(req)
+	resp, err := Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 256*1024*1024))
+	if err != nil {
+		return nil, err
+	}
+	// soft 404 pages answer 200 with html
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) && name != "description" {
+		return nil, fmt.Errorf("html response")
+	}
+	target := filepath.Join(d.gitDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err == nil {
+		_ = os.WriteFile(target, data, 0o644)
+	}
+	return data, nil
+}
+
+func (d *gitDumper) resolveHead(files map[string][]byte) string {
+	head := strings.TrimSpace(string(files["HEAD"]))
+	if !strings.HasPrefix(head, "ref: ") {
+		return head
+	}
+	ref := strings.TrimSpace(strings.TrimPrefix(head, "ref: "))
+	if h := strings.TrimSpace(string(files[ref])); len(h) == 40 {
+		return h
+	}
+	for _, line := range strings.Split(string(files["packed-refs"]), "\n") {
+		if f := strings.Fields(line); len(f) == 2 && f[1] == ref {
+			return f[0]
+		}
+	}
+	return ""
+}
+
+func (d *gitDumper) collectTree(hash, prefix string, out map[string]string, depth int) {
+	obj := d.object(hash)
+	if obj == nil || obj.kind != gitObjTree || depth > 64 {
+		return
+	}
+	for _, e := range parseGitTree(obj.data) {
+		switch {
+		case e.mode == "40000":
+			d.collectTree(e.hash, prefix+e.name+"/", out, depth+1)
+		case e.mode != "160000":
+			out[prefix+e.name] = e.hash
+		}
+	}
+}
+
+// object returns a decoded object from the packs or, failing that, the loose object store.
+func (d *gitDumper) object(hash string) *gitObject {
+	if len(hash) != 40 {
+		return nil
+	}
+	if obj, ok := d.objects[hash]; ok {
+		return obj
+	}
+	if d.missing[hash] {
+		return nil
+	}
+	for _, p := range d.packs {
+		if off, ok := p.offsets[hash]; ok {
+			if obj, err := d.packObject(p, off, 0); err == nil {
+				d.objects[hash] = obj
+				return obj
+			}
+		}
+	}
+	raw, err := d.fetch("objects/" + hash[:2] + "/" + hash[2:])
+	if err == nil {
+		if obj, err := parseLooseObject(raw); err == nil {
+			d.objects[hash] = obj
+			return obj
+		}
+	}
+	d.missing[hash] = true
+	return nil
+}
+
+func parseLooseObject(raw []byte) (*gitObject, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(io.LimitReader(zr, maxGitObjectBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxGitObjectBytes {
+		return nil, fmt.Errorf("object larger than %d bytes", maxGitObjectBytes)
+	}
+	nul := bytes.IndexByte(data, 0)
+	if nul < 0 {
+		return nil, fmt.Errorf("bad object header")
+	}
+	fields := strings.Fields(string(data[:nul]))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("bad object header")
+	}
+	kind := map[string]int{"commit": gitObjCommit, "tree": gitObjTree, "blob": gitObjBlob, "tag": gitObjTag}[fields[0]]
+	if kind == 0 {
+		return nil, fmt.Errorf("unknown object type")
+	}
+	return &gitObject{kind: kind, data: data[nul+1:]}, nil
+}
+
+type gitTreeEntry struct {
+	mode, name, hash string
+}
+
+func parseGitTree(data []byte) []gitTreeEntry {
+	var out []gitTreeEntry
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if sp < 0 || nul < sp || len(data) < nul+21 {
+			break
+		}
+		out = append(out, gitTreeEntry{mode: string(data[:sp]), name: string(data[sp+1 : nul]), hash: hex.EncodeToString(data[nul+1 : nul+21])})
+		data = data[nul+21:]
+	}
+	return out
+}
+
+// parseGitIndex reads the path and blob hash of every entry of a version 2, 3 or 4 index.
+func parseGitIndex(data []byte) ([]gitIndexEntry, error) {
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, fmt.Errorf("not a git index")
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("unsupported index version %d", version)
+	}
+	count := int(binary.BigEndian.Uint32(data[8:12]))
+	// every entry takes at least 62 bytes, a larger count is a corrupt or hostile header
+	if count > (len(data)-12)/62 {
+		return nil, fmt.Errorf("index claims %d entries in %d bytes", count, len(data))
+	}
+	pos := 12
+	var prev string
+	out := make([]gitIndexEntry, 0, count)
+	for i := 0; i < count; i++ {
+		if pos+62 > len(data) {
+			return out, fmt.Errorf("index truncated at entry %d", i)
+		}
+		start := pos
+		hash := hex.EncodeToString(data[pos+40 : pos+60])
+		flags := binary.BigEndian.Uint16(data[pos+60 : pos+62])
+		pos += 62
+		if version >= 3 && flags&0x4000 != 0 {
+			pos += 2
+		}
+		var name string
+		if version == 4 {
+			strip, n := gitOffsetVarint(data[pos:])
+			if n == 0 || int(strip) > len(prev) {
+				return out, fmt.Errorf("bad v4 path at entry %d", i)
+			}
+			pos += n
+			nul := bytes.IndexByte(data[pos:], 0)
+			if nul < 0 {
+				return out, fmt.Errorf("index truncated at entry %d", i)
+			}
+			name = prev[:len(prev)-int(strip)] + string(data[pos:pos+nul])
+			pos += nul + 1
+		} else {
+			nul := bytes.IndexByte(data[pos:], 0)
+			if nul < 0 {
+				return out, fmt.Errorf("index truncated at entry %d", i)
+			}
+			name = string(data[pos : pos+nul])
+			pos += nul
+			// entries are NUL padded to a multiple of eight bytes
+			pos = start + ((pos-start)/8+1)*8
+		}
+		prev = name
+		out = append(out, gitIndexEntry{Path: name, Hash: hash})
+	}
+	return out, nil
+}
+
+// gitOffsetVarint decodes the big-endian "offset" varint used by OFS_DELTA and index v4.
+func gitOffsetVarint(b []byte) (uint64, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	c := b[0]
+	v := uint64(c & 0x7f)
+	i := 1
+	for c&0x80 != 0 {
+		if i >= len(b) {
+			return 0, 0
+		}
+		c = b[i]
+		i++
+		v = ((v + 1) << 7) | uint64(c&0x7f)
+	}
+	return v, i
+}
+
+func (d *gitDumper) loadPack(name string) error {
+	idx, err := d.fetch("objects/pack/" + name + ".idx")
+	if err != nil {
+		return err
+	}
+	offsets, err := parsePackIndex(idx)
+	if err != nil {
+		return err
+	}
+	data, err := d.fetch("objects/pack/" + name + ".pack")
+	if err != nil {
+		return err
+	}
+	if len(data) < 12 || string(data[:4]) != "PACK" {
+		return fmt.Errorf("not a pack file")
+	}
+	d.packs = append(d.packs, &gitPack{data: data, offsets: offsets})
+	return nil
+}
+
+// parsePackIndex maps object hashes to pack offsets from a version 2 .idx file.
+func parsePackIndex(idx []byte) (map[string]int64, error) {
+	if len(idx) < 8+256*4 || !bytes.Equal(idx[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(idx[4:8]) != 2 {
+		return nil, fmt.Errorf("unsupported pack index")
+	}
+	n := int(binary.BigEndian.Uint32(idx[8+255*4 : 8+256*4]))
+	shaPos := 8 + 256*4
+	offPos := shaPos + n*20 + n*4
+	largePos := offPos + n*4
+	if len(idx) < largePos {
+		return nil, fmt.Errorf("pack index truncated")
+	}
+	out := make(map[string]int64, n)
+	for i := 0; i < n; i++ {
+		off := int64(binary.BigEndian.Uint32(idx[offPos+i*4:]))
+		if off&0x80000000 != 0 {
+			p := largePos + int(off&0x7fffffff)*8
+			if p+8 > len(idx) {
+				continue
+			}
+			off = int64(binary.BigEndian.Uint64(idx[p:]))
+		}
+		out[hex.EncodeToString(idx[shaPos+i*20:shaPos+i*20+20])] = off
+	}
+	return out, nil
+}
+
+func (d *gitDumper) packObject(p *gitPack, off int64, depth int) (*gitObject, error) {
+	if depth > 50 || off < 12 || off >= int64(len(p.data)) {
+		return nil, fmt.Errorf("bad pack offset")
+	}
+	data := p.data
+	pos := off
+	c := data[pos]
+	pos++
+	kind := int(c>>4) & 7
+	size, shift := uint64(c&0x0f), uint(4)
+	for c&0x80 != 0 && pos < int64(len(data)) {
+		c = data[pos]
+		pos++
+		if shift < 64 {
+			size |= uint64(c&0x7f) << shift
+		}
+		shift += 7
+	}
+	switch kind {
+	case gitObjCommit, gitObjTree, gitObjBlob, gitObjTag:
+		body, err := inflateAt(data, pos, size)
+		if err != nil {
+			return nil, err
+		}
+		return &gitObject{kind: kind, data: body}, nil
+	case gitOfsDelta:
+		rel, n := gitOffsetVarint(data[pos:])
+		if n == 0 {
+			return nil, fmt.Errorf("bad delta offset")
+		}
+		base, err := d.packObject(p, off-int64(rel), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return applyPackDelta(base, data, pos+int64(n), size)
+	case gitRefDelta:
+		if pos+20 > int64(len(data)) {
+			return nil, fmt.Errorf("truncated ref delta")
+		}
+		base := d.object(hex.EncodeToString(data[pos : pos+20]))
+		if base == nil {
+			return nil, fmt.Errorf("missing delta base")
+		}
+		return applyPackDelta(base, data, pos+20, size)
+	}
+	return nil, fmt.Errorf("unknown pack object type %d", kind)
+}
+
+// inflateAt inflates the pack entry at pos, rejecting one that outgrows its declared size.
+func inflateAt(data []byte, pos int64, size uint64) ([]byte, error) {
+	if size > maxGitObjectBytes {
+		return nil, fmt.Errorf("pack entry of %d bytes", size)
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data[pos:]))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, int64(size)+1))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(out)) > size {
+		return nil, fmt.Errorf("pack entry inflates past its %d bytes", size)
+	}
+	return out, nil
+}
+
+// gitDeltaMaxExpansion bounds a delta result by the size of the pack it came from.
+const gitDeltaMaxExpansion = 1024
+
+func applyPackDelta(base *gitObject, data []byte, pos int64, deltaSize uint64) (*gitObject, error) {
+	delta, err := inflateAt(data, pos, deltaSize)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(bytes.NewReader(delta))
+	if _, err := binary.ReadUvarint(r); err != nil {
+		return nil, err
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxGitObjectBytes || size > uint64(len(data))*gitDeltaMaxExpansion {
+		return nil, fmt.Errorf("delta result of %d bytes from a %d byte pack", size, len(data))
+	}
+	out := make([]byte, 0, min(size, uint64(len(base.data)+len(delta))))
+	for {
+		op, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if op&0x80 == 0 {
+			if op == 0 {
+				return nil, fmt.Errorf("bad delta opcode")
+			}
+			buf := make([]byte, op)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return nil, err
+			}
+			out = append(out, buf...)
+			continue
+		}
+		var cpOff, cpLen uint32
+		for i := uint(0); i < 7; i++ {
+			if op&(1<<i) == 0 {
+				continue
+			}
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if i < 4 {
+				cpOff |= uint32(b) << (8 * i)
+			} else {
+				cpLen |= uint32(b) << (8 * (i - 4))
+			}
+		}
+		if cpLen == 0 {
+			cpLen = 0x10000
+		}
+		if uint64(cpOff)+uint64(cpLen) > uint64(len(base.data)) {
+			return nil, fmt.Errorf("delta copy out of range")
+		}
+		out = append(out, base.data[cpOff:cpOff+cpLen]...)
+		if uint64(len(out)) > size {
+			return nil, fmt.Errorf("delta size mismatch")
+		}
+	}
+	if uint64(len(out)) != size {
+		return nil, fmt.Errorf("delta size mismatch")
+	}
+	return &gitObject{kind: base.kind, data: out}, nil
+}
@@ -0,0 +1,359 @@
+package utils
+
+import (
+	"bufio"
+	"compress/gzip"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var hprofMagic = []byte("JAVA PROFILE ")
+
+// HPROF top level record tags and heap dump sub-record tags that matter for string extraction.
+const (
+	hprofHeapDump        = 0x0c
+	hprofHeapDumpSegment = 0x1c
+	hprofClassDump       = 0x20
+	hprofInstanceDump    = 0x21
+	hprofObjArrayDump    = 0x22
+	hprofPrimArrayDump   = 0x23
+	hprofTypeObject      = 2
+	hprofTypeChar        = 5
+	hprofTypeByte        = 8
+)
+
+// heap strings shorter than this are noise, longer ones are mostly class data or page content.
+const (
+	hprofMinString   = 4
+	hprofMaxString   = 64 * 1024
+	hprofChunkBytes  = 4 * 1024 * 1024
+	hprofMaxValueLen = 256
+)
+
+var hprofPropertyKeyRe = regexp.MustCompile(`^[a-zA-Z][\w\-]*(\.[\w\-\[\]]+)+$`)
+
+// IsHeapDumpName reports whether a path looks like a Java heap dump.
+func IsHeapDumpName(name string) bool {
+	ln := strings.ToLower(name)
+	return strings.HasSuffix(ln, "heapdump") || strings.HasSuffix(ln, ".hprof") || strings.HasSuffix(ln, ".hprof.gz")
+}
+
+// HeapDumpStats summarises one heap dump scan.
+type HeapDumpStats struct {
+	Strings    int
+	Properties int
+}
+
+// ScanHeapDump streams an HPROF file (optionally gzip compressed), feeds its string instances
+// to the secret rules and stores "secret-looking property key followed by its value" pairs
+// into sensitive_hits, all with sourceURL as the source.
+func ScanHeapDump(db *sql.DB, sourceURL, saveDir string, r io.Reader) (HeapDumpStats, error) {
+	var st HeapDumpStats
+	br := bufio.NewReaderSize(r, 64*1024)
+	if head, _ := br.Peek(2); len(head) == 2 && head[0] == 0x1f && head[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return st, err
+		}
+		defer gz.Close()
+		br = bufio.NewReaderSize(gz, 64*1024)
+	}
+	// dedup on a 64-bit hash, a dump holds millions of strings up to 64 KB each
+	seen := make(map[uint64]struct{})
+	var chunk strings.Builder
+	var props []SensitiveHit
+	pendingKey := ""
+	flush := func() {
+		if chunk.Len() > 0 {
+			SensitiveInfoCollect(db, sourceURL, chunk.String(), saveDir)
+			chunk.Reset()
+		}
+	}
+	err := readHPROFStrings(br, func(s string) {
+		if pendingKey != "" && isHeapPropertyValue(s) {
+			props = append(props, SensitiveHit{
+				SourceURL:  sourceURL,
+				Category:   "Heapdump property",
+				RuleID:     "heapdump-property",
+				Confidence: ConfidenceMedium,
+				Content:    pendingKey + " = " + s,
+				Entropy:    calculateEntropy(s),
+				Status:     SecretUnverified,
+				SaveDir:    saveDir,
+			})
+		}
+		pendingKey = ""
+		if hprofPropertyKeyRe.MatchString(s) && actuatorSecretKeyRe.MatchString(s) {
+			pendingKey = s
+		}
+		h := fnv.New64a()
+		io.WriteString(h, s)
+		key := h.Sum64()
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		st.Strings++
+		chunk.WriteString(s)
+		chunk.WriteByte('\n')
+		if chunk.Len() >= hprofChunkBytes {
+			flush()
+		}
+	})
+	flush()
+	props = dedupHeapProperties(props)
+	st.Properties = len(props)
+	for _, p := range props {
+		FileWrite(saveDir+"heapdump.txt", "%s\t%s\n", p.SourceURL, p.Content)
+	}
+	if serr := SaveSensitiveHits(db, props); serr != nil {
+		Error("heapdump: save properties failed: %v", serr)
+	}
+	return st, err
+}
+
+func isHeapPropertyValue(s string) bool {
+	if s == "" || len(s) > hprofMaxValueLen || hprofPropertyKeyRe.MatchString(s) || actuatorMaskedValueRe.MatchString(s) {
+		return false
+	}
+	return !strings.ContainsAny(s, " \t\r\n")
+}
+
+func dedupHeapProperties(in []SensitiveHit) []SensitiveHit {
+	seen := make(map[string]bool)
+	out := in[:0]
+	for _, h := range in {
+		if !seen[h.Content] {
+			seen[h.Content] = true
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// readHPROFStrings walks the records of an HPROF stream and calls fn with the text held by every
+// char[] (Java 8 strings) and byte[] (Java 9+ compact strings) primitive array, in dump order.
+func readHPROFStrings(r *bufio.Reader, fn func(string)) error {
+	head, err := r.ReadString(0)
+	if err != nil || !strings.HasPrefix(head, string(hprofMagic)) {
+		return fmt.Errorf("not an hprof file")
+	}
+	h := &hprofReader{r: r}
+	idSize := h.u4()
+	h.skip(8) // timestamp
+	if h.err != nil {
+		return h.err
+	}
+	if idSize != 4 && idSize != 8 {
+		return fmt.Errorf("unsupported hprof id size %d", idSize)
+	}
+	h.idSize = int(idSize)
+	for {
+		tag, err := r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		h.skip(4) // time offset
+		length := int64(h.u4())
+		if h.err != nil {
+			return h.err
+		}
+		if tag == hprofHeapDump || tag == hprofHeapDumpSegment {
+			if err := h.heapDump(length, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		h.skip(length)
+		if h.err != nil {
+			return h.err
+		}
+	}
+}
+
+type hprofReader struct {
+	r      *bufio.Reader
+	idSize int
+	err    error
+	buf    [8]byte
+}
+
+func (h *hprofReader) read(n int) []byte {
+	if h.err != nil {
+		return h.buf[:n]
+	}
+	_, h.err = io.ReadFull(h.r, h.buf[:n])
+	return h.buf[:n]
+}
+
+func (h *hprofReader) u1() byte   { return h.read(1)[0] }
+func (h *hprofReader) u2() uint16 { return binary.BigEndian.Uint16(h.read(2)) }
+func (h *hprofReader) u4() uint32 { return binary.BigEndian.Uint32(h.read(4)) }
+
+func (h *hprofReader) skip(n int64) {
+	if h.err != nil || n <= 0 {
+		return
+	}
+	_, h.err = io.CopyN(io.Discard, h.r, n)
+}
+
+func (h *hprofReader) typeSize(t byte) int64 {
+	switch t {
+	case hprofTypeObject:
+		return int64(h.idSize)
+	case 4, hprofTypeByte: // boolean, byte
+		return 1
+	case hprofTypeChar, 9: // char, short
+		return 2
+	case 6, 10: // float, int
+		return 4
+	case 7, 11: // double, long
+		return 8
+	}
+	return -1
+}
+
+// heapDump reads the sub-records of one HEAP DUMP (SEGMENT) record of the given length.
+func (h *hprofReader) heapDump(length int64, fn func(string)) error {
+	id := int64(h.idSize)
+	// remaining is tracked by hand: sub-records carry no length of their own
+	remaining := length
+	consume := func(n int64) {
+		remaining -= n
+		h.skip(n)
+	}
+	for remaining > 0 && h.err == nil {
+		tag := h.u1()
+		remaining--
+		switch tag {
+		case 0xff, 0x05, 0x07: // root unknown, sticky class, monitor used
+			consume(id)
+		case 0x01: // root jni global
+			consume(2 * id)
+		case 0x02, 0x03, 0x08: // root jni local, java frame, thread object
+			consume(id + 8)
+		case 0x04, 0x06: // root native stack, thread block
+			consume(id + 4)
+		case hprofClassDump:
+			consume(id + 4 + 6*id + 4)
+			remaining -= 2
+			for n := h.u2(); n > 0 && h.err == nil; n-- {
+				remaining -= 2
+				h.skip(2)
+				t := h.u1()
+				remaining--
+				if h.typeSize(t) < 0 {
+					return fmt.Errorf("hprof: bad constant pool type %d", t)
+				}
+				consume(h.typeSize(t))
+			}
+			remaining -= 2
+			for n := h.u2(); n > 0 && h.err == nil; n-- {
+				consume(id)
+				t := h.u1()
+				remaining--
+				if h.typeSize(t) < 0 {
+					return fmt.Errorf("hprof: bad static field type %d", t)
+				}
+				consume(h.typeSize(t))
+			}
+			remaining -= 2
+			n := int64(h.u2())
+			consume(n * (id + 1))
+		case hprofInstanceDump:
+			consume(id + 4 + id)
+			n := int64(h.u4())
+			remaining -= 4
+			consume(n)
+		case hprofObjArrayDump:
+			consume(id + 4)
+			n := int64(h.u4())
+			remaining -= 4
+			consume(id + n*id)
+		case hprofPrimArrayDump:
+			consume(id + 4)
+			n := int64(h.u4())
+			t := h.u1()
+			remaining -= 5
+			size := h.typeSize(t)
+			if size < 0 {
+				return fmt.Errorf("hprof: bad array type %d", t)
+			}
+			total := n * size
+			remaining -= total
+			if (t != hprofTypeChar && t != hprofTypeByte) || n < hprofMinString || n*size > hprofMaxString*2 {
+				h.skip(total)
+				continue
+			}
+			data := make([]byte, total)
+			if h.err == nil {
+				_, h.err = io.ReadFull(h.r, data)
+			}
+			if s, ok := heapArrayString(t, data); ok {
+				fn(s)
+			}
+		default:
+			return fmt.Errorf("hprof: unknown heap sub-record 0x%02x", tag)
+		}
+	}
+	if h.err == nil && remaining < 0 {
+		return fmt.Errorf("hprof: heap dump record overrun")
+	}
+	return h.err
+}
+
+// heapArrayString decodes a char[] (UTF-16BE) or a byte[] (Latin-1 or UTF-8, or UTF-16 for non
+// Latin-1 compact strings) into text, rejecting arrays that hold binary data.
+func heapArrayString(t byte, data []byte) (string, bool) {
+	var s string
+	switch {
+	case t == hprofTypeChar:
+		s = decodeUTF16(data, binary.BigEndian)
+	case len(data)%2 == 0 && data[1] == 0 && data[0] != 0:
+		// StringUTF16 stores chars in native, usually little endian, order
+		s = decodeUTF16(data, binary.LittleEndian)
+	case utf8.Valid(data):
+		s = string(data)
+	default:
+		r := make([]rune, len(data))
+		for i, b := range data {
+			r[i] = rune(b)
+		}
+		s = string(r)
+	}
+	if utf8.RuneCountInString(s) < hprofMinString || !isHeapText(s) {
+		return "", false
+	}
+	return s, true
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	u := make([]uint16, len(data)/2)
+	for i := range u {
+		u[i] = order.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// isHeapText accepts printable text with tabs and newlines, as found in config values.
+func isHeapText(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r == utf8.RuneError || (r < 0x20 && r != '\t' && r != '\n' && r != '\r') || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
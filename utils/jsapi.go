@@ -0,0 +1,716 @@
+package utils
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// JSAPICall is an API path recovered from JavaScript together with the HTTP method and
+// parameter names seen at its call site. Method is empty for paths that were only built
+// by concatenation outside a recognized call.
+type JSAPICall struct {
+	Path   string
+	Method string
+	Params []string
+	Kind   string
+}
+
+// jsValue is the result of evaluating a string expression. Unknown operands are kept as
+// "{name}" placeholders; lit is set when any string literal took part, composite when the
+// value was built with "+" or a template substitution.
+type jsValue struct {
+	s         string
+	lit       bool
+	composite bool
+}
+
+type jsRange struct{ start, end int }
+
+type jsProp struct {
+	key   string
+	value int
+}
+
+var jsHTTPMethods = map[string]string{
+	"get": "GET", "post": "POST", "put": "PUT", "delete": "DELETE", "patch": "PATCH",
+	"head": "HEAD", "options": "OPTIONS", "getJSON": "GET", "request": "", "ajax": "",
+}
+
+var jsReserved = map[string]bool{
+	"return": true, "typeof": true, "new": true, "function": true, "true": true, "false": true,
+	"null": true, "undefined": true, "void": true, "delete": true, "var": true, "let": true,
+	"const": true, "if": true, "else": true, "case": true, "in": true, "of": true, "instanceof": true,
+}
+
+var jsPlaceholderRe = regexp.MustCompile(`\{[^{}]*\}`)
+
+var jsStaticExtRe = regexp.MustCompile(`(?i)\.(js|mjs|css|less|scss|png|jpe?g|gif|svg|ico|bmp|webp|woff2?|ttf|eot|otf|map|vue|mp3|mp4|webm)$`)
+
+type jsAnalyzer struct {
+	src       string
+	toks      []jsToken
+	assigns   map[string][]int
+	props     map[string][]int
+	cache     map[string]*jsValue
+	resolving map[string]bool
+	instances map[string]string
+	calls     map[string]*JSAPICall
+	// evalDepth and evalOps bound one top-level evalExpr against crafted bundles
+	evalDepth int
+	evalOps   int
+}
+
+// Limits of one evaluated expression: nesting, "+" operands and the built string.
+const (
+	jsMaxExprDepth    = 32
+	jsMaxExprOperands = 256
+	jsMaxExprLen      = 2048
+)
+
+// ExtractJSAPICalls tokenizes a script and returns API paths from fetch/axios/$.ajax/XHR call
+// sites, request-config objects ({url, method}) and string concatenations, with simple constant
+// propagation of variables and object properties.
+func ExtractJSAPICalls(src string) []JSAPICall {
+	return newJSAnalyzer(src).apiCalls()
+}
+
+// newJSAnalyzer tokenizes src once; the same analyzer serves API calls, routes and chunks.
+func newJSAnalyzer(src string) *jsAnalyzer {
+	a := &jsAnalyzer{
+		src:       src,
+		toks:      tokenizeJS(src),
+		assigns:   make(map[string][]int),
+		props:     make(map[string][]int),
+		cache:     make(map[string]*jsValue),
+		resolving: make(map[string]bool),
+		instances: make(map[string]string),
+		calls:     make(map[string]*JSAPICall),
+	}
+	a.collect()
+	return a
+}
+
+func (a *jsAnalyzer) apiCalls() []JSAPICall {
+	a.findInstances()
+	a.findCalls()
+	a.findConcats()
+	return a.results()
+}
+
+func (a *jsAnalyzer) isPunct(toks []jsToken, i int, text string) bool {
+	return i >= 0 && i < len(toks) && toks[i].kind == jsTokPunct && toks[i].text == text
+}
+
+func (a *jsAnalyzer) isIdent(toks []jsToken, i int) bool {
+	return i >= 0 && i < len(toks) && toks[i].kind == jsTokIdent
+}
+
+// dottedBefore returns the member chain ending at index end (inclusive), e.g. "a.b.c".
+func (a *jsAnalyzer) dottedBefore(end int) (string, int) {
+	if !a.isIdent(a.toks, end) {
+		return "", end
+	}
+	names := []string{a.toks[end].text}
+	j := end
+	for a.isPunct(a.toks, j-1, ".") && a.isIdent(a.toks, j-2) {
+		names = append([]string{a.toks[j-2].text}, names...)
+		j -= 2
+	}
+	return strings.Join(names, "."), j
+}
+
+// collect records every `name = expr` and `{ key: expr }` so identifiers can be resolved later.
+func (a *jsAnalyzer) collect() {
+	for i, t := range a.toks {
+		if t.kind != jsTokPunct {
+			continue
+		}
+		switch t.text {
+		case "=":
+			if name, _ := a.dottedBefore(i - 1); name != "" {
+				a.assigns[name] = append(a.assigns[name], i+1)
+			}
+		case ":":
+			if i >= 2 && (a.isPunct(a.toks, i-2, "{") || a.isPunct(a.toks, i-2, ",")) &&
+				(a.toks[i-1].kind == jsTokIdent || a.toks[i-1].kind == jsTokString) {
+				a.props[a.toks[i-1].text] = append(a.props[a.toks[i-1].text], i+1)
+			}
+		}
+	}
+}
+
+// findInstances maps axios instances to their baseURL: `x = axios.create({baseURL})` and
+// `x.defaults.baseURL = "..."`.
+func (a *jsAnalyzer) findInstances() {
+	for i, t := range a.toks {
+		if t.kind == jsTokIdent && t.text == "create" && a.isPunct(a.toks, i-1, ".") && a.isPunct(a.toks, i+1, "(") && a.isPunct(a.toks, i+2, "{") {
+			props, _ := a.parseObject(a.toks, i+2)
+			base, ok := a.propValue(props, "baseURL")
+			if !ok {
+				continue
+			}
+			_, start := a.dottedBefore(i - 2)
+			if a.isPunct(a.toks, start-1, "=") && a.isIdent(a.toks, start-2) {
+				a.instances[a.toks[start-2].text] = base
+			}
+		}
+	}
+	for name, idxs := range a.assigns {
+		if !strings.HasSuffix(name, ".defaults.baseURL") || len(idxs) != 1 {
+			continue
+		}
+		if v, _ := a.evalExpr(a.toks, idxs[0]); v.lit {
+			a.instances[strings.TrimSuffix(name, ".defaults.baseURL")] = v.s
+		}
+	}
+}
+
+func (a *jsAnalyzer) propValue(props []jsProp, key string) (string, bool) {
+	for _, p := range props {
+		if p.key == key {
+			v, _ := a.evalExpr(a.toks, p.value)
+			return v.s, v.lit
+		}
+	}
+	return "", false
+}
+
+func (a *jsAnalyzer) findCalls() {
+	toks := a.toks
+	for i, t := range toks {
+		switch {
+		case t.kind == jsTokPunct && t.text == "." && a.isIdent(toks, i+1) && toks[i+1].text == "open" && a.isPunct(toks, i+2, "(") &&
+			i+4 < len(toks) && toks[i+3].kind == jsTokString && a.isPunct(toks, i+4, ","):
+			// xhr.open("POST", url)
+			method := strings.ToUpper(toks[i+3].text)
+			if _, ok := jsHTTPMethods[strings.ToLower(method)]; ok {
+				v, _ := a.evalExpr(toks, i+5)
+				a.addCall(v, method, nil, "", "xhr")
+			}
+		case t.kind == jsTokPunct && t.text == "." && a.isIdent(toks, i+1) && a.isPunct(toks, i+2, "("):
+			method, ok := jsHTTPMethods[toks[i+1].text]
+			if !ok {
+				continue
+			}
+			receiver := ""
+			if a.isIdent(toks, i-1) {
+				receiver = toks[i-1].text
+			}
+			a.memberCall(i+2, receiver, method)
+		case t.kind == jsTokIdent && t.text == "fetch" && a.isPunct(toks, i+1, "("):
+			a.fetchCall(i + 1)
+		case t.kind == jsTokPunct && t.text == "(" && a.isPunct(toks, i+1, "{"):
+			receiver := ""
+			if a.isIdent(toks, i-1) {
+				receiver = toks[i-1].text
+			}
+			a.configCall(i+1, receiver, "GET", "config")
+		}
+	}
+}
+
+// memberCall handles axios.get(url, {params}), $.post(url, data), request.post(url, data) and
+// $.ajax({url, type, data}).
+func (a *jsAnalyzer) memberCall(open int, receiver, method string) {
+	args, _ := a.splitArgs(a.toks, open)
+	if len(args) == 0 {
+		return
+	}
+	if method == "" || a.isPunct(a.toks, args[0].start, "{") {
+		if method == "" {
+			method = "GET"
+		}
+		a.configCall(args[0].start, receiver, method, "ajax")
+		return
+	}
+	v, _ := a.evalExpr(a.toks, args[0].start)
+	var params []string
+	jquery := receiver == "$" || receiver == "jQuery"
+	for n, arg := range args[1:] {
+		if !a.isPunct(a.toks, arg.start, "{") {
+			continue
+		}
+		props, _ := a.parseObject(a.toks, arg.start)
+		if keys := a.objectKeysOf(props, "params"); keys != nil {
+			params = append(params, keys...)
+			continue
+		}
+		if n == 0 && (jquery || method != "GET" && method != "DELETE" && method != "HEAD") {
+			params = append(params, propKeys(props)...)
+		}
+	}
+	a.addCall(v, method, params, a.instances[receiver], "axios")
+}
+
+func (a *jsAnalyzer) fetchCall(open int) {
+	args, _ := a.splitArgs(a.toks, open)
+	if len(args) == 0 {
+		return
+	}
+	v, _ := a.evalExpr(a.toks, args[0].start)
+	method := "GET"
+	var params []string
+	if len(args) > 1 && a.isPunct(a.toks, args[1].start, "{") {
+		props, _ := a.parseObject(a.toks, args[1].start)
+		if m, ok := a.propValue(props, "method"); ok && m != "" {
+			method = strings.ToUpper(m)
+		}
+		for _, p := range props {
+			if p.key == "body" {
+				params = append(params, a.objectKeysAt(p.value)...)
+			}
+		}
+	}
+	a.addCall(v, method, params, "", "fetch")
+}
+
+// configCall handles calls taking a request config: request({url, method, params, data}).
+func (a *jsAnalyzer) configCall(brace int, receiver, method, kind string) {
+	props, _ := a.parseObject(a.toks, brace)
+	var urlProp *jsProp
+	for i := range props {
+		if props[i].key == "url" {
+			urlProp = &props[i]
+			break
+		}
+	}
+	if urlProp == nil {
+		return
+	}
+	v, _ := a.evalExpr(a.toks, urlProp.value)
+	for _, key := range []string{"method", "type"} {
+		if m, ok := a.propValue(props, key); ok && m != "" {
+			method = strings.ToUpper(m)
+			break
+		}
+	}
+	var params []string
+	params = append(params, a.objectKeysOf(props, "params")...)
+	params = append(params, a.objectKeysOf(props, "data")...)
+	a.addCall(v, method, params, a.instances[receiver], kind)
+}
+
+// findConcats records paths built by "+" or template substitution outside recognized calls.
+func (a *jsAnalyzer) findConcats() {
+	toks := a.toks
+	for i, t := range toks {
+		if i > 0 {
+			prev := toks[i-1]
+			if prev.kind == jsTokPunct && (prev.text == "." || prev.text == "?." || prev.text == "+") ||
+				prev.kind == jsTokIdent && !jsReserved[prev.text] || prev.kind == jsTokString || prev.kind == jsTokNumber {
+				continue
+			}
+		}
+		switch t.kind {
+		case jsTokTemplate:
+		case jsTokString:
+			if !a.isPunct(toks, i+1, "+") {
+				continue
+			}
+		case jsTokIdent:
+			j := i + 1
+			for (a.isPunct(toks, j, ".") || a.isPunct(toks, j, "?.")) && a.isIdent(toks, j+1) {
+				j += 2
+			}
+			if !a.isPunct(toks, j, "+") {
+				continue
+			}
+		default:
+			continue
+		}
+		if v, _ := a.evalExpr(toks, i); v.composite {
+			a.addCall(v, "", nil, "", "concat")
+		}
+	}
+}
+
+func (a *jsAnalyzer) evalExpr(toks []jsToken, i int) (jsValue, int) {
+	if a.evalDepth >= jsMaxExprDepth {
+		return jsValue{}, i
+	}
+	if a.evalDepth == 0 {
+		a.evalOps = 0
+	}
+	a.evalDepth++
+	defer func() { a.evalDepth-- }()
+	v, i := a.evalOperand(toks, i)
+	var b strings.Builder
+	b.WriteString(v.s)
+	for a.isPunct(toks, i, "+") {
+		if a.evalOps++; a.evalOps > jsMaxExprOperands {
+			break
+		}
+		w, j := a.evalOperand(toks, i+1)
+		if j == i+1 {
+			break
+		}
+		if b.Len()+len(w.s) <= jsMaxExprLen {
+			b.WriteString(w.s)
+		}
+		v.lit = v.lit || w.lit
+		v.composite = true
+		i = j
+	}
+	v.s = b.String()
+	return v, i
+}
+
+func (a *jsAnalyzer) evalOperand(toks []jsToken, i int) (jsValue, int) {
+	if i >= len(toks) {
+		return jsValue{}, i
+	}
+	t := toks[i]
+	switch t.kind {
+	case jsTokString:
+		return jsValue{s: t.text, lit: true}, i + 1
+	case jsTokNumber:
+		return jsValue{s: t.text}, i + 1
+	case jsTokTemplate:
+		v := jsValue{lit: true}
+		var b strings.Builder
+		for _, p := range t.parts {
+			part := p.lit
+			if p.expr != nil {
+				if a.evalOps++; a.evalOps > jsMaxExprOperands {
+					break
+				}
+				ev, _ := a.evalExpr(p.expr, 0)
+				if part = ev.s; part == "" {
+					part = "{var}"
+				}
+				v.composite = true
+			}
+			if b.Len()+len(part) <= jsMaxExprLen {
+				b.WriteString(part)
+			}
+		}
+		v.s = b.String()
+		return v, i + 1
+	case jsTokPunct:
+		if t.text != "(" {
+			return jsValue{}, i
+		}
+		v, j := a.evalExpr(toks, i+1)
+		if a.isPunct(toks, j, ")") {
+			return v, j + 1
+		}
+		return v, a.skipBalanced(toks, i)
+	case jsTokIdent:
+		if jsReserved[t.text] {
+			return jsValue{}, i
+		}
+		names := []string{t.text}
+		j := i + 1
+		for {
+			if (a.isPunct(toks, j, ".") || a.isPunct(toks, j, "?.")) && a.isIdent(toks, j+1) {
+				names = append(names, toks[j+1].text)
+				j += 2
+			} else if a.isPunct(toks, j, "[") && j+2 < len(toks) && toks[j+1].kind == jsTokString && a.isPunct(toks, j+2, "]") {
+				names = append(names, toks[j+1].text)
+				j += 3
+			} else {
+				break
+			}
+		}
+		last := names[len(names)-1]
+		if a.isPunct(toks, j, "(") {
+			end := a.skipBalanced(toks, j)
+			switch last {
+			case "encodeURIComponent", "encodeURI", "String", "escape":
+				v, _ := a.evalExpr(toks, j+1)
+				return v, end
+			}
+			return jsValue{s: "{" + last + "}"}, end
+		}
+		return a.lookup(names), j
+	}
+	return jsValue{}, i
+}
+
+func (a *jsAnalyzer) lookup(names []string) jsValue {
+	if names[0] == "this" && len(names) > 1 {
+		names = names[1:]
+	}
+	last := names[len(names)-1]
+	if v, ok := a.resolve("a:", strings.Join(names, "."), a.assigns); ok {
+		return v
+	}
+	if len(names) > 1 {
+		if v, ok := a.resolve("p:", last, a.props); ok {
+			return v
+		}
+	}
+	return jsValue{s: "{" + last + "}"}
+}
+
+// resolve evaluates every assignment of name; it succeeds when all of them agree on a string.
+func (a *jsAnalyzer) resolve(kind, name string, table map[string][]int) (jsValue, bool) {
+	key := kind + name
+	if v, ok := a.cache[key]; ok {
+		return deref(v)
+	}
+	idxs := table[name]
+	if len(idxs) == 0 || len(idxs) > 16 || a.resolving[key] {
+		return jsValue{}, false
+	}
+	a.resolving[key] = true
+	defer delete(a.resolving, key)
+	var out *jsValue
+	for _, idx := range idxs {
+		v, _ := a.evalExpr(a.toks, idx)
+		if !v.lit || out != nil && out.s != v.s {
+			out = nil
+			break
+		}
+		v.composite = false
+		out = &v
+	}
+	a.cache[key] = out
+	return deref(out)
+}
+
+func deref(v *jsValue) (jsValue, bool) {
+	if v == nil {
+		return jsValue{}, false
+	}
+	return *v, true
+}
+
+func (a *jsAnalyzer) skipBalanced(toks []jsToken, i int) int {
+	depth := 0
+	for ; i < len(toks); i++ {
+		if toks[i].kind != jsTokPunct {
+			continue
+		}
+		switch toks[i].text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(toks)
+}
+
+// valueEnd returns the index of the "," or closing bracket that ends the value starting at i.
+func (a *jsAnalyzer) valueEnd(toks []jsToken, i int) int {
+	for i < len(toks) {
+		t := toks[i]
+		if t.kind == jsTokPunct {
+			switch t.text {
+			case ",", ")", "]", "}":
+				return i
+			case "(", "[", "{":
+				i = a.skipBalanced(toks, i)
+				continue
+			}
+		}
+		i++
+	}
+	return i
+}
+
+func (a *jsAnalyzer) splitArgs(toks []jsToken, open int) ([]jsRange, int) {
+	var args []jsRange
+	i := open + 1
+	for i < len(toks) && !a.isPunct(toks, i, ")") {
+		end := a.valueEnd(toks, i)
+		if end > i {
+			args = append(args, jsRange{start: i, end: end})
+		}
+		if !a.isPunct(toks, end, ",") {
+			i = end
+			break
+		}
+		i = end + 1
+	}
+	return args, i + 1
+}
+
+// parseObject lists the properties of the object literal opening at brace.
+func (a *jsAnalyzer) parseObject(toks []jsToken, brace int) ([]jsProp, int) {
+	var props []jsProp
+	i := brace + 1
+	for i < len(toks) && !a.isPunct(toks, i, "}") {
+		t := toks[i]
+		switch {
+		case a.isPunct(toks, i, "..."):
+			i = a.valueEnd(toks, i+1)
+		case a.isPunct(toks, i, "["):
+			i = a.valueEnd(toks, a.skipBalanced(toks, i))
+		case t.kind == jsTokIdent || t.kind == jsTokString || t.kind == jsTokNumber:
+			switch {
+			case a.isPunct(toks, i+1, ":"):
+				props = append(props, jsProp{key: t.text, value: i + 2})
+				i = a.valueEnd(toks, i+2)
+			case a.isPunct(toks, i+1, ",") || a.isPunct(toks, i+1, "}"):
+				props = append(props, jsProp{key: t.text, value: i})
+				i++
+			default:
+				// method shorthand, getter/setter or async method
+				i = a.valueEnd(toks, i+1)
+			}
+		default:
+			i = a.valueEnd(toks, i+1)
+		}
+		if a.isPunct(toks, i, ",") {
+			i++
+		} else if !a.isPunct(toks, i, "}") {
+			break
+		}
+	}
+	return props, i + 1
+}
+
+func propKeys(props []jsProp) []string {
+	keys := make([]string, 0, len(props))
+	for _, p := range props {
+		keys = append(keys, p.key)
+	}
+	return keys
+}
+
+// objectKeysOf returns the keys of props[key] when it is an object literal (possibly wrapped).
+func (a *jsAnalyzer) objectKeysOf(props []jsProp, key string) []string {
+	for _, p := range props {
+		if p.key == key {
+			return a.objectKeysAt(p.value)
+		}
+	}
+	return nil
+}
+
+// objectKeysAt finds the object literal at i, also through JSON.stringify({..}),
+// qs.stringify({..}) and new URLSearchParams({..}).
+func (a *jsAnalyzer) objectKeysAt(i int) []string {
+	for n := 0; n < 6 && i < len(a.toks); n++ {
+		t := a.toks[i]
+		if a.isPunct(a.toks, i, "{") {
+			props, _ := a.parseObject(a.toks, i)
+			return propKeys(props)
+		}
+		if t.kind != jsTokIdent && !a.isPunct(a.toks, i, ".") && !a.isPunct(a.toks, i, "(") {
+			return nil
+		}
+		i++
+	}
+	return nil
+}
+
+func (a *jsAnalyzer) addCall(v jsValue, method string, params []string, base, kind string) {
+	if !v.lit {
+		return
+	}
+	p, query, ok := normalizeJSAPIPath(v.s)
+	if !ok {
+		return
+	}
+	if base != "" && strings.HasPrefix(base, "/") && !strings.HasPrefix(p, strings.TrimRight(base, "/")+"/") {
+		p = path.Clean(strings.TrimRight(base, "/") + p)
+	}
+	params = append(query, params...)
+	key := method + " " + p
+	call, exists := a.calls[key]
+	if !exists {
+		call = &JSAPICall{Path: p, Method: method, Kind: kind}
+		a.calls[key] = call
+	}
+	for _, name := range params {
+		if name != "" && !containsString(call.Params, name) {
+			call.Params = append(call.Params, name)
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeJSAPIPath drops leading unknown bases ("{baseURL}/user" -> "/user"), splits off the
+// query string and rejects values that are not plausible API paths.
+func normalizeJSAPIPath(s string) (string, []string, bool) {
+	for strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			break
+		}
+		rest := s[end+1:]
+		if !strings.HasPrefix(rest, "/") && !strings.HasPrefix(rest, "{") {
+			break
+		}
+		s = rest
+	}
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		s = s[:i]
+	}
+	var query []string
+	if i := strings.IndexByte(s, '?'); i >= 0 {
+		for _, kv := range strings.Split(s[i+1:], "&") {
+			if name := strings.SplitN(kv, "=", 2)[0]; name != "" && !strings.HasPrefix(name, "{") {
+				query = append(query, name)
+			}
+		}
+		s = s[:i]
+	}
+	if !strings.HasPrefix(s, "/") || strings.HasPrefix(s, "//") || len(s) < 2 {
+		return "", nil, false
+	}
+	for strings.Contains(s, "//") {
+		s = strings.ReplaceAll(s, "//", "/")
+	}
+	if strings.ContainsAny(s, " \t\r\n\"'<>\\`") || jsStaticExtRe.MatchString(s) || !isUsefulAPIPath(s) {
+		return "", nil, false
+	}
+	literal := jsPlaceholderRe.ReplaceAllString(s, "")
+	if !strings.ContainsAny(strings.ToLower(literal), "abcdefghijklmnopqrstuvwxyz") {
+		return "", nil, false
+	}
+	return s, query, true
+}
+
+func (a *jsAnalyzer) results() []JSAPICall {
+	// a concatenation that is also the url of a call (possibly under an instance baseURL)
+	// is already covered by that call
+	var bases []string
+	for _, b := range a.instances {
+		if b = strings.TrimRight(b, "/"); strings.HasPrefix(b, "/") && !containsString(bases, b) {
+			bases = append(bases, b)
+		}
+	}
+	covered := map[string]bool{}
+	for _, c := range a.calls {
+		if c.Method == "" {
+			continue
+		}
+		covered[c.Path] = true
+		for _, b := range bases {
+			if strings.HasPrefix(c.Path, b+"/") {
+				covered[c.Path[len(b):]] = true
+			}
+		}
+	}
+	var out []JSAPICall
+	for _, c := range a.calls {
+		if c.Method == "" && covered[c.Path] {
+			continue
+		}
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Path != out[j].Path {
+			return out[i].Path < out[j].Path
+		}
+		return out[i].Method < out[j].Method
+	})
+	return out
+}
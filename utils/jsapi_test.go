@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testJSAPIBundle = `
+var BASE = "/api/v1", t = {userInfo: "/user/info"};
+const svc = axios.create({baseURL: "/gateway", timeout: 5e3});
+function load(id) {
+	fetch(BASE + "/orders/" + encodeURIComponent(id) + "?page=1&size=" + n, {method: "POST", body: JSON.stringify({name: a, phone: b})});
+	svc.get("/menu/list", {params: {roleId: r}});
+	svc.post(` + "`/role/${id}/update`" + `, {roleName: x, status: 1});
+	$.ajax({url: "/legacy/query.do", type: "post", data: {keyword: k}});
+	xhr.open("DELETE", BASE + "/files/" + id);
+	return request({url: "/system/user/" + id, method: "put", data: form});
+}
+var link = BASE + "/export/excel";
+axios.get(t.userInfo);
+var x = "a/" + b, css = "/static/" + name + ".css", abs = "https://example.com/" + p;
+`
+
+func TestExtractJSAPICalls(t *testing.T) {
+	got := map[string]JSAPICall{}
+	for _, c := range ExtractJSAPICalls(testJSAPIBundle) {
+		got[c.Method+" "+c.Path] = c
+	}
+	want := map[string]string{
+		"POST /api/v1/orders/{id}":       "page,size,name,phone",
+		"GET /gateway/menu/list":         "roleId",
+		"POST /gateway/role/{id}/update": "roleName,status",
+		"POST /legacy/query.do":          "keyword",
+		"DELETE /api/v1/files/{id}":      "",
+		"PUT /system/user/{id}":          "",
+		" /api/v1/export/excel":          "",
+		"GET /user/info":                 "",
+	}
+	for key, params := range want {
+		c, ok := got[key]
+		if !ok {
+			t.Errorf("missing %q, got %v", key, got)
+			continue
+		}
+		if strings.Join(c.Params, ",") != params {
+			t.Errorf("%s params = %v, want %s", key, c.Params, params)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d calls, want %d: %v", len(got), len(want), got)
+	}
+}
+
+func TestExtractJSAPICallsAmbiguousConstant(t *testing.T) {
+	calls := ExtractJSAPICalls(`var u = "/a/list"; if (x) { u = "/b/list" } axios.post(u + "/page");`)
+	if len(calls) != 1 || calls[0].Path != "/page" || calls[0].Method != "POST" {
+		t.Fatalf("ambiguous constant should stay a placeholder: %+v", calls)
+	}
+}
+
+func TestExtractJSAPICallsCoveredConcat(t *testing.T) {
+	src := `const svc = axios.create({baseURL: "/gateway"});
+svc.get("/menu/list"); var a = "/menu" + "/list", b = "/list" + "", c = "/gateway/menu" + "/list";`
+	got := map[string]bool{}
+	for _, c := range ExtractJSAPICalls(src) {
+		got[c.Method+" "+c.Path] = true
+	}
+	// "/menu/list" is the call under its baseURL, a bare "/list" is a different path
+	want := map[string]bool{"GET /gateway/menu/list": true, " /list": true}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestExtractJSAPICallsHostileExpressions(t *testing.T) {
+	for name, src := range map[string]string{
+		"long chain": `var x = "/api"` + strings.Repeat(`+"/x"`, 100000) + ";",
+		"nested":     "var x = " + strings.Repeat(`"/a"+(`, 3000) + "b" + strings.Repeat(")", 3000) + ";",
+		"doubling": `var a0 = "/api";` + func() string {
+			var b strings.Builder
+			for i := 1; i < 2000; i++ {
+				fmt.Fprintf(&b, "var a%d = a%d + a%d;", i, i-1, i-1)
+			}
+			return b.String()
+		}(),
+	} {
+		calls := ExtractJSAPICalls(src)
+		if len(calls) == 0 {
+			t.Errorf("%s: no calls", name)
+		}
+		for _, c := range calls {
+			if len(c.Path) > jsMaxExprLen || !strings.HasPrefix(c.Path, "/a") {
+				t.Errorf("%s: unexpected path of %d bytes %.40q", name, len(c.Path), c.Path)
+			}
+		}
+	}
+}
+
+func BenchmarkExtractJSAPICalls(b *testing.B) {
+	var sb strings.Builder
+	sb.WriteString(`var BASE = "/api/v1"; const svc = axios.create({baseURL: "/gateway"});` + "\n")
+	for i := 0; sb.Len() < 1<<20; i++ {
+		fmt.Fprintf(&sb, `function f%d(id){svc.get("/m%d/list",{params:{roleId:r}});var u=BASE+"/r%d/"+id;fetch("/x%d/detail?id="+id,{method:"POST"});var c="/c%d/"+id+"/edit";}`+"\n", i, i, i, i, i)
+	}
+	src := sb.String()
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ExtractJSAPICalls(src)
+	}
+}
+
+func TestSaveAPICallsMergesMethods(t *testing.T) {
+	db, err := InitSpiderDB(filepath.Join(t.TempDir(), "spider.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	defer db.Close()
+	root := "http://example.com"
+	if err := SaveAPIPaths(db, root, root+"/a.js", []string{"/user/list", "/user/list"}, ""); err != nil {
+		t.Fatalf("save paths: %v", err)
+	}
+	if err := SaveAPICalls(db, root, root+"/a.js", []JSAPICall{{Path: "/user/list", Method: "GET", Params: []string{"page"}}}, ""); err != nil {
+		t.Fatalf("save calls: %v", err)
+	}
+	if err := SaveAPICalls(db, root, root+"/b.js", []JSAPICall{{Path: "/user/list", Method: "POST", Params: []string{"page", "name"}}}, ""); err != nil {
+		t.Fatalf("save calls: %v", err)
+	}
+	rows, err := LoadAPIPaths(db)
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("rows=%+v err=%v", rows, err)
+	}
+	if rows[0].Method != "GET,POST" || rows[0].Params != "page,name" {
+		t.Fatalf("unexpected merge: %+v", rows[0])
+	}
+	ops := buildAPIExport(rows, nil, "")[0].Ops
+	if len(ops) != 2 || ops[0].Method != "GET" || ops[1].Method != "POST" || ops[1].Params[0].In != "formData" {
+		t.Fatalf("unexpected export ops: %+v", ops)
+	}
+
+	// a later script repeating a stored path still saves its new ones and keeps the merged call
+	if err := SaveAPIPaths(db, root, root+"/c.js", []string{"/user/list", "/role/list"}, ""); err != nil {
+		t.Fatalf("save repeated paths: %v", err)
+	}
+	if rows, _ = LoadAPIPaths(db); len(rows) != 2 || rows[0].Path != "/role/list" || rows[1].Method != "GET,POST" {
+		t.Fatalf("after repeated paths: %+v", rows)
+	}
+}
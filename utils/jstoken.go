@@ -0,0 +1,295 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A small JavaScript tokenizer used by the API extractor. It understands strings, template
+// literals (with nested ${} expressions), regex literals, comments and punctuators, which is
+// enough to follow string concatenation and call sites in minified bundles.
+
+type jsTokKind int
+
+const (
+	jsTokIdent jsTokKind = iota
+	jsTokString
+	jsTokTemplate
+	jsTokNumber
+	jsTokPunct
+	jsTokRegex
+)
+
+type jsToken struct {
+	kind jsTokKind
+	// text is the identifier/punctuator, or the decoded value of a string literal.
+	text string
+	// parts holds the pieces of a template literal: literal text alternating with ${} expressions.
+	parts []jsTemplatePart
+}
+
+type jsTemplatePart struct {
+	lit  string
+	expr []jsToken
+}
+
+var jsPunctuators = []string{
+	">>>=", "...", "===", "!==", "**=", "<<=", ">>=", ">>>", "&&=", "||=", "??=",
+	"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "++", "--", "+=", "-=", "*=", "/=",
+	"%=", "&=", "|=", "^=", "**", "<<", ">>",
+}
+
+// keywords after which a "/" starts a regex literal rather than a division.
+var jsRegexKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true,
+	"delete": true, "void": true, "throw": true, "case": true, "do": true, "else": true,
+	"yield": true, "await": true,
+}
+
+type jsLexer struct {
+	src  string
+	pos  int
+	toks []jsToken
+}
+
+// tokenizeJS splits src into tokens; malformed input is tokenized on a best-effort basis.
+func tokenizeJS(src string) []jsToken {
+	lx := &jsLexer{src: src}
+	lx.run(false)
+	return lx.toks
+}
+
+// run tokenizes until EOF, or until the "}" closing a template substitution when inTemplate is set.
+func (lx *jsLexer) run(inTemplate bool) {
+	depth := 0
+	for lx.pos < len(lx.src) {
+		c := lx.src[lx.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			lx.pos++
+		case c == '/' && lx.peek(1) == '/':
+			if i := strings.IndexByte(lx.src[lx.pos:], '\n'); i >= 0 {
+				lx.pos += i + 1
+			} else {
+				lx.pos = len(lx.src)
+			}
+		case c == '/' && lx.peek(1) == '*':
+			if i := strings.Index(lx.src[lx.pos+2:], "*/"); i >= 0 {
+				lx.pos += i + 4
+			} else {
+				lx.pos = len(lx.src)
+			}
+		case c == '\'' || c == '"':
+			lx.toks = append(lx.toks, jsToken{kind: jsTokString, text: lx.readString(c)})
+		case c == '`':
+			lx.toks = append(lx.toks, lx.readTemplate())
+		case isJSIdentStart(c):
+			start := lx.pos
+			for lx.pos < len(lx.src) && isJSIdentPart(lx.src[lx.pos]) {
+				lx.pos++
+			}
+			lx.toks = append(lx.toks, jsToken{kind: jsTokIdent, text: lx.src[start:lx.pos]})
+		case c >= '0' && c <= '9' || c == '.' && lx.peek(1) >= '0' && lx.peek(1) <= '9':
+			start := lx.pos
+			for lx.pos < len(lx.src) {
+				ch := lx.src[lx.pos]
+				if isJSIdentPart(ch) || ch == '.' {
+					lx.pos++
+					continue
+				}
+				if (ch == '+' || ch == '-') && (lx.src[lx.pos-1] == 'e' || lx.src[lx.pos-1] == 'E') && !strings.HasPrefix(lx.src[start:], "0x") {
+					lx.pos++
+					continue
+				}
+				break
+			}
+			lx.toks = append(lx.toks, jsToken{kind: jsTokNumber, text: lx.src[start:lx.pos]})
+		case c == '/' && lx.regexAllowed():
+			if tok, ok := lx.readRegex(); ok {
+				lx.toks = append(lx.toks, tok)
+				continue
+			}
+			lx.pos++
+			lx.toks = append(lx.toks, jsToken{kind: jsTokPunct, text: "/"})
+		default:
+			if inTemplate {
+				if c == '{' {
+					depth++
+				} else if c == '}' {
+					if depth == 0 {
+						lx.pos++
+						return
+					}
+					depth--
+				}
+			}
+			lx.toks = append(lx.toks, jsToken{kind: jsTokPunct, text: lx.readPunct()})
+		}
+	}
+}
+
+func (lx *jsLexer) peek(n int) byte {
+	if lx.pos+n < len(lx.src) {
+		return lx.src[lx.pos+n]
+	}
+	return 0
+}
+
+func isJSIdentStart(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isJSIdentPart(c byte) bool {
+	return isJSIdentStart(c) || c >= '0' && c <= '9'
+}
+
+func (lx *jsLexer) readPunct() string {
+	for _, p := range jsPunctuators {
+		if strings.HasPrefix(lx.src[lx.pos:], p) {
+			lx.pos += len(p)
+			return p
+		}
+	}
+	lx.pos++
+	return lx.src[lx.pos-1 : lx.pos]
+}
+
+func (lx *jsLexer) regexAllowed() bool {
+	if len(lx.toks) == 0 {
+		return true
+	}
+	prev := lx.toks[len(lx.toks)-1]
+	switch prev.kind {
+	case jsTokPunct:
+		return prev.text != ")" && prev.text != "]" && prev.text != "}"
+	case jsTokIdent:
+		return jsRegexKeywords[prev.text]
+	}
+	return false
+}
+
+func (lx *jsLexer) readRegex() (jsToken, bool) {
+	i := lx.pos + 1
+	inClass := false
+	for i < len(lx.src) {
+		c := lx.src[i]
+		switch {
+		case c == '\n':
+			return jsToken{}, false
+		case c == '\\':
+			i++
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '/' && !inClass:
+			i++
+			for i < len(lx.src) && isJSIdentPart(lx.src[i]) {
+				i++
+			}
+			tok := jsToken{kind: jsTokRegex, text: lx.src[lx.pos:i]}
+			lx.pos = i
+			return tok, true
+		}
+		i++
+	}
+	return jsToken{}, false
+}
+
+// readEscape decodes the escape sequence after a backslash at lx.pos.
+func (lx *jsLexer) readEscape(b *strings.Builder) {
+	lx.pos++ // backslash
+	if lx.pos >= len(lx.src) {
+		return
+	}
+	c := lx.src[lx.pos]
+	lx.pos++
+	switch c {
+	case 'n':
+		b.WriteByte('\n')
+	case 't':
+		b.WriteByte('\t')
+	case 'r':
+		b.WriteByte('\r')
+	case 'b', 'f', 'v', '0':
+	case '\r', '\n':
+		// line continuation
+	case 'x':
+		if lx.pos+2 <= len(lx.src) {
+			if v, err := strconv.ParseUint(lx.src[lx.pos:lx.pos+2], 16, 8); err == nil {
+				b.WriteRune(rune(v))
+				lx.pos += 2
+			}
+		}
+	case 'u':
+		hex := ""
+		if lx.pos < len(lx.src) && lx.src[lx.pos] == '{' {
+			if end := strings.IndexByte(lx.src[lx.pos:], '}'); end > 0 {
+				hex = lx.src[lx.pos+1 : lx.pos+end]
+				lx.pos += end + 1
+			}
+		} else if lx.pos+4 <= len(lx.src) {
+			hex = lx.src[lx.pos : lx.pos+4]
+			lx.pos += 4
+		}
+		if v, err := strconv.ParseUint(hex, 16, 32); err == nil && utf8.ValidRune(rune(v)) {
+			b.WriteRune(rune(v))
+		}
+	default:
+		b.WriteByte(c)
+	}
+}
+
+func (lx *jsLexer) readString(quote byte) string {
+	var b strings.Builder
+	lx.pos++
+	for lx.pos < len(lx.src) {
+		c := lx.src[lx.pos]
+		if c == quote {
+			lx.pos++
+			break
+		}
+		if c == '\n' {
+			break
+		}
+		if c == '\\' {
+			lx.readEscape(&b)
+			continue
+		}
+		b.WriteByte(c)
+		lx.pos++
+	}
+	return b.String()
+}
+
+func (lx *jsLexer) readTemplate() jsToken {
+	tok := jsToken{kind: jsTokTemplate}
+	var b strings.Builder
+	lx.pos++
+	for lx.pos < len(lx.src) {
+		c := lx.src[lx.pos]
+		if c == '`' {
+			lx.pos++
+			break
+		}
+		if c == '\\' {
+			lx.readEscape(&b)
+			continue
+		}
+		if c == '$' && lx.peek(1) == '{' {
+			tok.parts = append(tok.parts, jsTemplatePart{lit: b.String()})
+			b.Reset()
+			lx.pos += 2
+			sub := &jsLexer{src: lx.src, pos: lx.pos}
+			sub.run(true)
+			lx.pos = sub.pos
+			tok.parts = append(tok.parts, jsTemplatePart{expr: sub.toks})
+			continue
+		}
+		b.WriteByte(c)
+		lx.pos++
+	}
+	tok.parts = append(tok.parts, jsTemplatePart{lit: b.String()})
+	return tok
+}
@@ -0,0 +1,336 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalScanStats summarises a `godscan local` run.
+type LocalScanStats struct {
+	RootURL    string
+	Files      int
+	Archives   int
+	Skipped    int
+	APIs       int
+	SourceMaps int
+	// HeapStrings counts the distinct strings pulled out of heap dumps.
+	HeapStrings int
+}
+
+// localScanner runs the spider extractors over files on disk; every file is addressed as
+// file:///abs/path and archive members as file:///abs/archive.zip!/member.
+type localScanner struct {
+	root     string
+	saveDir  string
+	maxBytes int64
+	db       *sql.DB
+	stats    LocalScanStats
+	// maps holds the .map files met so far, refs the sourceMappingURL comments pointing at them.
+	maps map[string]int
+	refs []SourceMapHit
+}
+
+// DefaultLocalMaxBytes is the default per-file size limit of local scans.
+const DefaultLocalMaxBytes = 16 * 1024 * 1024
+
+var localSkipDirs = map[string]bool{".git": true, ".svn": true, "node_modules": true}
+
+// ScanLocal walks a directory, a single file or a zip/tar/tar.gz archive and stores API paths,
+// sensitive hits and source maps into db under a file:// root, like the spider does for a site.
+func ScanLocal(target, saveDir string, maxBytes int64, db *sql.DB) (LocalScanStats, error) {
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		return LocalScanStats{}, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return LocalScanStats{}, err
+	}
+	s := &localScanner{
+		root:     localFileURL(abs),
+		saveDir:  saveDir,
+		maxBytes: maxBytes,
+		db:       db,
+		maps:     make(map[string]int),
+	}
+	s.stats.RootURL = s.root
+	if info.IsDir() {
+		err = filepath.WalkDir(abs, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				Debug("local: %v", err)
+				return nil
+			}
+			if d.IsDir() {
+				if p != abs && localSkipDirs[d.Name()] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.Type().IsRegular() {
+				s.scanPath(p)
+			}
+			return nil
+		})
+	} else {
+		s.scanPath(abs)
+	}
+	s.saveSourceMapRefs()
+	return s.stats, err
+}
+
+func localFileURL(p string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(p)}).String()
+}
+
+func (s *localScanner) scanPath(p string) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return
+	}
+	if archiveKind(p) != "" {
+		s.scanArchive(p, localFileURL(p)+"!/", info.Size())
+		return
+	}
+	if IsHeapDumpName(p) {
+		f, err := os.Open(p)
+		if err != nil {
+			Debug("local: open %s: %v", p, err)
+			return
+		}
+		defer f.Close()
+		s.scanHeapDump(localFileURL(p), f)
+		return
+	}
+	if s.maxBytes > 0 && info.Size() > s.maxBytes {
+		s.stats.Skipped++
+		Debug("local: skip %s (%d bytes)", p, info.Size())
+		return
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		Debug("local: read %s: %v", p, err)
+		return
+	}
+	s.scanFile(localFileURL(p), p, data)
+}
+
+func archiveKind(name string) string {
+	ln := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(ln, ".zip"), strings.HasSuffix(ln, ".jar"), strings.HasSuffix(ln, ".war"):
+		return "zip"
+	case strings.HasSuffix(ln, ".tar.gz"), strings.HasSuffix(ln, ".tgz"):
+		return "tgz"
+	case strings.HasSuffix(ln, ".tar"):
+		return "tar"
+	}
+	return ""
+}
+
+// scanArchive scans every member of the archive at p, addressing them as base+name.
+func (s *localScanner) scanArchive(p, base string, size int64) {
+	s.stats.Archives++
+	f, err := os.Open(p)
+	if err != nil {
+		Debug("local: open %s: %v", p, err)
+		return
+	}
+	defer f.Close()
+	switch archiveKind(p) {
+	case "zip":
+		zr, err := zip.NewReader(f, size)
+		if err != nil {
+			Warning("local: %s is not a valid zip: %v", p, err)
+			return
+		}
+		for _, zf := range zr.File {
+			if zf.FileInfo().IsDir() || localSkipped(zf.Name) {
+				continue
+			}
+			if IsHeapDumpName(zf.Name) {
+				if rc, err := zf.Open(); err == nil {
+					s.scanHeapDump(base+zf.Name, rc)
+					rc.Close()
+				}
+				continue
+			}
+			if s.maxBytes > 0 && int64(zf.UncompressedSize64) > s.maxBytes {
+				s.stats.Skipped++
+				continue
+			}
+			rc, err := zf.Open()
+			if err != nil {
+				continue
+			}
+			data, err := s.readLimited(rc)
+			rc.Close()
+			if err == nil {
+				s.scanFile(base+zf.Name, zf.Name, data)
+			}
+		}
+	case "tgz", "tar":
+		var r io.Reader = f
+		if archiveKind(p) == "tgz" {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				Warning("local: %s is not a valid gzip: %v", p, err)
+				return
+			}
+			defer gz.Close()
+			r = gz
+		}
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				if err != io.EOF {
+					Warning("local: read %s: %v", p, err)
+				}
+				return
+			}
+			if hdr.Typeflag != tar.TypeReg || localSkipped(hdr.Name) {
+				continue
+			}
+			if IsHeapDumpName(hdr.Name) {
+				s.scanHeapDump(base+hdr.Name, tr)
+				continue
+			}
+			if s.maxBytes > 0 && hdr.Size > s.maxBytes {
+				s.stats.Skipped++
+				continue
+			}
+			data, err := s.readLimited(tr)
+			if err == nil {
+				s.scanFile(base+hdr.Name, hdr.Name, data)
+			}
+		}
+	}
+}
+
+// scanHeapDump streams a heap dump regardless of maxBytes: dumps are large by nature.
+func (s *localScanner) scanHeapDump(fileURL string, r io.Reader) {
+	st, err := ScanHeapDump(s.db, fileURL, s.saveDir, r)
+	if err != nil {
+		Warning("heapdump %s: %v", fileURL, err)
+	}
+	s.stats.Files++
+	s.stats.HeapStrings += st.Strings
+}
+
+func localSkipped(name string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(name), "/") {
+		if localSkipDirs[part] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *localScanner) readLimited(r io.Reader) ([]byte, error) {
+	if s.maxBytes <= 0 {
+		return io.ReadAll(r)
+	}
+	return io.ReadAll(io.LimitReader(r, s.maxBytes))
+}
+
+// scanFile runs the same extractors as handleJSAsset over one file body.
+func (s *localScanner) scanFile(fileURL, name string, data []byte) {
+	if isBinaryContent(data) {
+		return
+	}
+	s.stats.Files++
+	ln := strings.ToLower(name)
+	if strings.HasSuffix(ln, ".map") {
+		// the recovered sources are scanned one by one instead of the raw json
+		s.maps[fileURL] = len(data)
+		extractSourceMap(s.root, fileURL, s.saveDir, data, &s.stats.APIs, s.db)
+		return
+	}
+	body := string(data)
+	SensitiveInfoCollect(s.db, fileURL, body, s.saveDir)
+	if !isLocalCodeFile(ln) {
+		return
+	}
+	ParseJavaScriptUrl(fileURL, s.root, body, s.saveDir, &s.stats.APIs, s.db)
+	if sm := sourceMapFromContent(fileURL, body); sm != "" {
+		s.sourceMapRef(fileURL, sm)
+	}
+}
+
+func (s *localScanner) sourceMapRef(jsURL, mapURL string) {
+	const inline = "data:application/json;"
+	if strings.HasPrefix(mapURL, inline) {
+		i := strings.Index(mapURL, "base64,")
+		if i < 0 {
+			return
+		}
+		body, err := base64.StdEncoding.DecodeString(mapURL[i+len("base64,"):])
+		if err != nil {
+			return
+		}
+		extractSourceMap(s.root, jsURL+".map", s.saveDir, body, &s.stats.APIs, s.db)
+		s.refs = append(s.refs, SourceMapHit{RootURL: s.root, JSURL: jsURL, MapURL: jsURL + " (inline)", Status: 200, Length: len(body)})
+		return
+	}
+	s.refs = append(s.refs, SourceMapHit{RootURL: s.root, JSURL: jsURL, MapURL: mapURL})
+}
+
+// saveSourceMapRefs stores the sourceMappingURL references once the walk is over, marking
+// those whose .map file was found on disk with status 200 and the others with 404.
+func (s *localScanner) saveSourceMapRefs() {
+	for i := range s.refs {
+		if s.refs[i].Status != 0 {
+			continue
+		}
+		if n, ok := s.maps[s.refs[i].MapURL]; ok {
+			s.refs[i].Status, s.refs[i].Length = 200, n
+		} else {
+			s.refs[i].Status = 404
+		}
+	}
+	for _, r := range s.refs {
+		FileWrite(s.saveDir+"sourcemaps.txt", "%s\t%d\t%d\t%s\n", r.MapURL, r.Status, r.Length, r.JSURL)
+		if r.Status == 200 {
+			s.stats.SourceMaps++
+		}
+	}
+	if err := SaveSourceMaps(s.db, s.refs); err != nil {
+		Error("save source maps failed: %v", err)
+	}
+}
+
+func isLocalCodeFile(name string) bool {
+	for _, ext := range []string{".js", ".mjs", ".cjs", ".jsx", ".ts", ".tsx", ".vue", ".html", ".htm"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBinaryContent treats a NUL byte in the first 8KB as a binary file.
+func isBinaryContent(data []byte) bool {
+	if len(data) > 8192 {
+		data = data[:8192]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+func (st LocalScanStats) String() string {
+	out := fmt.Sprintf("%d file(s), %d archive(s), %d skipped, %d api path(s), %d source map(s)", st.Files, st.Archives, st.Skipped, st.APIs, st.SourceMaps)
+	if st.HeapStrings > 0 {
+		out += fmt.Sprintf(", %d heap string(s)", st.HeapStrings)
+	}
+	return out
+}
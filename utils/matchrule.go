@@ -61,6 +61,7 @@ func logRequestError(targetURL string, err error) {
 
 type Packjson struct {
 	Fingerprint []Fingerprint
+	Rules       []ExprRule
 }
 
 type Fingerprint struct {
@@ -68,6 +69,8 @@ type Fingerprint struct {
 	Method   string
 	Location string
 	Keyword  []string
+	// Pack is the rule pack the fingerprint was loaded from (not part of the json schema).
+	Pack string `json:"-"`
 }
 
 //go:embed ehole.json
@@ -92,9 +95,10 @@ func chooseLocator(headers string, body string, title string, fp Fingerprint) st
 }
 
 // 递归解析表达式，考虑括号和逻辑优先级
+// key="v" 为包含，key=="v" 为相等，key!="v" 为不包含
 func preprocessAndEvaluate(input string, context map[string]string) (bool, error) {
 	// 使用 regexp2 包来替换原来的 regexp
-	var re = regexp2.MustCompile(`\(([^()]*)\)|((\w+)\s*=\s*"((?:[^"]|"(?! && | \|\| |$))*)")`, regexp2.None)
+	var re = regexp2.MustCompile(`\(([^()]*)\)|((\w+)\s*(==|!=|=)\s*"((?:[^"]|"(?! && | \|\| |$))*)")`, regexp2.None)
 	// 不断替换直到无括号为止
 	for {
 		matches, _ := re.FindStringMatch(input)
@@ -119,8 +123,17 @@ func preprocessAndEvaluate(input string, context map[string]string) (bool, error
 				}
 				input = strings.Replace(input, fmt.Sprintf("(%s)", match.Groups()[1].String()), resultStr, 1)
 			} else if match.Groups()[2].String() != "" { // 匹配到键值对表达式
-				key, value := match.Groups()[3].String(), match.Groups()[4].String()
-				if strings.Contains(context[key], value) {
+				key, op, value := match.Groups()[3].String(), match.Groups()[4].String(), match.Groups()[5].String()
+				var ok bool
+				switch op {
+				case "==":
+					ok = context[key] == value
+				case "!=":
+					ok = !strings.Contains(context[key], value)
+				default:
+					ok = strings.Contains(context[key], value)
+				}
+				if ok {
 					input = strings.Replace(input, match.Groups()[2].String(), "true", 1)
 				} else {
 					input = strings.Replace(input, match.Groups()[2].String(), "false", 1)
@@ -158,6 +171,8 @@ type FingerResult struct {
 	HeadersJSON string
 	Body        []byte
 	Status      int
+	// FingerPacks maps each matched Cms to the rule pack it came from.
+	FingerPacks map[string]string
 	Err         error
 }
 
@@ -171,22 +186,24 @@ func FingerScan(url string, method string, followRedirect bool) FingerResult {
 	}
 	defer resp.Body.Close()
 
+	headersJSON := MapToJson(resp.Header)
+	config, err := loadFingerConfig(url)
+	if err != nil {
+		return FingerResult{Finger: common.NoFinger, HeadersJSON: headersJSON, Status: -1, Err: err}
+	}
+
 	if !followRedirect && isRedirect(resp.StatusCode) {
-		return FingerResult{
+		res := FingerResult{
 			Finger:      common.NoFinger,
 			Server:      serverHeader,
 			Title:       "",
 			ContentType: resp.Header.Get("Content-Type"),
 			Location:    resp.Header.Get("Location"),
-			HeadersJSON: MapToJson(resp.Header),
+			HeadersJSON: headersJSON,
 			Status:      resp.StatusCode,
 		}
-	}
-
-	headersJSON := MapToJson(resp.Header)
-	config, err := loadFingerConfig(url)
-	if err != nil {
-		return FingerResult{Finger: common.NoFinger, HeadersJSON: headersJSON, Status: -1, Err: err}
+		applyExprRules(&res, resp, url, config.Rules)
+		return res
 	}
 
 	bodyBytes, contentLength := readResponseBody(resp)
@@ -207,9 +224,10 @@ func FingerScan(url string, method string, followRedirect bool) FingerResult {
 		return FingerResult{Finger: common.NoFinger, HeadersJSON: headersJSON, Status: -1, Err: err}
 	}
 
-	finger := matchFingerprints(doc, string(bodyBytes), title, headersJSON, config)
-	return FingerResult{
+	finger, packs := matchFingerprints(doc, string(bodyBytes), title, headersJSON, config)
+	res := FingerResult{
 		Finger:      finger,
+		FingerPacks: packs,
 		Server:      serverHeader,
 		Title:       title,
 		ContentType: resp.Header.Get("Content-Type"),
@@ -218,6 +236,8 @@ func FingerScan(url string, method string, followRedirect bool) FingerResult {
 		Body:        bodyBytes,
 		Status:      resp.StatusCode,
 	}
+	applyExprRules(&res, resp, url, config.Rules)
+	return res
 }
 
 func doHTTPRequest(url, method string, followRedirect bool) (*http.Response, string, error) {
@@ -265,20 +285,22 @@ func isRedirect(status int) bool {
 }
 
 func loadFingerConfig(url string) (Packjson, error) {
-	var config Packjson
-	if err := json.Unmarshal([]byte(eholeJson), &config); err != nil {
-		Fatal("%s %s unmarshal failed", url, err)
+	fingerRulesMu.Lock()
+	defer fingerRulesMu.Unlock()
+	if fingerRules != nil {
+		return *fingerRules, nil
+	}
+	config, err := LoadFingerRules(fingerRulesPaths)
+	if err != nil {
+		Fatal("%s %s load finger rules failed", url, err)
 		return config, err
 	}
+	fingerRules = &config
 	return config, nil
 }
 
 func readResponseBody(resp *http.Response) ([]byte, int) {
-	maxBody := viper.GetInt("max-body-bytes")
-	if maxBody <= 0 || maxBody > 4*1024*1024 {
-		maxBody = 4 * 1024 * 1024
-	}
-	bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, int64(maxBody)))
+	bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, int64(responseBodyCap())))
 	contentLength := len(bodyBytes)
 	if cl := resp.Header.Get("Content-Length"); cl != "" {
 		if v, e := strconv.Atoi(cl); e == nil {
@@ -288,6 +310,15 @@ func readResponseBody(resp *http.Response) ([]byte, int) {
 	return bodyBytes, contentLength
 }
 
+// responseBodyCap is the body limit FingerScan reads under: max-body-bytes, at most 4MB.
+func responseBodyCap() int {
+	maxBody := viper.GetInt("max-body-bytes")
+	if maxBody <= 0 || maxBody > 4*1024*1024 {
+		maxBody = 4 * 1024 * 1024
+	}
+	return maxBody
+}
+
 func buildDocument(body []byte, serverContentType string) (*goquery.Document, string, error) {
 	if len(body) == 0 {
 		doc, _ := goquery.NewDocumentFromReader(strings.NewReader("<html></html>"))
@@ -306,7 +337,7 @@ func buildDocument(body []byte, serverContentType string) (*goquery.Document, st
 	return doc, title, nil
 }
 
-func matchFingerprints(doc *goquery.Document, body string, title string, headers string, config Packjson) string {
+func matchFingerprints(doc *goquery.Document, body string, title string, headers string, config Packjson) (string, map[string]string) {
 	context := map[string]string{
 		"title":  title,
 		"body":   body,
@@ -314,27 +345,37 @@ func matchFingerprints(doc *goquery.Document, body string, title string, headers
 		"header": headers,
 	}
 	var cms []string
+	packs := make(map[string]string)
+	hit := func(fp Fingerprint) {
+		cms = append(cms, fp.Cms)
+		if _, ok := packs[fp.Cms]; !ok {
+			packs[fp.Cms] = fp.Pack
+		}
+	}
 	for _, fp := range config.Fingerprint {
 		matcher, found := methodMatchers[fp.Method]
 		if found {
 			locator := chooseLocator(headers, body, title, fp)
 			if matcher(locator, fp.Keyword) {
-				cms = append(cms, fp.Cms)
+				hit(fp)
 			}
 			continue
 		}
+		if len(fp.Keyword) == 0 {
+			continue
+		}
 		res, err := preprocessAndEvaluate(fp.Keyword[0], context)
 		if err != nil {
 			Error("%s %s", err, fp.Keyword[0])
 			continue
 		}
 		if res {
-			cms = append(cms, fp.Cms)
+			hit(fp)
 		}
 	}
 	cms = RemoveDuplicatesString(cms)
 	if len(cms) == 0 {
-		return common.NoFinger
+		return common.NoFinger, nil
 	}
-	return strings.Join(cms, ",")
+	return strings.Join(cms, ","), packs
 }
@@ -0,0 +1,360 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// Severity levels of a Finding, most severe first.
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityInfo     = "info"
+)
+
+var severityRank = map[string]int{SeverityCritical: 0, SeverityHigh: 1, SeverityMedium: 2, SeverityLow: 3, SeverityInfo: 4}
+
+// Finding is one severity-rated result of a middleware module check or a POC template.
+type Finding struct {
+	RootURL  string `json:"root_url"`
+	URL      string `json:"url"`
+	Module   string `json:"module"`
+	Title    string `json:"title"`
+	Severity string `json:"severity"`
+	Detail   string `json:"detail"`
+	// TemplateID and Evidence are set by the POC engine (Module "poc").
+	TemplateID string `json:"template_id"`
+	Evidence   string `json:"evidence"`
+}
+
+// MiddlewareModule recognizes one product from a fingerprint result and runs its read-only checks.
+type MiddlewareModule interface {
+	Name() string
+	// Base returns the console base url of the product behind pageURL, or "" when fres is not it.
+	Base(pageURL string, fres FingerResult) string
+	Check(base string, db *sql.DB) []Finding
+}
+
+var (
+	middlewareModules = []MiddlewareModule{nacosModule{}, druidModule{}, xxlJobModule{}, geoServerModule{}}
+	middlewareSeen    sync.Map
+)
+
+// RunMiddlewareModules runs every module recognizing fres once per console base url, stores the
+// findings and returns them.
+func RunMiddlewareModules(pageURL string, fres FingerResult, db *sql.DB) []Finding {
+	if fres.Status == -1 {
+		return nil
+	}
+	var out []Finding
+	for _, m := range middlewareModules {
+		base := m.Base(pageURL, fres)
+		if base == "" {
+			continue
+		}
+		if _, loaded := middlewareSeen.LoadOrStore(m.Name()+" "+base, true); loaded {
+			continue
+		}
+		Debug("[%s] checking %s", m.Name(), base)
+		findings := m.Check(base, db)
+		for i := range findings {
+			findings[i].RootURL, findings[i].Module = apiDocRoot(base), m.Name()
+			Success("[%s] [%s] %s %s %s", m.Name(), colorSeverity(findings[i].Severity), findings[i].Title, findings[i].URL, findings[i].Detail)
+		}
+		if err := SaveFindings(db, findings); err != nil {
+			Error("save findings failed: %v", err)
+		}
+		out = append(out, findings...)
+	}
+	return out
+}
+
+func colorSeverity(s string) string {
+	switch s {
+	case SeverityCritical, SeverityHigh:
+		return color.RedString(s)
+	case SeverityMedium:
+		return color.YellowString(s)
+	}
+	return s
+}
+
+// middlewareBase cuts pageURL after the first path segment equal to seg ("/nacos/#/login" ->
+// "/nacos"); without it, the product is assumed at fallback under the site root.
+func middlewareBase(pageURL, seg, fallback string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	p := u.Path
+	if i := strings.Index(strings.ToLower(p)+"/", "/"+seg+"/"); i >= 0 {
+		p = p[:i+1+len(seg)]
+	} else {
+		p = fallback
+	}
+	u.Path, u.RawQuery, u.Fragment = p, "", ""
+	return strings.TrimSuffix(u.String(), "/")
+}
+
+func fingerHas(fres FingerResult, words ...string) bool {
+	f := strings.ToLower(fres.Finger + " " + fres.Title)
+	for _, w := range words {
+		if strings.Contains(f, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// middlewareGet returns body, status and the url finally answered after redirects.
+func middlewareGet(rawURL string) ([]byte, int, string) {
+	resp, err := fetchGet(rawURL)
+	if err != nil {
+		return nil, -1, rawURL
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, apiDocMaxBytes))
+	return body, resp.StatusCode, resp.Request.URL.String()
+}
+
+type nacosModule struct{}
+
+func (nacosModule) Name() string { return "nacos" }
+
+func (nacosModule) Base(pageURL string, fres FingerResult) string {
+	if !fingerHas(fres, "nacos") && !strings.Contains(strings.ToLower(pageURL), "/nacos") {
+		return ""
+	}
+	return middlewareBase(pageURL, "nacos", "/nacos")
+}
+
+func (nacosModule) Check(base string, db *sql.DB) []Finding {
+	var out []Finding
+	stateURL := base + "/v1/console/server/state"
+	if body, status, _ := middlewareGet(stateURL); status == http.StatusOK {
+		var state map[string]any
+		if json.Unmarshal(body, &state) == nil && state["version"] != nil {
+			out = append(out, Finding{URL: stateURL, Title: "Nacos version", Severity: SeverityInfo, Detail: fmt.Sprintf("version=%v mode=%v", state["version"], state["standalone_mode"])})
+			if fmt.Sprint(state["auth_enabled"]) == "false" {
+				out = append(out, Finding{URL: stateURL, Title: "Nacos auth disabled", Severity: SeverityHigh, Detail: "auth_enabled=false"})
+			}
+		}
+	}
+	usersURL := base + "/v1/auth/users?pageNo=1&pageSize=100"
+	if body, status, _ := middlewareGet(usersURL); status == http.StatusOK {
+		var page struct {
+			TotalCount int `json:"totalCount"`
+			PageItems  []struct {
+				Username string `json:"username"`
+			} `json:"pageItems"`
+		}
+		if json.Unmarshal(body, &page) == nil && len(page.PageItems) > 0 {
+			var names []string
+			for _, u := range page.PageItems {
+				names = append(names, u.Username)
+			}
+			out = append(out, Finding{URL: usersURL, Title: "Nacos user list readable without login", Severity: SeverityHigh, Detail: strings.Join(names, ",")})
+		}
+	}
+	configsURL := base + "/v1/cs/configs?dataId=&group=&search=accurate&pageNo=1&pageSize=100"
+	body, status, _ := middlewareGet(configsURL)
+	if status != http.StatusOK {
+		return out
+	}
+	var page struct {
+		TotalCount int `json:"totalCount"`
+		PageItems  []struct {
+			DataID  string `json:"dataId"`
+			Group   string `json:"group"`
+			Tenant  string `json:"tenant"`
+			Content string `json:"content"`
+		} `json:"pageItems"`
+	}
+	if json.Unmarshal(body, &page) != nil || page.PageItems == nil {
+		return out
+	}
+	var ids []string
+	saveDir := ""
+	if u, err := url.Parse(base); err == nil {
+		saveDir = hostOutputDir(u, "middleware")
+	}
+	for _, c := range page.PageItems {
+		ids = append(ids, c.Group+"/"+c.DataID)
+		// every config is grepped on its own so that hits point at the dataId they came from
+		src := fmt.Sprintf("%s/v1/cs/configs?dataId=%s&group=%s&tenant=%s", base, url.QueryEscape(c.DataID), url.QueryEscape(c.Group), url.QueryEscape(c.Tenant))
+		SensitiveInfoCollect(db, src, c.Content, saveDir)
+	}
+	if len(ids) == 0 {
+		// the endpoint is open, but nothing readable sits in the public namespace
+		out = append(out, Finding{URL: configsURL, Title: "Nacos config listing without auth", Severity: SeverityHigh, Detail: "listing answered without auth but returned no config (empty pageItems)"})
+		return out
+	}
+	out = append(out, Finding{URL: configsURL, Title: "Nacos config listing without auth", Severity: SeverityCritical, Detail: fmt.Sprintf("%d config(s): %s", page.TotalCount, strings.Join(ids, ","))})
+	return out
+}
+
+type druidModule struct{}
+
+func (druidModule) Name() string { return "druid" }
+
+func (druidModule) Base(pageURL string, fres FingerResult) string {
+	if !fingerHas(fres, "druid") && !strings.Contains(strings.ToLower(pageURL), "/druid") {
+		return ""
+	}
+	return middlewareBase(pageURL, "druid", "/druid")
+}
+
+func (druidModule) Check(base string, db *sql.DB) []Finding {
+	var out []Finding
+	indexURL := base + "/index.html"
+	// a protected console redirects to login.html
+	if body, status, final := middlewareGet(indexURL); status == http.StatusOK && !strings.Contains(final, "login.html") && strings.Contains(strings.ToLower(string(body)), "druid") {
+		out = append(out, Finding{URL: indexURL, Title: "Druid monitor console without login", Severity: SeverityMedium, Detail: "datasource, SQL and URI statistics readable"})
+	}
+	sessionsURL := base + "/websession.json"
+	body, status, _ := middlewareGet(sessionsURL)
+	var res struct {
+		ResultCode int `json:"ResultCode"`
+		Content    []struct {
+			SessionID string `json:"SESSIONID"`
+		} `json:"Content"`
+	}
+	if status == http.StatusOK && json.Unmarshal(body, &res) == nil && res.ResultCode == 1 && len(res.Content) > 0 {
+		var ids []string
+		for i, s := range res.Content {
+			if i == 5 {
+				ids = append(ids, "...")
+				break
+			}
+			ids = append(ids, s.SessionID)
+		}
+		out = append(out, Finding{URL: sessionsURL, Title: "Druid web sessions exposed", Severity: SeverityHigh, Detail: fmt.Sprintf("%d session(s): %s", len(res.Content), strings.Join(ids, ","))})
+	}
+	return out
+}
+
+type xxlJobModule struct{}
+
+func (xxlJobModule) Name() string { return "xxl-job" }
+
+func (xxlJobModule) Base(pageURL string, fres FingerResult) string {
+	if !fingerHas(fres, "xxl-job", "任务调度中心") && !strings.Contains(strings.ToLower(pageURL), "/xxl-job-admin") {
+		return ""
+	}
+	return middlewareBase(pageURL, "xxl-job-admin", "/xxl-job-admin")
+}
+
+func (xxlJobModule) Check(base string, db *sql.DB) []Finding {
+	loginURL := base + "/toLogin"
+	body, status, _ := middlewareGet(loginURL)
+	text := strings.ToLower(string(body))
+	if status != http.StatusOK || !(strings.Contains(text, "xxl-job") || strings.Contains(text, "任务调度中心")) {
+		return nil
+	}
+	// only the page is checked; trying admin/123456 is left to the weak password commands
+	return []Finding{{URL: loginURL, Title: "XXL-JOB admin login page", Severity: SeverityMedium, Detail: "default account admin/123456 worth checking"}}
+}
+
+type geoServerModule struct{}
+
+func (geoServerModule) Name() string { return "geoserver" }
+
+func (geoServerModule) Base(pageURL string, fres FingerResult) string {
+	if !fingerHas(fres, "geoserver") && !strings.Contains(strings.ToLower(pageURL), "/geoserver") {
+		return ""
+	}
+	return middlewareBase(pageURL, "geoserver", "/geoserver")
+}
+
+func (geoServerModule) Check(base string, db *sql.DB) []Finding {
+	var out []Finding
+	versionURL := base + "/rest/about/version.json"
+	if body, status, _ := middlewareGet(versionURL); status == http.StatusOK {
+		var v struct {
+			About struct {
+				Resource []struct {
+					Name    string `json:"@name"`
+					Version any    `json:"Version"`
+				} `json:"resource"`
+			} `json:"about"`
+		}
+		if json.Unmarshal(body, &v) == nil {
+			for _, r := range v.About.Resource {
+				if r.Name == "GeoServer" {
+					out = append(out, Finding{URL: versionURL, Title: "GeoServer version", Severity: SeverityInfo, Detail: fmt.Sprintf("version=%v", r.Version)})
+				}
+			}
+		}
+	}
+	wsURL := base + "/rest/workspaces.json"
+	body, status, _ := middlewareGet(wsURL)
+	var ws struct {
+		Workspaces struct {
+			Workspace []struct {
+				Name string `json:"name"`
+			} `json:"workspace"`
+		} `json:"workspaces"`
+	}
+	// {}, soft-404 JSON and SPA fallbacks decode fine too, only a real workspace list counts
+	if status == http.StatusOK && json.Unmarshal(body, &ws) == nil && len(ws.Workspaces.Workspace) > 0 {
+		var names []string
+		for _, w := range ws.Workspaces.Workspace {
+			names = append(names, w.Name)
+		}
+		out = append(out, Finding{URL: wsURL, Title: "GeoServer REST listing without auth", Severity: SeverityHigh, Detail: fmt.Sprintf("%d workspace(s): %s", len(names), strings.Join(names, ","))})
+	}
+	return out
+}
+
+func SaveFindings(db *sql.DB, findings []Finding) error {
+	if db == nil || len(findings) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO findings (root_url, url, module, title, severity, detail, template_id, evidence, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, f := range findings {
+		if _, err := stmt.Exec(f.RootURL, f.URL, f.Module, f.Title, f.Severity, f.Detail, f.TemplateID, f.Evidence); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadFindings returns the findings most severe first.
+func LoadFindings(db *sql.DB) ([]Finding, error) {
+	rows, err := db.Query(`SELECT root_url, url, module, title, severity, detail, template_id, evidence FROM findings ORDER BY root_url, module, url`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Finding
+	for rows.Next() {
+		var f Finding
+		if err := rows.Scan(&f.RootURL, &f.URL, &f.Module, &f.Title, &f.Severity, &f.Detail, &f.TemplateID, &f.Evidence); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return severityRank[out[i].Severity] < severityRank[out[j].Severity] })
+	return out, rows.Err()
+}
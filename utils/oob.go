@@ -0,0 +1,361 @@
+package utils
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OOB tokens are lowercase so that they survive DNS case folding.
+const (
+	oobTokenLen  = 16
+	oobMaxHits   = 10000
+	oobPollPath  = "/__oob/poll"
+	oobTokenChar = "abcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// oobTokenSep splits a hit into the labels, path segments and query parts a token can fill.
+func oobTokenSep(r rune) bool {
+	return strings.ContainsRune("./?=& ", r)
+}
+
+// OOBHit is one callback received by the listener.
+type OOBHit struct {
+	Protocol string `json:"protocol"`
+	Remote   string `json:"remote"`
+	// Raw is the dns query name, or "METHOD host/path?query" for http.
+	Raw  string    `json:"raw"`
+	Time time.Time `json:"time"`
+}
+
+// OOBListener is an HTTP + DNS callback listener. It only records hits: tokens are issued and
+// correlated by an OOBClient, in the same process or remotely through the poll endpoint.
+type OOBListener struct {
+	// Domain is the zone delegated to the DNS listener (NS record pointing at this host).
+	Domain string
+	// IP is answered to A queries under Domain, usually the public address of the listener.
+	IP string
+	// Secret protects the poll endpoint; polling is disabled when empty.
+	Secret string
+
+	mu    sync.Mutex
+	hits  []OOBHit
+	base  int
+	httpL net.Listener
+	dnsC  net.PacketConn
+	srv   *http.Server
+}
+
+// Start listens on httpAddr and/or dnsAddr (udp); an empty address disables that protocol.
+func (l *OOBListener) Start(httpAddr, dnsAddr string) error {
+	l.Domain = strings.ToLower(strings.Trim(l.Domain, "."))
+	if httpAddr != "" {
+		ln, err := net.Listen("tcp", httpAddr)
+		if err != nil {
+			return fmt.Errorf("oob http listen: %w", err)
+		}
+		l.httpL = ln
+		l.srv = &http.Server{Handler: l, ReadHeaderTimeout: 10 * time.Second}
+		go l.srv.Serve(ln)
+	}
+	if dnsAddr != "" {
+		if l.Domain == "" {
+			l.Close()
+			return fmt.Errorf("oob dns listener needs a domain")
+		}
+		pc, err := net.ListenPacket("udp", dnsAddr)
+		if err != nil {
+			l.Close()
+			return fmt.Errorf("oob dns listen: %w", err)
+		}
+		l.dnsC = pc
+		go l.serveDNS()
+	}
+	return nil
+}
+
+// HTTPAddr and DNSAddr return the bound addresses, useful with ":0".
+func (l *OOBListener) HTTPAddr() string {
+	if l.httpL == nil {
+		return ""
+	}
+	return l.httpL.Addr().String()
+}
+
+func (l *OOBListener) DNSAddr() string {
+	if l.dnsC == nil {
+		return ""
+	}
+	return l.dnsC.LocalAddr().String()
+}
+
+func (l *OOBListener) Close() {
+	if l.srv != nil {
+		l.srv.Close()
+	}
+	if l.dnsC != nil {
+		l.dnsC.Close()
+	}
+}
+
+func (l *OOBListener) record(h OOBHit) {
+	h.Time = time.Now()
+	l.mu.Lock()
+	l.hits = append(l.hits, h)
+	if len(l.hits) > oobMaxHits {
+		drop := len(l.hits) - oobMaxHits
+		l.hits = append([]OOBHit(nil), l.hits[drop:]...)
+		l.base += drop
+	}
+	l.mu.Unlock()
+	Info("[oob] %s hit from %s: %s", h.Protocol, h.Remote, h.Raw)
+}
+
+// HitsSince returns the hits recorded after cursor since and the next cursor.
+func (l *OOBListener) HitsSince(since int) ([]OOBHit, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i := since - l.base
+	if i < 0 {
+		i = 0
+	}
+	if i > len(l.hits) {
+		i = len(l.hits)
+	}
+	return append([]OOBHit(nil), l.hits[i:]...), l.base + len(l.hits)
+}
+
+type oobPollResponse struct {
+	Next int      `json:"next"`
+	Hits []OOBHit `json:"hits"`
+}
+
+func (l *OOBListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == oobPollPath {
+		if l.Secret == "" || r.URL.Query().Get("secret") != l.Secret {
+			http.NotFound(w, r)
+			return
+		}
+		since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+		hits, next := l.HitsSince(since)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(oobPollResponse{Next: next, Hits: hits})
+		return
+	}
+	host, _, _ := net.SplitHostPort(r.RemoteAddr)
+	l.record(OOBHit{Protocol: "http", Remote: host, Raw: r.Method + " " + r.Host + r.URL.RequestURI()})
+	io.Copy(io.Discard, io.LimitReader(r.Body, 64*1024))
+	w.Write([]byte("ok"))
+}
+
+func (l *OOBListener) serveDNS() {
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := l.dnsC.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		resp, name, ok := l.answerDNS(buf[:n])
+		if !ok {
+			continue
+		}
+		if name == l.Domain || strings.HasSuffix(name, "."+l.Domain) {
+			host, _, _ := net.SplitHostPort(addr.String())
+			l.record(OOBHit{Protocol: "dns", Remote: host, Raw: name})
+		}
+		l.dnsC.WriteTo(resp, addr)
+	}
+}
+
+// answerDNS builds the reply to a single-question query: an A record with l.IP for names under
+// the domain, an empty authoritative answer otherwise.
+func (l *OOBListener) answerDNS(q []byte) ([]byte, string, bool) {
+	if len(q) < 12 || binary.BigEndian.Uint16(q[4:]) != 1 {
+		return nil, "", false
+	}
+	var labels []string
+	pos := 12
+	for {
+		if pos >= len(q) {
+			return nil, "", false
+		}
+		n := int(q[pos])
+		pos++
+		if n == 0 {
+			break
+		}
+		if n > 63 || pos+n > len(q) {
+			return nil, "", false
+		}
+		labels = append(labels, string(q[pos:pos+n]))
+		pos += n
+	}
+	if pos+4 > len(q) {
+		return nil, "", false
+	}
+	qtype := binary.BigEndian.Uint16(q[pos:])
+	question := q[12 : pos+4]
+	name := strings.ToLower(strings.Join(labels, "."))
+
+	resp := make([]byte, 12, 12+len(question)+16)
+	copy(resp, q[:2])
+	flags := uint16(0x8400) | binary.BigEndian.Uint16(q[2:])&0x0100 // QR, AA, copy RD
+	binary.BigEndian.PutUint16(resp[2:], flags)
+	binary.BigEndian.PutUint16(resp[4:], 1)
+	resp = append(resp, question...)
+	ip := net.ParseIP(l.IP).To4()
+	under := name == l.Domain || strings.HasSuffix(name, "."+l.Domain)
+	if under && qtype == 1 && ip != nil {
+		binary.BigEndian.PutUint16(resp[6:], 1)
+		resp = append(resp, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4)
+		resp = append(resp, ip...)
+	}
+	return resp, name, true
+}
+
+// OOBProbe describes the request a token was issued for.
+type OOBProbe struct {
+	TemplateID string
+	Title      string
+	Severity   string
+	Target     string
+	URL        string
+}
+
+// OOBClient issues per-request tokens and turns listener hits carrying them into findings. It reads
+// hits from an embedded listener, or polls a remote one (godscan oob on a VPS).
+type OOBClient struct {
+	// Domain receives dns callbacks as <token>.<Domain>; HTTPBase receives <HTTPBase>/<token>.
+	Domain   string
+	HTTPBase string
+
+	listener *OOBListener
+	pollURL  string
+	secret   string
+
+	mu     sync.Mutex
+	probes map[string]*OOBProbe
+	cursor int
+	db     *sql.DB
+}
+
+// NewOOBClient correlates hits of an in-process listener.
+func NewOOBClient(l *OOBListener, httpBase string, db *sql.DB) *OOBClient {
+	return &OOBClient{Domain: l.Domain, HTTPBase: strings.TrimSuffix(httpBase, "/"), listener: l, probes: make(map[string]*OOBProbe), db: db}
+}
+
+// NewRemoteOOBClient correlates hits polled from a listener started elsewhere with the same secret.
+func NewRemoteOOBClient(server, secret, domain string, db *sql.DB) *OOBClient {
+	server = strings.TrimSuffix(server, "/")
+	return &OOBClient{Domain: strings.ToLower(strings.Trim(domain, ".")), HTTPBase: server, pollURL: server + oobPollPath, secret: secret, probes: make(map[string]*OOBProbe), db: db}
+}
+
+// NewToken registers a probe and returns its unique token.
+func (c *OOBClient) NewToken(p OOBProbe) string {
+	b := make([]byte, oobTokenLen)
+	n := big.NewInt(int64(len(oobTokenChar)))
+	for i := range b {
+		v, _ := rand.Int(rand.Reader, n)
+		b[i] = oobTokenChar[v.Int64()]
+	}
+	token := string(b)
+	c.mu.Lock()
+	c.probes[token] = &p
+	c.mu.Unlock()
+	return token
+}
+
+// SetProbeURL records the final request url once the request carrying token is built.
+func (c *OOBClient) SetProbeURL(token, u string) {
+	c.mu.Lock()
+	if p, ok := c.probes[token]; ok {
+		p.URL = u
+	}
+	c.mu.Unlock()
+}
+
+// DNSName and URL are the callback addresses of token; empty when that protocol is not set up.
+func (c *OOBClient) DNSName(token string) string {
+	if c.Domain == "" {
+		return ""
+	}
+	return token + "." + c.Domain
+}
+
+func (c *OOBClient) URL(token string) string {
+	if c.HTTPBase == "" {
+		return ""
+	}
+	return c.HTTPBase + "/" + token
+}
+
+// Poll fetches the hits received since the previous call, stores those carrying a known token as
+// findings and returns them.
+func (c *OOBClient) Poll() ([]Finding, error) {
+	var hits []OOBHit
+	if c.listener != nil {
+		hits, c.cursor = c.listener.HitsSince(c.cursor)
+	} else {
+		resp, err := fetchGet(c.pollURL + "?secret=" + url.QueryEscape(c.secret) + "&since=" + strconv.Itoa(c.cursor))
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("oob poll: status %d", resp.StatusCode)
+		}
+		var pr oobPollResponse
+		if err := json.NewDecoder(resp.Body).Decode(&pr); err != nil {
+			return nil, fmt.Errorf("oob poll: %w", err)
+		}
+		hits, c.cursor = pr.Hits, pr.Next
+	}
+	findings := c.correlate(hits)
+	if err := SaveFindings(c.db, findings); err != nil {
+		Error("save oob findings failed: %v", err)
+	}
+	return findings, nil
+}
+
+func (c *OOBClient) correlate(hits []OOBHit) []Finding {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []Finding
+	for _, h := range hits {
+		for _, token := range strings.FieldsFunc(strings.ToLower(h.Raw), oobTokenSep) {
+			p, ok := c.probes[token]
+			if !ok {
+				continue
+			}
+			u := p.URL
+			if u == "" {
+				u = p.Target
+			}
+			f := Finding{
+				RootURL:    apiDocRoot(u),
+				URL:        u,
+				Module:     "oob",
+				Title:      p.Title,
+				Severity:   p.Severity,
+				Detail:     fmt.Sprintf("%s interaction from %s", h.Protocol, h.Remote),
+				TemplateID: p.TemplateID,
+				Evidence:   fmt.Sprintf("%s %s from %s at %s (token %s)", h.Protocol, h.Raw, h.Remote, h.Time.Format(time.RFC3339), token),
+			}
+			Success("[oob] [%s] %s %s <- %s %s", colorSeverity(f.Severity), f.TemplateID, f.URL, h.Protocol, h.Remote)
+			out = append(out, f)
+			break
+		}
+	}
+	return out
+}
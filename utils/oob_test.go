@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"encoding/binary"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testOOBTemplate = `
+id: demo-ssrf-oob
+info:
+  name: Demo blind SSRF
+  severity: high
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/fetch?url={{oob_url}}"
+      - "{{BaseURL}}/resolve?host={{interactsh-url}}"
+`
+
+func dnsQuery(name string) []byte {
+	q := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	for _, label := range strings.Split(name, ".") {
+		q = append(q, byte(len(label)))
+		q = append(q, label...)
+	}
+	return append(q, 0, 0, 1, 0, 1)
+}
+
+func TestOOBCorrelation(t *testing.T) {
+	l := &OOBListener{Domain: "OOB.Example.com.", IP: "10.1.2.3", Secret: "s3"}
+	if err := l.Start("127.0.0.1:0", "127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	db, err := InitSpiderDB(filepath.Join(t.TempDir(), "spider.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	oob := NewOOBClient(l, "http://"+l.HTTPAddr(), db)
+	SetPocOOB(oob)
+	defer SetPocOOB(nil)
+
+	// a "vulnerable" target fetching urls and resolving hosts it is given
+	srv := mustTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fetch":
+			if resp, err := http.Get(r.URL.Query().Get("url")); err == nil {
+				resp.Body.Close()
+			}
+		case "/resolve":
+			c, err := net.Dial("udp", l.DNSAddr())
+			if err != nil {
+				return
+			}
+			defer c.Close()
+			c.Write(dnsQuery(strings.ToUpper(r.URL.Query().Get("host"))))
+			c.SetReadDeadline(time.Now().Add(2 * time.Second))
+			buf := make([]byte, 512)
+			n, err := c.Read(buf)
+			// one A answer carrying --oob-ip
+			if err != nil || n < 4 || binary.BigEndian.Uint16(buf[6:]) != 1 || net.IP(buf[n-4:n]).String() != "10.1.2.3" {
+				http.Error(w, "bad dns answer", http.StatusInternalServerError)
+			}
+		}
+	}))
+	defer srv.Close()
+	oldClient, oldNoRedirect := Client, ClientNoRedirect
+	Client, ClientNoRedirect = srv.Client(), srv.Client()
+	defer func() { Client, ClientNoRedirect = oldClient, oldNoRedirect }()
+
+	tpl, err := ParsePocTemplate([]byte(testOOBTemplate), "oob.yaml")
+	if err != nil || !tpl.OOB {
+		t.Fatalf("parse: %v %+v", err, tpl)
+	}
+	if _, err := RunPocTemplate(tpl, srv.URL, ""); err != nil {
+		t.Fatal(err)
+	}
+	// unrelated noise is recorded but not correlated
+	if resp, err := http.Get("http://" + l.HTTPAddr() + "/favicon.ico"); err == nil {
+		resp.Body.Close()
+	}
+
+	findings, err := oob.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]Finding{}
+	for _, f := range findings {
+		got[f.Detail[:strings.Index(f.Detail, " ")]] = f
+	}
+	if len(findings) != 2 || got["http"].URL == "" || got["dns"].URL == "" {
+		t.Fatalf("want one http and one dns finding, got %+v", findings)
+	}
+	if f := got["dns"]; f.TemplateID != "demo-ssrf-oob" || f.Module != "oob" || f.Severity != SeverityHigh ||
+		!strings.HasPrefix(f.URL, srv.URL+"/resolve?host=") || !strings.Contains(f.Evidence, ".oob.example.com") {
+		t.Fatalf("unexpected dns finding %+v", f)
+	}
+	if !strings.HasPrefix(got["http"].URL, srv.URL+"/fetch?url=") || got["http"].URL == got["dns"].URL {
+		t.Fatalf("unexpected http finding %+v", got["http"])
+	}
+	if again, _ := oob.Poll(); len(again) != 0 {
+		t.Fatalf("hits correlated twice: %+v", again)
+	}
+	saved, _ := LoadFindings(db)
+	if len(saved) != 2 {
+		t.Fatalf("want 2 stored findings, got %+v", saved)
+	}
+
+	// a remote client sees the same hits through the poll endpoint
+	remote := NewRemoteOOBClient("http://"+l.HTTPAddr(), "s3", l.Domain, nil)
+	remote.probes = oob.probes
+	Client = http.DefaultClient
+	if res, err := remote.Poll(); err != nil || len(res) != 2 {
+		t.Fatalf("remote poll: %v %+v", err, res)
+	}
+	if _, err := NewRemoteOOBClient("http://"+l.HTTPAddr(), "wrong", "", nil).Poll(); err == nil {
+		t.Fatal("poll with a wrong secret succeeded")
+	}
+}
+
+func TestOOBCorrelateTokenBoundaries(t *testing.T) {
+	oob := NewRemoteOOBClient("http://oob.example.com", "s", "oob.example.com", nil)
+	dns := oob.NewToken(OOBProbe{Target: "http://a/", Title: "dns"})
+	web := oob.NewToken(OOBProbe{Target: "http://b/", Title: "http"})
+	if len(dns) != oobTokenLen || strings.Trim(dns, oobTokenChar) != "" || dns == web {
+		t.Fatalf("bad tokens %q %q", dns, web)
+	}
+	hits := []OOBHit{
+		// the token only matches as a whole label or path segment, not at any 16-char window
+		{Protocol: "dns", Raw: "xx" + dns + ".oob.example.com"},
+		{Protocol: "http", Raw: "GET oob.example.com/a" + web},
+		{Protocol: "dns", Raw: "x1." + strings.ToUpper(dns) + ".oob.example.com"},
+		{Protocol: "http", Raw: "GET oob.example.com/cb?id=" + web + "&x=1"},
+	}
+	got := oob.correlate(hits)
+	if len(got) != 2 || got[0].Title != "dns" || got[1].Title != "http" {
+		t.Fatalf("unexpected findings %+v", got)
+	}
+}
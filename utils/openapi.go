@@ -0,0 +1,370 @@
+package utils
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const apiDocMaxBytes = 16 * 1024 * 1024
+
+var apiDocSeen sync.Map
+
+var apiDocMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// APIParam is one operation parameter; request bodies use In "body" with the media type as Type.
+type APIParam struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required,omitempty"`
+	Type     string `json:"type,omitempty"`
+}
+
+// APIEndpointRow is one method+path from a Swagger 2.0 / OpenAPI 3.x document.
+type APIEndpointRow struct {
+	RootURL  string     `json:"root_url"`
+	DocURL   string     `json:"doc_url"`
+	Method   string     `json:"method"`
+	Path     string     `json:"path"`
+	Summary  string     `json:"summary"`
+	Params   []APIParam `json:"params"`
+	Security string     `json:"security"`
+}
+
+// apiDoc is the subset of Swagger 2.0 / OpenAPI 3.x shared by both versions.
+type apiDoc struct {
+	Swagger  string                                `json:"swagger"`
+	OpenAPI  string                                `json:"openapi"`
+	BasePath string                                `json:"basePath"`
+	Servers  []struct{ URL string }                `json:"servers"`
+	Paths    map[string]map[string]json.RawMessage `json:"paths"`
+	Security []map[string][]string                 `json:"security"`
+	// Swagger 2.0 keeps shared parameters at the top level, OpenAPI 3 under components.
+	Parameters map[string]apiDocParam `json:"parameters"`
+	Components struct {
+		Parameters map[string]apiDocParam `json:"parameters"`
+	} `json:"components"`
+}
+
+type apiDocParam struct {
+	Ref      string `json:"$ref"`
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+	Type     string `json:"type"`
+	Schema   struct {
+		Type string `json:"type"`
+	} `json:"schema"`
+}
+
+type apiDocOperation struct {
+	Summary     string                 `json:"summary"`
+	OperationID string                 `json:"operationId"`
+	Parameters  []apiDocParam          `json:"parameters"`
+	Consumes    []string               `json:"consumes"`
+	Security    *[]map[string][]string `json:"security"`
+	RequestBody *struct {
+		Ref      string                     `json:"$ref"`
+		Required bool                       `json:"required"`
+		Content  map[string]json.RawMessage `json:"content"`
+	} `json:"requestBody"`
+}
+
+func looksLikeJSON(body []byte) bool {
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// isAPIDoc reports whether body looks like a Swagger 2.0 or OpenAPI 3.x document.
+func isAPIDoc(body []byte) bool {
+	var probe struct {
+		Swagger string          `json:"swagger"`
+		OpenAPI string          `json:"openapi"`
+		Paths   json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return false
+	}
+	return (probe.Swagger != "" || probe.OpenAPI != "") && len(probe.Paths) > 0
+}
+
+// ParseAPIDoc lists every operation of a Swagger 2.0 / OpenAPI 3.x document.
+// Paths are prefixed with basePath (2.0) or the path of the first server (3.x).
+func ParseAPIDoc(body []byte) ([]APIEndpointRow, error) {
+	var doc apiDoc
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Swagger == "" && doc.OpenAPI == "" {
+		return nil, fmt.Errorf("not a swagger/openapi document")
+	}
+	prefix := doc.BasePath
+	if doc.OpenAPI != "" && len(doc.Servers) > 0 {
+		if u, err := url.Parse(doc.Servers[0].URL); err == nil {
+			prefix = u.Path
+		}
+	}
+	prefix = strings.TrimRight(prefix, "/")
+	shared := doc.Parameters
+	if doc.OpenAPI != "" {
+		shared = doc.Components.Parameters
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var out []APIEndpointRow
+	for _, p := range paths {
+		item := doc.Paths[p]
+		var pathParams []apiDocParam
+		if raw, ok := item["parameters"]; ok {
+			_ = json.Unmarshal(raw, &pathParams)
+		}
+		for _, method := range apiDocMethods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			var op apiDocOperation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				Debug("openapi: skip %s %s: %v", method, p, err)
+				continue
+			}
+			summary := op.Summary
+			if summary == "" {
+				summary = op.OperationID
+			}
+			security := doc.Security
+			if op.Security != nil {
+				security = *op.Security
+			}
+			out = append(out, APIEndpointRow{
+				Method:   strings.ToUpper(method),
+				Path:     prefix + "/" + strings.TrimLeft(p, "/"),
+				Summary:  summary,
+				Params:   apiDocParams(append(append([]apiDocParam{}, pathParams...), op.Parameters...), shared, op),
+				Security: apiDocSecurity(security),
+			})
+		}
+	}
+	return out, nil
+}
+
+func apiDocParams(params []apiDocParam, shared map[string]apiDocParam, op apiDocOperation) []APIParam {
+	var out []APIParam
+	seen := make(map[string]int)
+	for _, p := range params {
+		if p.Ref != "" {
+			name := p.Ref[strings.LastIndex(p.Ref, "/")+1:]
+			resolved, ok := shared[name]
+			if !ok {
+				continue
+			}
+			p = resolved
+		}
+		if p.Name == "" {
+			continue
+		}
+		typ := p.Type
+		if typ == "" {
+			typ = p.Schema.Type
+		}
+		if p.In == "body" && len(op.Consumes) > 0 {
+			typ = op.Consumes[0]
+		}
+		param := APIParam{Name: p.Name, In: p.In, Required: p.Required || p.In == "path", Type: typ}
+		// operation-level parameters override path-level ones with the same name and location
+		key := p.In + ":" + p.Name
+		if i, ok := seen[key]; ok {
+			out[i] = param
+			continue
+		}
+		seen[key] = len(out)
+		out = append(out, param)
+	}
+	if op.RequestBody != nil {
+		mediaTypes := make([]string, 0, len(op.RequestBody.Content))
+		for mt := range op.RequestBody.Content {
+			mediaTypes = append(mediaTypes, mt)
+		}
+		sort.Strings(mediaTypes)
+		typ := strings.Join(mediaTypes, ",")
+		out = append(out, APIParam{Name: "body", In: "body", Required: op.RequestBody.Required, Type: typ})
+	}
+	return out
+}
+
+// apiDocSecurity renders requirements as "a+b|c" (alternatives separated by |); "" means none.
+func apiDocSecurity(reqs []map[string][]string) string {
+	var alts []string
+	for _, req := range reqs {
+		if len(req) == 0 {
+			// an empty requirement object makes authentication optional
+			return ""
+		}
+		names := make([]string, 0, len(req))
+		for name := range req {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		alts = append(alts, strings.Join(names, "+"))
+	}
+	return strings.Join(alts, "|")
+}
+
+// apiDocIndexURLs resolves a springfox swagger-resources list or a springdoc swagger-config
+// into absolute document urls. Entries are relative to the application context, i.e. the
+// directory holding the index. Only urls ending in one of the two index names are read, and
+// only entries on the index's own host are returned.
+func apiDocIndexURLs(indexURL string, body []byte) []string {
+	base, err := url.Parse(indexURL)
+	if err != nil {
+		return nil
+	}
+	indexPath := strings.TrimRight(base.Path, "/")
+	if !strings.HasSuffix(indexPath, "swagger-resources") && !strings.HasSuffix(indexPath, "swagger-config") {
+		return nil
+	}
+	type resource struct {
+		URL      string `json:"url"`
+		Location string `json:"location"`
+	}
+	var resources []resource
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &resources); err != nil {
+			return nil
+		}
+	} else {
+		var config struct {
+			URL  string     `json:"url"`
+			URLs []resource `json:"urls"`
+		}
+		if err := json.Unmarshal(trimmed, &config); err != nil {
+			return nil
+		}
+		resources = config.URLs
+		if config.URL != "" {
+			resources = append(resources, resource{URL: config.URL})
+		}
+	}
+	contextPath := base.Path
+	for _, suffix := range []string{"/swagger-resources", "/v3/api-docs/swagger-config"} {
+		if strings.HasSuffix(indexPath, suffix) {
+			contextPath = strings.TrimSuffix(indexPath, suffix)
+			break
+		}
+	}
+	var out []string
+	for _, r := range resources {
+		ref := r.URL
+		if ref == "" {
+			ref = r.Location
+		}
+		if ref == "" {
+			continue
+		}
+		target, err := url.Parse(ref)
+		if err != nil {
+			continue
+		}
+		if !target.IsAbs() {
+			if strings.HasPrefix(target.Path, "/") {
+				target.Path = contextPath + target.Path
+			}
+			target = base.ResolveReference(target)
+		} else if !strings.EqualFold(target.Host, base.Host) {
+			Debug("openapi: %s lists off-host document %s, skipped", indexURL, ref)
+			continue
+		}
+		out = append(out, target.String())
+	}
+	return RemoveDuplicatesString(out)
+}
+
+// IngestAPIDocs stores the endpoints of a Swagger/OpenAPI document found at docURL, or
+// follows a swagger-resources / swagger-config index to the per-group documents.
+// Each document url is processed once per run; it returns the number of endpoints stored.
+// A body that is not valid JSON and fills the max-body-bytes cap was cut short by FingerScan,
+// it is fetched again under the larger apiDocMaxBytes cap.
+func IngestAPIDocs(db *sql.DB, rootURL, docURL string, body []byte) int {
+	if _, loaded := apiDocSeen.LoadOrStore(docURL, struct{}{}); loaded {
+		return 0
+	}
+	if len(body) >= responseBodyCap() && len(body) < apiDocMaxBytes && !json.Valid(body) {
+		Debug("openapi: %s truncated at %d bytes, fetching it again", docURL, len(body))
+		full, err := fetchAPIDoc(docURL)
+		if err != nil {
+			Debug("openapi: fetch %s failed: %v", docURL, err)
+			return 0
+		}
+		body = full
+	}
+	if isAPIDoc(body) {
+		rows, err := ParseAPIDoc(body)
+		if err != nil {
+			Debug("openapi: parse %s failed: %v", docURL, err)
+			return 0
+		}
+		for i := range rows {
+			rows[i].RootURL = rootURL
+			rows[i].DocURL = docURL
+		}
+		if err := SaveAPIEndpoints(db, rows); err != nil {
+			Error("openapi: save %s failed: %v", docURL, err)
+			return 0
+		}
+		if len(rows) > 0 {
+			Info("openapi: %s -> %d endpoint(s)", docURL, len(rows))
+		}
+		return len(rows)
+	}
+	total := 0
+	for _, u := range apiDocIndexURLs(docURL, body) {
+		if _, seen := apiDocSeen.Load(u); seen {
+			continue
+		}
+		doc, err := fetchAPIDoc(u)
+		if err != nil {
+			Debug("openapi: fetch %s failed: %v", u, err)
+			continue
+		}
+		total += IngestAPIDocs(db, rootURL, u, doc)
+	}
+	return total
+}
+
+func fetchAPIDoc(docURL string) ([]byte, error) {
+	resp, err := fetchGet(docURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, apiDocMaxBytes+1))
+	if len(body) > apiDocMaxBytes {
+		Info("openapi: %s is larger than %d bytes, truncated", docURL, apiDocMaxBytes)
+		body = body[:apiDocMaxBytes]
+	}
+	return body, err
+}
+
+// apiDocRoot returns scheme://host of a document url.
+func apiDocRoot(docURL string) string {
+	u, err := url.Parse(docURL)
+	if err != nil {
+		return docURL
+	}
+	return u.Scheme + "://" + u.Host
+}
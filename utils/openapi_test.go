@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const testSwagger2 = `{
+  "swagger": "2.0",
+  "basePath": "/api",
+  "security": [{"api_key": []}],
+  "parameters": {"pageSize": {"name": "size", "in": "query", "type": "integer"}},
+  "paths": {
+    "/users/{id}": {
+      "parameters": [{"name": "id", "in": "path", "type": "string"}],
+      "get": {"summary": "get user", "parameters": [{"$ref": "#/parameters/pageSize"}]},
+      "post": {"operationId": "updateUser", "consumes": ["application/json"], "security": [],
+               "parameters": [{"name": "user", "in": "body", "schema": {"type": "object"}}]}
+    }
+  }
+}`
+
+const testOpenAPI3 = `{
+  "openapi": "3.0.1",
+  "servers": [{"url": "http://internal:8080/v1/"}],
+  "paths": {
+    "/login": {
+      "post": {"summary": "login", "security": [{"oauth": ["read"], "key": []}, {"basic": []}],
+               "requestBody": {"required": true, "content": {"application/json": {}, "application/x-www-form-urlencoded": {}}}}
+    }
+  }
+}`
+
+func TestParseAPIDoc(t *testing.T) {
+	rows, err := ParseAPIDoc([]byte(testSwagger2))
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("swagger2 rows=%+v err=%v", rows, err)
+	}
+	get, post := rows[0], rows[1]
+	if get.Method != "GET" || get.Path != "/api/users/{id}" || get.Summary != "get user" || get.Security != "api_key" {
+		t.Fatalf("get = %+v", get)
+	}
+	if len(get.Params) != 2 || get.Params[0] != (APIParam{Name: "id", In: "path", Required: true, Type: "string"}) || get.Params[1].Name != "size" {
+		t.Fatalf("get params = %+v", get.Params)
+	}
+	if post.Security != "" || post.Summary != "updateUser" || post.Params[1] != (APIParam{Name: "user", In: "body", Type: "application/json"}) {
+		t.Fatalf("post = %+v", post)
+	}
+
+	rows, err = ParseAPIDoc([]byte(testOpenAPI3))
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("openapi3 rows=%+v err=%v", rows, err)
+	}
+	login := rows[0]
+	if login.Path != "/v1/login" || login.Security != "key+oauth|basic" {
+		t.Fatalf("login = %+v", login)
+	}
+	if len(login.Params) != 1 || login.Params[0] != (APIParam{Name: "body", In: "body", Required: true, Type: "application/json,application/x-www-form-urlencoded"}) {
+		t.Fatalf("login params = %+v", login.Params)
+	}
+}
+
+func TestIngestSwaggerResources(t *testing.T) {
+	srv := mustTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ctx/swagger-resources":
+			_, _ = w.Write([]byte(`[{"name":"default","url":"/v2/api-docs?group=default","swaggerVersion":"2.0"},{"name":"admin","location":"/v3/api-docs/admin"}]`))
+		case "/ctx/v2/api-docs":
+			if r.URL.Query().Get("group") != "default" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(testSwagger2))
+		case "/ctx/v3/api-docs/admin":
+			_, _ = w.Write([]byte(testOpenAPI3))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	oldClient, oldNoRedirect := Client, ClientNoRedirect
+	Client, ClientNoRedirect = srv.Client(), srv.Client()
+	defer func() {
+		Client, ClientNoRedirect = oldClient, oldNoRedirect
+	}()
+
+	db, err := InitSpiderDB(filepath.Join(t.TempDir(), "spider.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	defer db.Close()
+
+	indexURL := srv.URL + "/ctx/swagger-resources"
+	body, err := fetchAPIDoc(indexURL)
+	if err != nil {
+		t.Fatalf("fetch index: %v", err)
+	}
+	if n := IngestAPIDocs(db, srv.URL, indexURL, body); n != 3 {
+		t.Fatalf("ingested %d endpoints, want 3", n)
+	}
+	rows, err := LoadAPIEndpoints(db)
+	if err != nil || len(rows) != 3 {
+		t.Fatalf("rows=%+v err=%v", rows, err)
+	}
+	for _, r := range rows {
+		if r.RootURL != srv.URL || r.DocURL == indexURL || len(r.Params) == 0 {
+			t.Fatalf("unexpected row %+v", r)
+		}
+	}
+}
+
+func TestAPIDocIndexURLsScope(t *testing.T) {
+	body := []byte(`{"url":"/v3/api-docs","urls":[{"url":"http://evil.example/v3/api-docs"},{"url":"HTTP://App.Example/v3/api-docs/admin"}]}`)
+	if got := apiDocIndexURLs("http://app.example/data/items.json", body); len(got) != 0 {
+		t.Fatalf("non-index url followed: %v", got)
+	}
+	got := apiDocIndexURLs("http://app.example/ctx/v3/api-docs/swagger-config", body)
+	if len(got) != 2 || got[0] != "http://App.Example/v3/api-docs/admin" || got[1] != "http://app.example/ctx/v3/api-docs" {
+		t.Fatalf("swagger-config urls = %v", got)
+	}
+	for _, u := range got {
+		if strings.Contains(u, "evil") {
+			t.Fatalf("off-host url followed: %v", got)
+		}
+	}
+}
+
+func TestIngestTruncatedAPIDoc(t *testing.T) {
+	srv := mustTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testSwagger2))
+	}))
+	defer srv.Close()
+
+	oldClient, oldNoRedirect := Client, ClientNoRedirect
+	Client, ClientNoRedirect = srv.Client(), srv.Client()
+	defer func() {
+		Client, ClientNoRedirect = oldClient, oldNoRedirect
+	}()
+	oldMax := viper.Get("max-body-bytes")
+	viper.Set("max-body-bytes", 64)
+	t.Cleanup(func() { viper.Set("max-body-bytes", oldMax) })
+
+	db, err := InitSpiderDB(filepath.Join(t.TempDir(), "spider.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	defer db.Close()
+
+	docURL := srv.URL + "/v2/api-docs"
+	if n := IngestAPIDocs(db, srv.URL, docURL, []byte(testSwagger2)[:64]); n != 2 {
+		t.Fatalf("ingested %d endpoints from a truncated body, want 2", n)
+	}
+}
@@ -0,0 +1,825 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"embed"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed pocs
+var builtinPocs embed.FS
+
+// PocTemplate is a nuclei-style YAML check: http request blocks with matchers and extractors.
+type PocTemplate struct {
+	ID   string `yaml:"id"`
+	Info struct {
+		Name        string `yaml:"name"`
+		Author      string `yaml:"author"`
+		Severity    string `yaml:"severity"`
+		Description string `yaml:"description"`
+		Tags        string `yaml:"tags"`
+	} `yaml:"info"`
+	// Variables are rendered once per target and usable as {{name}} in every request.
+	Variables map[string]string `yaml:"variables"`
+	HTTP      []*PocRequest     `yaml:"http"`
+	// Requests is the older nuclei name of HTTP.
+	Requests []*PocRequest `yaml:"requests"`
+	// Source is the file the template came from, "builtin/<name>" for embedded ones.
+	Source string `yaml:"-"`
+	// OOB is set when the template uses {{interactsh-url}}, {{oob_domain}} or {{oob_url}}.
+	OOB bool `yaml:"-"`
+}
+
+type PocRequest struct {
+	Method  string            `yaml:"method"`
+	Path    []string          `yaml:"path"`
+	Raw     []string          `yaml:"raw"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	// Redirects follows 3xx answers; off by default like nuclei.
+	Redirects         bool            `yaml:"redirects"`
+	StopAtFirstMatch  bool            `yaml:"stop-at-first-match"`
+	MatchersCondition string          `yaml:"matchers-condition"`
+	Matchers          []*PocMatcher   `yaml:"matchers"`
+	Extractors        []*PocExtractor `yaml:"extractors"`
+}
+
+type PocMatcher struct {
+	Type            string   `yaml:"type"`
+	Name            string   `yaml:"name"`
+	Part            string   `yaml:"part"`
+	Condition       string   `yaml:"condition"`
+	Negative        bool     `yaml:"negative"`
+	CaseInsensitive bool     `yaml:"case-insensitive"`
+	Status          []int    `yaml:"status"`
+	Words           []string `yaml:"words"`
+	Regex           []string `yaml:"regex"`
+	DSL             []string `yaml:"dsl"`
+
+	regex []*regexp.Regexp
+	dsl   []*govaluate.EvaluableExpression
+}
+
+type PocExtractor struct {
+	Type     string   `yaml:"type"`
+	Name     string   `yaml:"name"`
+	Part     string   `yaml:"part"`
+	Group    int      `yaml:"group"`
+	Regex    []string `yaml:"regex"`
+	KVal     []string `yaml:"kval"`
+	DSL      []string `yaml:"dsl"`
+	Internal bool     `yaml:"internal"`
+
+	regex []*regexp.Regexp
+	dsl   []*govaluate.EvaluableExpression
+}
+
+var pocPlaceholderRe = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// pocFunctions are the helpers usable in DSL matchers and {{...}} placeholders.
+var pocFunctions = map[string]govaluate.ExpressionFunction{
+	"contains": func(args ...any) (any, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("contains(s, sub)")
+		}
+		return strings.Contains(fmt.Sprint(args[0]), fmt.Sprint(args[1])), nil
+	},
+	"icontains": func(args ...any) (any, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("icontains(s, sub)")
+		}
+		return strings.Contains(strings.ToLower(fmt.Sprint(args[0])), strings.ToLower(fmt.Sprint(args[1]))), nil
+	},
+	"contains_all": func(args ...any) (any, error) {
+		if len(args) < 2 {
+			return nil, fmt.Errorf("contains_all(s, sub...)")
+		}
+		for _, a := range args[1:] {
+			if !strings.Contains(fmt.Sprint(args[0]), fmt.Sprint(a)) {
+				return false, nil
+			}
+		}
+		return true, nil
+	},
+	"contains_any": func(args ...any) (any, error) {
+		if len(args) < 2 {
+			return nil, fmt.Errorf("contains_any(s, sub...)")
+		}
+		for _, a := range args[1:] {
+			if strings.Contains(fmt.Sprint(args[0]), fmt.Sprint(a)) {
+				return true, nil
+			}
+		}
+		return false, nil
+	},
+	"regex": func(args ...any) (any, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("regex(pattern, s)")
+		}
+		re, err := regexp.Compile(fmt.Sprint(args[0]))
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(fmt.Sprint(args[1])), nil
+	},
+	"len":        pocStringFunc(func(s string) any { return float64(len(s)) }),
+	"to_lower":   pocStringFunc(func(s string) any { return strings.ToLower(s) }),
+	"to_upper":   pocStringFunc(func(s string) any { return strings.ToUpper(s) }),
+	"trim_space": pocStringFunc(func(s string) any { return strings.TrimSpace(s) }),
+	"base64":     pocStringFunc(func(s string) any { return base64.StdEncoding.EncodeToString([]byte(s)) }),
+	"url_encode": pocStringFunc(func(s string) any { return url.QueryEscape(s) }),
+	"hex_encode": pocStringFunc(func(s string) any { return hex.EncodeToString([]byte(s)) }),
+	"md5":        pocStringFunc(func(s string) any { return fmt.Sprintf("%x", md5.Sum([]byte(s))) }),
+	"sha1":       pocStringFunc(func(s string) any { return fmt.Sprintf("%x", sha1.Sum([]byte(s))) }),
+	"sha256":     pocStringFunc(func(s string) any { return fmt.Sprintf("%x", sha256.Sum256([]byte(s))) }),
+	"base64_decode": pocStringFunc(func(s string) any {
+		b, _ := base64.StdEncoding.DecodeString(s)
+		return string(b)
+	}),
+	"url_decode": pocStringFunc(func(s string) any {
+		d, _ := url.QueryUnescape(s)
+		return d
+	}),
+	"rand_text_alpha": func(args ...any) (any, error) {
+		n := 8
+		if len(args) == 1 {
+			if f, ok := args[0].(float64); ok {
+				n = int(f)
+			}
+		}
+		return pocRandText(n), nil
+	},
+}
+
+func pocStringFunc(fn func(string) any) govaluate.ExpressionFunction {
+	return func(args ...any) (any, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expects one argument")
+		}
+		return fn(fmt.Sprint(args[0])), nil
+	}
+}
+
+func pocRandText(n int) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[rand.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+// LoadPocTemplates loads the embedded templates plus user YAML files (or directories of
+// *.yaml/*.yml, walked recursively). A user template replaces the built-in one with the same id.
+func LoadPocTemplates(paths []string) ([]*PocTemplate, error) {
+	var out []*PocTemplate
+	index := make(map[string]int)
+	add := func(t *PocTemplate) {
+		if i, ok := index[t.ID]; ok {
+			out[i] = t
+			return
+		}
+		index[t.ID] = len(out)
+		out = append(out, t)
+	}
+	err := fs.WalkDir(builtinPocs, "pocs", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := builtinPocs.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		t, err := ParsePocTemplate(data, "builtin/"+d.Name())
+		if err != nil {
+			return err
+		}
+		add(t)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, root := range paths {
+		root = strings.TrimSpace(root)
+		if root == "" {
+			continue
+		}
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || (p != root && !isPocFile(p)) {
+				return nil
+			}
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			t, err := ParsePocTemplate(data, p)
+			if err != nil {
+				return err
+			}
+			add(t)
+			return nil
+		})
+		if err != nil {
+			return out, fmt.Errorf("poc templates %s: %w", root, err)
+		}
+	}
+	return out, nil
+}
+
+func isPocFile(p string) bool {
+	ext := strings.ToLower(filepath.Ext(p))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// ParsePocTemplate decodes one YAML template and compiles its regexes and DSL expressions.
+func ParsePocTemplate(data []byte, source string) (*PocTemplate, error) {
+	var t PocTemplate
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	t.Source = source
+	t.OOB = pocOOBRe.Match(data)
+	t.HTTP = append(t.HTTP, t.Requests...)
+	t.Requests = nil
+	if strings.TrimSpace(t.ID) == "" {
+		return nil, fmt.Errorf("%s: empty id", source)
+	}
+	if len(t.HTTP) == 0 {
+		return nil, fmt.Errorf("%s: no http requests", source)
+	}
+	if t.Info.Severity == "" {
+		t.Info.Severity = SeverityInfo
+	}
+	t.Info.Severity = strings.ToLower(t.Info.Severity)
+	if _, ok := severityRank[t.Info.Severity]; !ok {
+		return nil, fmt.Errorf("%s: unknown severity %q", source, t.Info.Severity)
+	}
+	for i, r := range t.HTTP {
+		if len(r.Path) == 0 && len(r.Raw) == 0 {
+			return nil, fmt.Errorf("%s: request #%d has neither path nor raw", source, i)
+		}
+		if r.Method == "" {
+			r.Method = http.MethodGet
+		}
+		for _, m := range r.Matchers {
+			if err := m.compile(); err != nil {
+				return nil, fmt.Errorf("%s: request #%d: %w", source, i, err)
+			}
+		}
+		for _, e := range r.Extractors {
+			if err := e.compile(); err != nil {
+				return nil, fmt.Errorf("%s: request #%d: %w", source, i, err)
+			}
+		}
+	}
+	return &t, nil
+}
+
+func compilePocRegexes(exprs []string, caseInsensitive bool) ([]*regexp.Regexp, error) {
+	var out []*regexp.Regexp
+	for _, e := range exprs {
+		if caseInsensitive {
+			e = "(?i)" + e
+		}
+		re, err := regexp.Compile(e)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+func compilePocDSL(exprs []string) ([]*govaluate.EvaluableExpression, error) {
+	var out []*govaluate.EvaluableExpression
+	for _, e := range exprs {
+		ev, err := govaluate.NewEvaluableExpressionWithFunctions(e, pocFunctions)
+		if err != nil {
+			return nil, fmt.Errorf("dsl %q: %w", e, err)
+		}
+		out = append(out, ev)
+	}
+	return out, nil
+}
+
+func (m *PocMatcher) compile() error {
+	var err error
+	switch m.Type {
+	case "status", "word":
+	case "regex":
+		m.regex, err = compilePocRegexes(m.Regex, m.CaseInsensitive)
+	case "dsl":
+		m.dsl, err = compilePocDSL(m.DSL)
+	default:
+		return fmt.Errorf("unknown matcher type %q", m.Type)
+	}
+	if m.Condition != "" && m.Condition != "and" && m.Condition != "or" {
+		return fmt.Errorf("unknown matcher condition %q", m.Condition)
+	}
+	return err
+}
+
+func (e *PocExtractor) compile() error {
+	var err error
+	switch e.Type {
+	case "kval":
+	case "regex":
+		e.regex, err = compilePocRegexes(e.Regex, false)
+	case "dsl":
+		e.dsl, err = compilePocDSL(e.DSL)
+	default:
+		return fmt.Errorf("unknown extractor type %q", e.Type)
+	}
+	return err
+}
+
+// FilterPocTemplates keeps templates whose id/name contains one of names, that carry one of
+// tags and whose severity is one of severities; empty filters keep everything.
+func FilterPocTemplates(ts []*PocTemplate, names, tags, severities []string) []*PocTemplate {
+	var out []*PocTemplate
+	for _, t := range ts {
+		if len(names) > 0 && !pocAnyMatch(names, func(n string) bool {
+			n = strings.ToLower(n)
+			return strings.Contains(strings.ToLower(t.ID), n) || strings.Contains(strings.ToLower(t.Info.Name), n)
+		}) {
+			continue
+		}
+		if len(tags) > 0 && !pocAnyMatch(tags, func(tag string) bool {
+			for _, have := range strings.Split(t.Info.Tags, ",") {
+				if strings.EqualFold(strings.TrimSpace(have), tag) {
+					return true
+				}
+			}
+			return false
+		}) {
+			continue
+		}
+		if len(severities) > 0 && !pocAnyMatch(severities, func(s string) bool { return strings.EqualFold(s, t.Info.Severity) }) {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+func pocAnyMatch(list []string, fn func(string) bool) bool {
+	for _, v := range list {
+		if v = strings.TrimSpace(v); v != "" && fn(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// pocVars is the evaluation context of one template run against one target.
+type pocVars map[string]any
+
+func newPocVars(target string) (pocVars, error) {
+	u, err := url.Parse(strings.TrimSuffix(target, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid target %q", target)
+	}
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	return pocVars{
+		"BaseURL":  u.String(),
+		"RootURL":  u.Scheme + "://" + u.Host,
+		"Hostname": u.Host,
+		"Host":     u.Hostname(),
+		"Port":     port,
+		"Path":     u.Path,
+		"Scheme":   u.Scheme,
+		"randstr":  pocRandText(12),
+	}, nil
+}
+
+// render replaces every {{expr}} by a variable or a helper expression; unknown ones are kept.
+func (v pocVars) render(s string) string {
+	return pocPlaceholderRe.ReplaceAllStringFunc(s, func(m string) string {
+		expr := strings.TrimSpace(m[2 : len(m)-2])
+		if val, ok := v[expr]; ok {
+			return pocString(val)
+		}
+		ev, err := govaluate.NewEvaluableExpressionWithFunctions(expr, pocFunctions)
+		if err != nil {
+			return m
+		}
+		out, err := ev.Evaluate(v)
+		if err != nil {
+			return m
+		}
+		return pocString(out)
+	})
+}
+
+func pocString(v any) string {
+	if f, ok := v.(float64); ok && f == float64(int64(f)) {
+		return fmt.Sprint(int64(f))
+	}
+	return fmt.Sprint(v)
+}
+
+var (
+	pocOOBRe = regexp.MustCompile(`\{\{\s*(interactsh-url|oob_domain|oob_url)\s*\}\}`)
+	pocOOB   *OOBClient
+)
+
+// SetPocOOB enables OOB templates: each of their requests gets a fresh token from c, and callbacks
+// are turned into findings by c.Poll instead of the request matchers.
+func SetPocOOB(c *OOBClient) {
+	pocOOB = c
+}
+
+// oobToken issues a token for the next request of t and exposes it as {{interactsh-url}}
+// (dns name, or host:port of the http listener), {{oob_domain}} and {{oob_url}}.
+func (v pocVars) oobToken(t *PocTemplate, target string) string {
+	token := pocOOB.NewToken(OOBProbe{TemplateID: t.ID, Title: pocTitle(t), Severity: t.Info.Severity, Target: target})
+	name, u := pocOOB.DNSName(token), pocOOB.URL(token)
+	host := name
+	if host == "" {
+		if pu, err := url.Parse(u); err == nil {
+			host = pu.Host + pu.Path
+		}
+	}
+	v["interactsh-url"], v["oob_domain"], v["oob_url"] = host, name, u
+	return token
+}
+
+// RunPocTemplate runs t against target and returns one finding per matched request.
+func RunPocTemplate(t *PocTemplate, target, cookie string) ([]Finding, error) {
+	if t.OOB && pocOOB == nil {
+		return nil, fmt.Errorf("template %s needs an oob listener", t.ID)
+	}
+	vars, err := newPocVars(target)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(t.Variables))
+	for k := range t.Variables {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		vars[k] = vars.render(t.Variables[k])
+	}
+	var out []Finding
+	n := 0
+	for _, r := range t.HTTP {
+		count := len(r.Path)
+		if len(r.Raw) > 0 {
+			count = len(r.Raw)
+		}
+		for i := 0; i < count; i++ {
+			token := ""
+			if t.OOB {
+				token = vars.oobToken(t, target)
+			}
+			req, err := r.build(i, vars)
+			if err != nil {
+				return out, err
+			}
+			if token != "" {
+				pocOOB.SetProbeURL(token, req.URL.String())
+			}
+			if cookie != "" && req.Header.Get("Cookie") == "" {
+				req.Header.Set("Cookie", cookie)
+			}
+			n++
+			if err := vars.send(req, r.Redirects, n); err != nil {
+				Debug("poc %s %s: %v", t.ID, req.URL, err)
+				continue
+			}
+			evidence := r.extract(vars)
+			matched, words := r.match(vars)
+			if !matched {
+				continue
+			}
+			evidence = append(words, evidence...)
+			out = append(out, Finding{
+				RootURL:    apiDocRoot(req.URL.String()),
+				URL:        req.URL.String(),
+				Module:     "poc",
+				Title:      pocTitle(t),
+				Severity:   t.Info.Severity,
+				Detail:     t.Info.Description,
+				TemplateID: t.ID,
+				Evidence:   strings.Join(evidence, "; "),
+			})
+			if r.StopAtFirstMatch {
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+func pocTitle(t *PocTemplate) string {
+	if t.Info.Name != "" {
+		return t.Info.Name
+	}
+	return t.ID
+}
+
+func (r *PocRequest) build(i int, vars pocVars) (*http.Request, error) {
+	if len(r.Raw) > 0 {
+		return parsePocRaw(vars.render(r.Raw[i]), vars)
+	}
+	target := vars.render(r.Path[i])
+	var req *http.Request
+	var err error
+	if r.Body != "" {
+		req, err = http.NewRequest(r.Method, target, strings.NewReader(vars.render(r.Body)))
+	} else {
+		req, err = http.NewRequest(r.Method, target, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	SetHeaders(req)
+	for k, v := range r.Headers {
+		req.Header.Set(k, vars.render(v))
+	}
+	return req, nil
+}
+
+// parsePocRaw turns a rendered raw block ("POST /x HTTP/1.1\nHost: ...\n\nbody") into a request
+// against the target's BaseURL.
+func parsePocRaw(raw string, vars pocVars) (*http.Request, error) {
+	raw = strings.TrimLeft(raw, "\r\n")
+	head, body, _ := strings.Cut(strings.ReplaceAll(raw, "\r\n", "\n"), "\n\n")
+	sc := bufio.NewScanner(strings.NewReader(head))
+	if !sc.Scan() {
+		return nil, fmt.Errorf("empty raw request")
+	}
+	parts := strings.Fields(sc.Text())
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("bad raw request line %q", sc.Text())
+	}
+	target := parts[1]
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		target = pocString(vars["BaseURL"]) + target
+	}
+	req, err := http.NewRequest(parts[0], target, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	SetHeaders(req)
+	for sc.Scan() {
+		k, v, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		// the connection always goes to the target, Host only overrides the header
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		if strings.EqualFold(k, "Content-Length") {
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+// send performs req and stores the response as status_code, body, header, all_headers,
+// content_length and content_type, also suffixed with _n for the n-th request of the template.
+// Every response header goes to header_<name> so that a server can never overwrite those.
+func (v pocVars) send(req *http.Request, redirects bool, n int) error {
+	client := Client
+	if !redirects {
+		client = enforceNoRedirectClient(ClientNoRedirect)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := readResponseBody(resp)
+	var hb strings.Builder
+	keys := make([]string, 0, len(resp.Header))
+	for k := range resp.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, val := range resp.Header[k] {
+			fmt.Fprintf(&hb, "%s: %s\r\n", k, val)
+		}
+	}
+	vals := map[string]any{
+		"status_code":    float64(resp.StatusCode),
+		"body":           string(body),
+		"header":         hb.String(),
+		"all_headers":    hb.String(),
+		"content_length": float64(len(body)),
+		"content_type":   resp.Header.Get("Content-Type"),
+	}
+	for k, val := range vals {
+		v[k] = val
+		v[fmt.Sprintf("%s_%d", k, n)] = val
+	}
+	for k := range resp.Header {
+		v[pocHeaderVar(k)] = resp.Header.Get(k)
+	}
+	return nil
+}
+
+func (v pocVars) part(name string) string {
+	switch name {
+	case "", "body":
+		return pocString(v["body"])
+	case "header", "all_headers":
+		return pocString(v["header"])
+	case "all", "response", "raw":
+		return pocString(v["header"]) + "\r\n" + pocString(v["body"])
+	}
+	if val, ok := v[name]; ok {
+		return pocString(val)
+	}
+	if val, ok := v[pocHeaderVar(name)]; ok {
+		return pocString(val)
+	}
+	return ""
+}
+
+// pocHeaderVar is the variable holding response header name, e.g. header_x_powered_by.
+func pocHeaderVar(name string) string {
+	return "header_" + strings.ToLower(strings.ReplaceAll(name, "-", "_"))
+}
+
+// match applies the matchers and returns the matched words/regexes as evidence.
+func (r *PocRequest) match(vars pocVars) (bool, []string) {
+	if len(r.Matchers) == 0 {
+		return false, nil
+	}
+	and := r.MatchersCondition == "and"
+	var evidence []string
+	for _, m := range r.Matchers {
+		ok, ev := m.match(vars)
+		if m.Negative {
+			ok, ev = !ok, nil
+		}
+		if and && !ok {
+			return false, nil
+		}
+		if ok {
+			evidence = append(evidence, ev...)
+			if !and {
+				return true, evidence
+			}
+		}
+	}
+	return and, evidence
+}
+
+func (m *PocMatcher) match(vars pocVars) (bool, []string) {
+	and := m.Condition == "and"
+	var evidence []string
+	// check folds one item result into the matcher result; done reports an early decision
+	result := and
+	check := func(ok bool, ev string) (done bool) {
+		if ok && ev != "" {
+			evidence = append(evidence, ev)
+		}
+		if and && !ok {
+			result = false
+			return true
+		}
+		if !and && ok {
+			result = true
+			return true
+		}
+		return false
+	}
+	switch m.Type {
+	case "status":
+		code, ok := vars["status_code"].(float64)
+		if !ok {
+			return false, nil
+		}
+		status := int(code)
+		for _, s := range m.Status {
+			if status == s {
+				return true, []string{fmt.Sprintf("status=%d", s)}
+			}
+		}
+		return false, nil
+	case "word":
+		text := vars.part(m.Part)
+		if m.CaseInsensitive {
+			text = strings.ToLower(text)
+		}
+		for _, w := range m.Words {
+			w = vars.render(w)
+			needle := w
+			if m.CaseInsensitive {
+				needle = strings.ToLower(w)
+			}
+			if check(strings.Contains(text, needle), w) {
+				break
+			}
+		}
+	case "regex":
+		text := vars.part(m.Part)
+		for _, re := range m.regex {
+			if check(re.MatchString(text), re.FindString(text)) {
+				break
+			}
+		}
+	case "dsl":
+		for i, ev := range m.dsl {
+			out, err := ev.Evaluate(vars)
+			b, _ := out.(bool)
+			if check(err == nil && b, m.DSL[i]) {
+				break
+			}
+		}
+	}
+	if !result {
+		return false, nil
+	}
+	return true, evidence
+}
+
+// extract runs the extractors, storing named values as variables for the next requests, and
+// returns the non-internal ones as evidence.
+func (r *PocRequest) extract(vars pocVars) []string {
+	var evidence []string
+	for _, e := range r.Extractors {
+		values := e.extract(vars)
+		if len(values) == 0 {
+			continue
+		}
+		if e.Name != "" {
+			vars[e.Name] = values[0]
+		}
+		if !e.Internal {
+			label := e.Name
+			if label == "" {
+				label = e.Type
+			}
+			evidence = append(evidence, label+"="+strings.Join(values, ","))
+		}
+	}
+	return evidence
+}
+
+func (e *PocExtractor) extract(vars pocVars) []string {
+	var out []string
+	seen := make(map[string]bool)
+	add := func(s string) {
+		if s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	switch e.Type {
+	case "regex":
+		text := vars.part(e.Part)
+		for _, re := range e.regex {
+			for _, m := range re.FindAllStringSubmatch(text, 16) {
+				if e.Group < len(m) {
+					add(m[e.Group])
+				}
+			}
+		}
+	case "kval":
+		for _, k := range e.KVal {
+			if v, ok := vars[pocHeaderVar(k)]; ok {
+				add(pocString(v))
+			}
+		}
+	case "dsl":
+		for _, ev := range e.dsl {
+			if v, err := ev.Evaluate(vars); err == nil {
+				add(pocString(v))
+			}
+		}
+	}
+	return out
+}
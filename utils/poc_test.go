@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPocTemplate = `
+id: demo-login-token
+info:
+  name: Demo token leak
+  severity: high
+  tags: demo,token
+variables:
+  user: "{{to_upper('admin')}}"
+http:
+  - raw:
+      - |
+        POST /api/login HTTP/1.1
+        Host: {{Hostname}}
+        Content-Type: application/json
+
+        {"user":"{{user}}"}
+    extractors:
+      - type: regex
+        name: token
+        internal: true
+        group: 1
+        regex:
+          - '"token":"([a-z0-9]+)"'
+  - method: GET
+    path:
+      - "{{BaseURL}}/api/profile?token={{token}}"
+      - "{{BaseURL}}/api/other"
+    headers:
+      X-Token: "{{token}}"
+    matchers-condition: and
+    matchers:
+      - type: status
+        status: [200]
+      - type: word
+        part: body
+        condition: and
+        words:
+          - '"role":"admin"'
+          - 'secret'
+      - type: word
+        negative: true
+        words:
+          - "denied"
+      - type: dsl
+        dsl:
+          - 'contains(content_type, "json") && status_code_1 == 200 && len(body) > 10'
+    extractors:
+      - type: kval
+        kval:
+          - X-Powered-By
+      - type: regex
+        name: secret
+        group: 1
+        regex:
+          - '"secret":"([^"]+)"'
+`
+
+func TestRunPocTemplate(t *testing.T) {
+	srv := mustTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Powered-By", "Express")
+		switch r.URL.Path {
+		case "/api/login":
+			buf := make([]byte, 64)
+			n, _ := r.Body.Read(buf)
+			if r.Method != http.MethodPost || !strings.Contains(string(buf[:n]), `"user":"ADMIN"`) {
+				http.Error(w, "bad login", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"token":"abc123"}`))
+		case "/api/profile":
+			if r.URL.Query().Get("token") != "abc123" || r.Header.Get("X-Token") != "abc123" {
+				http.Error(w, "denied", http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"role":"admin","secret":"s3cr3t"}`))
+		default:
+			http.Error(w, "denied", http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+	oldClient, oldNoRedirect := Client, ClientNoRedirect
+	Client, ClientNoRedirect = srv.Client(), srv.Client()
+	defer func() { Client, ClientNoRedirect = oldClient, oldNoRedirect }()
+
+	tpl, err := ParsePocTemplate([]byte(testPocTemplate), "demo.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := RunPocTemplate(tpl, srv.URL+"/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("want 1 finding, got %+v", findings)
+	}
+	f := findings[0]
+	if f.TemplateID != "demo-login-token" || f.Severity != SeverityHigh || f.URL != srv.URL+"/api/profile?token=abc123" {
+		t.Fatalf("unexpected finding %+v", f)
+	}
+	for _, want := range []string{`"role":"admin"`, "kval=Express", "secret=s3cr3t"} {
+		if !strings.Contains(f.Evidence, want) {
+			t.Errorf("evidence %q misses %q", f.Evidence, want)
+		}
+	}
+	if strings.Contains(f.Evidence, "token=") {
+		t.Errorf("internal extractor leaked into evidence: %q", f.Evidence)
+	}
+
+	db, err := InitSpiderDB(filepath.Join(t.TempDir(), "spider.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := SaveFindings(db, findings); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := LoadFindings(db)
+	if err != nil || len(saved) != 1 || saved[0].TemplateID != f.TemplateID || saved[0].Evidence != f.Evidence {
+		t.Fatalf("findings round trip: %v %+v", err, saved)
+	}
+}
+
+func TestRunPocTemplateHostileHeaders(t *testing.T) {
+	srv := mustTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// headers named after the response variables must not replace them
+		w.Header().Set("Body", "clobbered")
+		w.Header().Set("Status-Code", "200")
+		w.Header().Set("Content-Length_1", "x")
+		w.Header().Set("X-Powered-By", "Express")
+		w.Write([]byte(`{"role":"admin"}`))
+	}))
+	defer srv.Close()
+	oldClient, oldNoRedirect := Client, ClientNoRedirect
+	Client, ClientNoRedirect = srv.Client(), srv.Client()
+	defer func() { Client, ClientNoRedirect = oldClient, oldNoRedirect }()
+
+	tpl, err := ParsePocTemplate([]byte(`
+id: hostile-headers
+info:
+  name: Hostile headers
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+    matchers-condition: and
+    matchers:
+      - type: status
+        status: [200]
+      - type: word
+        words:
+          - '"role":"admin"'
+      - type: dsl
+        dsl:
+          - 'header_body == "clobbered" && header_x_powered_by == "Express"'
+    extractors:
+      - type: kval
+        kval:
+          - Status-Code
+`), "hostile.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := RunPocTemplate(tpl, srv.URL, "")
+	if err != nil || len(findings) != 1 || !strings.Contains(findings[0].Evidence, "kval=200") {
+		t.Fatalf("unexpected result %v %+v", err, findings)
+	}
+}
+
+func TestLoadPocTemplates(t *testing.T) {
+	builtin, err := LoadPocTemplates(nil)
+	if err != nil || len(builtin) == 0 {
+		t.Fatalf("built-in templates: %v %d", err, len(builtin))
+	}
+	dir := t.TempDir()
+	override := strings.Replace(testPocTemplate, "id: demo-login-token", "id: git-config-exposure", 1)
+	os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(override), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a template"), 0o644)
+	all, err := LoadPocTemplates([]string{dir})
+	if err != nil || len(all) != len(builtin) {
+		t.Fatalf("override: %v %d != %d", err, len(all), len(builtin))
+	}
+	if got := FilterPocTemplates(all, []string{"git-config"}, nil, nil); len(got) != 1 || got[0].Source != filepath.Join(dir, "a.yaml") {
+		t.Fatalf("user template did not replace built-in: %+v", got)
+	}
+	if got := FilterPocTemplates(all, nil, []string{"nacos"}, []string{"critical"}); len(got) != 1 || got[0].ID != "nacos-auth-bypass-ua" {
+		t.Fatalf("tag/severity filter: %+v", got)
+	}
+	if _, err := ParsePocTemplate([]byte("id: x\nhttp:\n  - path: ['{{BaseURL}}']\n    matchers:\n      - type: regex\n        regex: ['(']\n"), "bad.yaml"); err == nil {
+		t.Fatal("expected regex compile error")
+	}
+}
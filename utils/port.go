@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"net/url"
@@ -228,17 +230,30 @@ func handleWorker(tasks <-chan ProtocolInfo, results chan ProtocolInfo, wg *sync
 	}
 }
 
-func PortScan(IpRange string, PortRange string) {
+// PortScan scans the targets, identifies services on open ports and returns the identified services.
+// portListKey names the journal unit of a host scanned over ports: a short hash of the sorted
+// list, so equivalent -p specs share it and --top-ports does not yield a huge key.
+func portListKey(ports []int) string {
+	sorted := append([]int(nil), ports...)
+	sort.Ints(sorted)
+	h := sha1.New()
+	for _, p := range sorted {
+		fmt.Fprintf(h, "%d,", p)
+	}
+	return "done " + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func PortScan(IpRange string, PortRange string) []ServiceResultRow {
 	ips, err := convertTargetListToPool(strings.Split(IpRange, ","))
 	if err != nil {
 		Error("%s", err)
-		return
+		return nil
 	}
 
 	ports_list, err := parsePorts(PortRange)
 	if err != nil {
 		Error("%s", err)
-		return
+		return nil
 	}
 	Info("Total IP(s): %d", len(ips))
 	Info("Total Port(s): %d", len(ports_list))
@@ -250,6 +265,10 @@ func PortScan(IpRange string, PortRange string) {
 	bar.SetTemplateString(`{{string . "prefix"}} {{counters . }} {{bar . "|" "█" "█" "░" "|"}} {{percent . }} | {{etime . }}`)
 	bar.SetRefreshRate(200 * time.Millisecond)
 
+	ck := NewCheckpoint(GetSpiderDB(), "port", viper.GetBool("resume"))
+	defer ck.Close()
+	var allOpen []ProtocolInfo
+
 	taskChan := make(chan ProtocolInfo, viper.GetInt("threads"))
 	results := make(chan ProtocolInfo)
 
@@ -260,6 +279,24 @@ func PortScan(IpRange string, PortRange string) {
 		go handleWorker(taskChan, results, &wg)
 	}
 
+	// the journal holds one unit per finished host (keyed by the port list, so a different
+	// -p rescans it) plus its open ports; closed ports are not journaled
+	hostDone := portListKey(ports_list)
+	var todo []string
+	for _, ip := range ips {
+		if ck.Done(ip, hostDone) {
+			bar.Add(len(ports_list))
+			// skipped hosts were not probed this time; keep their open ports for service identification
+			for _, port := range ports_list {
+				if data, ok := ck.Data(ip, strconv.Itoa(port)); ok && data == "open" {
+					allOpen = append(allOpen, ProtocolInfo{Ip: ip, Port: port})
+				}
+			}
+			continue
+		}
+		todo = append(todo, ip)
+	}
+
 	// 任务生产者-分发任务 (新起一个 goroutinue ，进行分发数据)
 	go func(arr []string) {
 		for _, ip := range arr {
@@ -268,22 +305,42 @@ func PortScan(IpRange string, PortRange string) {
 			}
 		}
 		close(taskChan)
-	}(ips)
+	}(todo)
 
 	go func() {
 		wg.Wait()
 		close(results)
 	}()
-	var allOpen []ProtocolInfo
+	tried := make(map[string]int, len(todo))
 	for resPortInfo := range results {
 		if resPortInfo.Port > 0 {
 			allOpen = append(allOpen, ProtocolInfo{Ip: resPortInfo.Ip, Port: resPortInfo.Port})
 			Success("Open: %s:%d", resPortInfo.Ip, resPortInfo.Port)
+			ck.Mark(resPortInfo.Ip, strconv.Itoa(resPortInfo.Port), "open")
+		}
+		if tried[resPortInfo.Ip]++; tried[resPortInfo.Ip] == len(ports_list) {
+			ck.Mark(resPortInfo.Ip, hostDone, "")
+			delete(tried, resPortInfo.Ip)
 		}
 		bar.Increment()
 	}
 
 	bar.Finish()
+	if skipped := ck.Skipped(); skipped > 0 {
+		Info("resume: skipped %d finished host(s)", skipped)
+	}
 
-	ScanWithIpAndPort(allOpen)
+	openRows := make([]OpenPortRow, 0, len(allOpen))
+	for _, p := range allOpen {
+		openRows = append(openRows, OpenPortRow{IP: p.Ip, Port: p.Port, Protocol: "tcp"})
+	}
+	if err := SaveOpenPorts(GetSpiderDB(), openRows); err != nil {
+		Warning("save open ports failed: %v", err)
+	}
+
+	services := ScanWithIpAndPort(allOpen)
+	if GetSpiderDB() != nil {
+		Info("Port/service results persisted to spider.db (run `godscan report` to view)")
+	}
+	return services
 }
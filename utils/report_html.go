@@ -20,6 +20,7 @@ type HTMLReportData struct {
 	GeneratedAt   string
 	Summary       []SpiderRecord
 	APIs          []APIPathRow
+	Routes        []FrontendRoute
 	Sensitive     []SensitiveHit
 	SourceMaps    []SourceMapHit
 	Pages         []PageSnapshotMeta
@@ -27,6 +28,7 @@ type HTMLReportData struct {
 	Scores        []ScoredRow
 	CDNHosts      []CDNHostRow
 	Graph         []GraphEdge
+	Services      []ServiceResultRow
 	ImportantAPIs []string
 	LLMSummary    *LLMSummary
 }
@@ -54,6 +56,10 @@ func ExportHTMLReport(ctx context.Context, db *sql.DB, outputPath string, llmCfg
 	if err != nil {
 		return err
 	}
+	routes, err := LoadFrontendRoutes(db)
+	if err != nil {
+		return err
+	}
 	sens, err := LoadSensitiveHits(db)
 	if err != nil {
 		return err
@@ -74,6 +80,10 @@ func ExportHTMLReport(ctx context.Context, db *sql.DB, outputPath string, llmCfg
 	if err != nil {
 		return err
 	}
+	services, err := LoadPortServices(db)
+	if err != nil {
+		return err
+	}
 
 	graph, _ := LoadGraphDB(db, 2000)
 	if len(graph) == 0 {
@@ -84,12 +94,14 @@ func ExportHTMLReport(ctx context.Context, db *sql.DB, outputPath string, llmCfg
 		GeneratedAt:   time.Now().Format(time.RFC3339),
 		Summary:       summary,
 		APIs:          apis,
+		Routes:        routes,
 		Sensitive:     sens,
 		SourceMaps:    smaps,
 		Pages:         pages,
 		PageBodies:    pageBodies,
 		CDNHosts:      cdns,
 		Graph:         graph,
+		Services:      services,
 		ImportantAPIs: common.ImportantApi,
 	}
 	data.Scores = buildScores(data)
@@ -168,6 +180,7 @@ func buildScores(data HTMLReportData) []ScoredRow {
 			"url_count":       rec.UrlCount,
 			"cdn_count":       rec.CDNCount,
 			"icon_hash":       rec.IconHash,
+			"icon_finger":     rec.IconFinger,
 			"finger":          "", // filled if available in summary later
 			"map_count":       mapCounts[rec.Url],
 			"sensitive_count": sensCounts[rec.Url],
@@ -380,9 +393,11 @@ const reportHTMLTemplate = `<!DOCTYPE html>
     <button data-target="section-summary">Summary</button>
     <button data-target="section-important">Important APIs</button>
     <button data-target="section-api">APIs</button>
+    <button data-target="section-routes">Routes</button>
     <button data-target="section-sensitive">Sensitive</button>
     <button data-target="section-maps">SourceMaps</button>
     <button data-target="section-pages">Pages</button>
+    <button data-target="section-services">Ports</button>
     <button data-target="section-graph">Graph</button>
   </nav>
   <main>
@@ -445,7 +460,7 @@ const reportHTMLTemplate = `<!DOCTYPE html>
         <table data-table="summary">
           <thead id="summary-head">
             <tr>
-              <th data-col="Url">URL</th><th data-col="IconBase64">Icon</th><th data-col="IconHash">Icon hash</th><th data-col="ApiCount">API</th><th data-col="UrlCount">URLs</th><th data-col="CDNCount">CDN URLs</th><th data-col="CDNHosts">CDN Hosts</th><th data-col="Status">Status</th><th data-col="SaveDir">Save Dir</th>
+              <th data-col="Url">URL</th><th data-col="IconBase64">Icon</th><th data-col="IconHash">Icon hash</th><th data-col="IconFinger">Icon finger</th><th data-col="ApiCount">API</th><th data-col="UrlCount">URLs</th><th data-col="CDNCount">CDN URLs</th><th data-col="CDNHosts">CDN Hosts</th><th data-col="Status">Status</th><th data-col="SaveDir">Save Dir</th>
             </tr>
           </thead>
           <tbody id="summary-body"></tbody>
@@ -501,6 +516,28 @@ const reportHTMLTemplate = `<!DOCTYPE html>
       </div>
     </section>
 
+    {{/* Front-end routes */}}
+    <section class="panel section" id="section-routes">
+      <header>
+        <h2>Front-end routes</h2>
+        <div class="controls">
+          <input id="route-search" type="search" placeholder="Filter root/path/component/source">
+          <select id="route-page-size">
+            <option value="200">200 / page</option>
+            <option value="500">500 / page</option>
+            <option value="1000">1000 / page</option>
+          </select>
+          <div class="pagination" id="route-pagination"></div>
+        </div>
+      </header>
+      <div style="overflow:auto">
+        <table data-table="routes">
+          <thead id="route-head"><tr><th data-col="root_url">Root</th><th data-col="path">Path</th><th data-col="name">Name</th><th data-col="component">Component</th><th data-col="title">Title</th><th data-col="requires_auth">Auth</th><th data-col="hidden">Hidden</th><th data-col="source_url">Source</th></tr></thead>
+          <tbody id="route-body"></tbody>
+        </table>
+      </div>
+    </section>
+
     {{/* Sensitive */}}
     <section class="panel section" id="section-sensitive">
       <header>
@@ -508,7 +545,7 @@ const reportHTMLTemplate = `<!DOCTYPE html>
       </header>
       <div class="panel" style="margin-bottom:12px;">
         <header>
-          <h3 style="margin:0;font-size:14px;color:var(--accent-2);">security-rule-* (high risk)</h3>
+          <h3 style="margin:0;font-size:14px;color:var(--accent-2);">Secrets (rule detectors, high risk)</h3>
           <div class="controls">
             <input id="sens-rule-search" type="search" placeholder="Filter category/content/source">
             <select id="sens-rule-page-size">
@@ -521,7 +558,7 @@ const reportHTMLTemplate = `<!DOCTYPE html>
         </header>
         <div style="overflow:auto">
           <table data-table="sensitive_rules">
-            <thead id="sens-rule-head"><tr><th data-col="category">Category</th><th data-col="content">Content</th><th data-col="source_url">Source</th><th data-col="entropy">Entropy</th><th data-col="save_dir">Save Dir</th></tr></thead>
+            <thead id="sens-rule-head"><tr><th data-col="category">Category</th><th data-col="rule_id">Rule</th><th data-col="confidence">Confidence</th><th data-col="status">Status</th><th data-col="content">Content</th><th data-col="source_url">Source</th><th data-col="entropy">Entropy</th><th data-col="save_dir">Save Dir</th></tr></thead>
             <tbody id="sens-rule-body"></tbody>
           </table>
         </div>
@@ -592,6 +629,28 @@ const reportHTMLTemplate = `<!DOCTYPE html>
       </div>
     </section>
 
+    {{/* Ports / Services */}}
+    <section class="panel section" id="section-services">
+      <header>
+        <h2>Ports &amp; services</h2>
+        <div class="controls">
+          <input id="svc-search" type="search" placeholder="Filter ip/service/product/banner">
+          <select id="svc-page-size">
+            <option value="200">200 / page</option>
+            <option value="500">500 / page</option>
+            <option value="1000">1000 / page</option>
+          </select>
+          <div class="pagination" id="svc-pagination"></div>
+        </div>
+      </header>
+      <div style="overflow:auto">
+        <table data-table="services">
+          <thead id="svc-head"><tr><th data-col="ip">IP</th><th data-col="port">Port</th><th data-col="protocol">Proto</th><th data-col="service">Service</th><th data-col="product">Product</th><th data-col="version">Version</th><th data-col="cpe">CPE</th><th data-col="soft_match">Soft</th><th data-col="spider_url">Spider</th><th data-col="banner">Banner</th></tr></thead>
+          <tbody id="svc-body"></tbody>
+        </table>
+      </div>
+    </section>
+
     {{/* Graph */}}
     <section class="panel section" id="section-graph">
       <header>
@@ -639,8 +698,9 @@ const reportHTMLTemplate = `<!DOCTYPE html>
         pageMeta[p.url] = {status:p.status, type:p.content_type};
       }
     });
-    const sensitiveRules = (data.Sensitive||[]).filter(s => (s.category||"").toLowerCase().startsWith("security-rule"));
-    const sensitiveOther = (data.Sensitive||[]).filter(s => !(s.category||"").toLowerCase().startsWith("security-rule"));
+    const isSecretHit = (s) => (s.category||"").toLowerCase().startsWith("security-rule") || (s.confidence && s.confidence !== "info");
+    const sensitiveRules = (data.Sensitive||[]).filter(s => isSecretHit(s));
+    const sensitiveOther = (data.Sensitive||[]).filter(s => !isSecretHit(s));
     const fmt = {
       esc: (s) => String(s || "").replace(/[&<>"']/g, c => ({'&':'&amp;','<':'&lt;','>':'&gt;','"':'&quot;',"'":'&#39;'}[c])),
       num: (n) => isFinite(n) ? n.toLocaleString() : n
@@ -989,6 +1049,7 @@ const reportHTMLTemplate = `<!DOCTYPE html>
           const esc = fmt.esc(hash);
           return '<div class="hash-cell"><span class="ellipsis-long">'+esc+'</span><div class="hash-bubble"><pre>'+esc+'</pre><button class="mini-copy" data-hash="'+esc+'">copy</button></div></div>';
         }, raw:true},
+        {key:"IconFinger"},
         {key:"ApiCount"},
         {key:"UrlCount"},
         {key:"CDNCount"},
@@ -1081,6 +1142,9 @@ const reportHTMLTemplate = `<!DOCTYPE html>
       data: sensitiveRules,
       columns: [
         {key:"category"},
+        {key:"rule_id"},
+        {key:"confidence"},
+        {key:"status", render:(r)=> '<span title="'+fmt.esc(r.detail||"")+'">'+fmt.esc(r.status||"")+'</span>', raw:true},
         {key:"content", render:(r)=> '<span class="ellipsis-long" title="'+fmt.esc(r.content||"")+'">'+fmt.esc(r.content||"")+'</span>', raw:true},
         {key:"source_url", render:(r)=> '<span class="ellipsis-long" title="'+fmt.esc(r.source_url||"")+'">'+fmt.esc(r.source_url||"")+'</span>', raw:true},
         {key:"entropy", render:(r)=>r.entropy?.toFixed? r.entropy.toFixed(2): r.entropy},
@@ -1109,6 +1173,25 @@ const reportHTMLTemplate = `<!DOCTYPE html>
       headId:"sens-other-head",
     });
 
+    setupTable({
+      data: data.Routes || [],
+      columns: [
+        {key:"root_url"},
+        {key:"path", render:(r)=> '<span class="ellipsis" title="'+fmt.esc(r.path||"")+'">'+fmt.esc(r.path||"")+'</span>', raw:true},
+        {key:"name"},
+        {key:"component", render:(r)=> '<span class="ellipsis" title="'+fmt.esc(r.component||"")+'">'+fmt.esc(r.component||"")+'</span>', raw:true},
+        {key:"title"},
+        {key:"requires_auth", render:(r)=> r.requires_auth ? "yes" : ""},
+        {key:"hidden", render:(r)=> r.hidden ? "yes" : ""},
+        {key:"source_url", render:(r)=> '<span class="ellipsis" title="'+fmt.esc(r.source_url||"")+'">'+fmt.esc(r.source_url||"")+'</span>', raw:true},
+      ],
+      tbodyId:"route-body",
+      searchId:"route-search",
+      pagerId:"route-pagination",
+      pageSizeId:"route-page-size",
+      headId:"route-head",
+    });
+
     setupTable({
       data: data.SourceMaps || [],
       columns: [
@@ -1142,6 +1225,28 @@ const reportHTMLTemplate = `<!DOCTYPE html>
       headId:"page-head",
     });
 
+    setupTable({
+      data: data.Services || [],
+      columns: [
+        {key:"ip"},
+        {key:"port"},
+        {key:"protocol"},
+        {key:"service"},
+        {key:"product"},
+        {key:"version"},
+        {key:"cpe"},
+        {key:"soft_match", render:(r)=> r.soft_match ? "soft" : ""},
+        {key:"spider_url"},
+        {key:"banner", render:(r)=> '<span class="ellipsis-long" title="'+fmt.esc(r.banner||"")+'">'+fmt.esc(r.banner||"")+'</span>', raw:true},
+      ],
+      tbodyId:"svc-body",
+      searchId:"svc-search",
+      pagerId:"svc-pagination",
+      pageSizeId:"svc-page-size",
+      headId:"svc-head",
+      initialSortKey:"ip",
+    });
+
     function buildFindStats() {
       const m = {};
       const inc = (root, key) => {
@@ -1206,6 +1311,7 @@ const reportHTMLTemplate = `<!DOCTYPE html>
       const importantAPIs = apis.filter(a => isImportant(a.path));
       const sens = (data.Sensitive||[]).filter(s => rootOf(s.source_url) === root);
       const maps = (data.SourceMaps||[]).filter(m => m.root_url === root);
+      const routes = (data.Routes||[]).filter(r => r.root_url === root);
       const pages = (data.Pages||[]).filter(p => p.root_url === root || rootOf(p.url) === root);
       const pageBodies = (data.PageBodies||[]).filter(p => p.root_url === root || rootOf(p.url) === root);
       const cdns = (data.CDNHosts||[]).filter(c => c.Root === root);
@@ -1213,7 +1319,7 @@ const reportHTMLTemplate = `<!DOCTYPE html>
 
       const iconHtml = summary && summary.IconBase64 ? '<img class="icon-thumb" src="data:image/x-icon;base64,'+fmt.esc(summary.IconBase64)+'" alt="icon" title="'+fmt.esc(summary.IconHash||"")+'">' : '<span class="small">-</span>';
       const info = summary ? ''
-        + '<div class="row">Status: <code>'+fmt.esc(summary.Status)+'</code> | Icon: '+iconHtml+' <button class="mini-copy" data-hash="'+fmt.esc(summary.IconHash||"")+'">copy</button> <span class="small">'+fmt.esc(summary.IconHash || "-")+'</span></div>'
+        + '<div class="row">Status: <code>'+fmt.esc(summary.Status)+'</code> | Icon: '+iconHtml+' <button class="mini-copy" data-hash="'+fmt.esc(summary.IconHash||"")+'">copy</button> <span class="small">'+fmt.esc(summary.IconHash || "-")+'</span>'+(summary.IconFinger ? ' | Icon finger: <code>'+fmt.esc(summary.IconFinger)+'</code>' : '')+'</div>'
         + '<div class="row">API: '+fmt.num(summary.ApiCount||0)+' | URLs: '+fmt.num(summary.UrlCount||0)+' | CDN: '+fmt.num(summary.CDNCount||0)+'</div>'
         + '<div class="row">SaveDir: <code>'+fmt.esc(summary.SaveDir||"")+'</code></div>'
         : '<div class="row">No summary info</div>';
@@ -1235,6 +1341,7 @@ const reportHTMLTemplate = `<!DOCTYPE html>
         + findingsSummary
         + block("Important APIs", importantAPIs, a => "<li><code>"+fmt.esc(a.path)+"</code> <span class='small'>src "+fmt.esc(a.source_url||"")+"</span></li>")
         + block("APIs", apis, a => "<li><code>"+fmt.esc(a.path)+"</code> <span class='small'>src "+fmt.esc(a.source_url||"")+"</span></li>")
+        + block("Front-end routes", routes, r => "<li><code>"+fmt.esc(r.path)+"</code>"+(r.requires_auth ? " <span class='tag'>auth</span>" : "")+(r.hidden ? " <span class='tag'>hidden</span>" : "")+" <span class='small'>"+fmt.esc(r.component||"")+"</span></li>")
         + block("Sensitive", sens, s => "<li><code>"+fmt.esc(s.category||"")+"</code>: "+fmt.esc(s.content||"")+"</li>")
         + block("SourceMaps", maps, m => "<li><code>"+fmt.esc(m.map_url||"")+"</code> <span class='small'>js "+fmt.esc(m.js_url||"")+"</span></li>")
         + block("Pages", pages, p => "<li><code>"+fmt.esc(p.status)+"</code> "+fmt.esc(p.url||"")+" <span class='small'>"+fmt.esc(p.content_type||"")+" · "+fmt.num(p.length||0)+" bytes</span></li>")
@@ -1265,6 +1372,7 @@ const reportHTMLTemplate = `<!DOCTYPE html>
       scores: data.Scores || [],
       summary: data.Summary || [],
       api: data.APIs || [],
+      routes: data.Routes || [],
       important: importantAPIData,
       sensitive_rules: sensitiveRules,
       sensitive_other: sensitiveOther,
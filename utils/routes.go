@@ -0,0 +1,303 @@
+package utils
+
+import (
+	"database/sql"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FrontendRoute is a client-side route declared in a vue-router / react-router table.
+type FrontendRoute struct {
+	RootURL      string `json:"root_url"`
+	SourceURL    string `json:"source_url"`
+	Path         string `json:"path"`
+	Name         string `json:"name"`
+	Component    string `json:"component"`
+	Title        string `json:"title"`
+	RequiresAuth bool   `json:"requires_auth"`
+	Hidden       bool   `json:"hidden"`
+}
+
+// keys that mark an object with a path as a route definition rather than any other config
+var routeKeys = []string{"component", "components", "element", "children", "redirect", "meta", "name", "lazy", "Component"}
+
+var routeAuthKeys = map[string]bool{
+	"requiresAuth": true, "requireAuth": true, "needLogin": true, "requireLogin": true,
+	"requiresLogin": true, "auth": true, "login": true, "authRequired": true,
+}
+
+var (
+	jsxRouteRe     = regexp.MustCompile(`<Route\b[^>]*?\bpath\s*=\s*\{?["'` + "`" + `]([^"'` + "`" + `]+)["'` + "`" + `]\}?[^>]*>`)
+	jsxComponentRe = regexp.MustCompile(`\b(?:component\s*=\s*\{\s*([\w$.]+)|element\s*=\s*\{\s*<\s*([\w$.]+))`)
+)
+
+// ExtractFrontendRoutes returns the routes declared in a bundle: vue-router `routes` arrays,
+// react-router object routes (including compiled createElement(Route, {path})) and raw
+// <Route path> JSX. Child paths are joined onto their parent.
+func ExtractFrontendRoutes(src string) []FrontendRoute {
+	return newJSAnalyzer(src).frontendRoutes()
+}
+
+func (a *jsAnalyzer) frontendRoutes() []FrontendRoute {
+	var out []FrontendRoute
+	index := make(map[string]int)
+	add := func(r FrontendRoute) {
+		if i, ok := index[r.Path]; ok {
+			prev := &out[i]
+			prev.RequiresAuth = prev.RequiresAuth || r.RequiresAuth
+			prev.Hidden = prev.Hidden || r.Hidden
+			if prev.Component == "" {
+				prev.Component = r.Component
+			}
+			if prev.Name == "" {
+				prev.Name = r.Name
+			}
+			if prev.Title == "" {
+				prev.Title = r.Title
+			}
+			return
+		}
+		index[r.Path] = len(out)
+		out = append(out, r)
+	}
+	done := make(map[int]bool)
+	var walk func(brace int, props []jsProp, parent string, parentAuth bool)
+	walk = func(brace int, props []jsProp, parent string, parentAuth bool) {
+		done[brace] = true
+		r, ok := a.routeFrom(props, parent)
+		if !ok {
+			return
+		}
+		r.RequiresAuth = r.RequiresAuth || parentAuth
+		add(r)
+		for _, p := range props {
+			if p.key != "children" || !a.isPunct(a.toks, p.value, "[") {
+				continue
+			}
+			i := p.value + 1
+			for i < len(a.toks) && !a.isPunct(a.toks, i, "]") {
+				if a.isPunct(a.toks, i, "{") {
+					childProps, _ := a.parseObject(a.toks, i)
+					walk(i, childProps, r.Path, r.RequiresAuth)
+				}
+				i = a.valueEnd(a.toks, i)
+				if a.isPunct(a.toks, i, ",") {
+					i++
+				} else {
+					break
+				}
+			}
+		}
+	}
+	for _, i := range a.pathObjects() {
+		if done[i] {
+			continue
+		}
+		props, _ := a.parseObject(a.toks, i)
+		if isRouteObject(props) {
+			walk(i, props, "", false)
+		}
+	}
+	for _, m := range jsxRouteRe.FindAllStringSubmatch(a.src, -1) {
+		p := strings.TrimSpace(m[1])
+		if !validRoutePath(p, "") {
+			continue
+		}
+		r := FrontendRoute{Path: p}
+		if c := jsxComponentRe.FindStringSubmatch(m[0]); c != nil {
+			r.Component = c[1] + c[2]
+		}
+		add(r)
+	}
+	return out
+}
+
+// pathObjects returns, in source order, the braces of the object literals holding a "path"
+// key, found in one pass so that only those are parsed.
+func (a *jsAnalyzer) pathObjects() []int {
+	var open, out []int
+	for i, t := range a.toks {
+		if t.kind == jsTokPunct {
+			switch t.text {
+			case "{", "[", "(":
+				open = append(open, i)
+			case "}", "]", ")":
+				if len(open) > 0 {
+					open = open[:len(open)-1]
+				}
+			}
+			continue
+		}
+		if t.text != "path" || (t.kind != jsTokIdent && t.kind != jsTokString) || len(open) == 0 {
+			continue
+		}
+		brace := open[len(open)-1]
+		if !a.isPunct(a.toks, brace, "{") || !(a.isPunct(a.toks, i+1, ":") || a.isPunct(a.toks, i+1, ",") || a.isPunct(a.toks, i+1, "}")) {
+			continue
+		}
+		if len(out) == 0 || out[len(out)-1] != brace {
+			out = append(out, brace)
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+func isRouteObject(props []jsProp) bool {
+	hasPath, hasRouteKey := false, false
+	for _, p := range props {
+		if p.key == "path" {
+			hasPath = true
+		}
+		for _, k := range routeKeys {
+			hasRouteKey = hasRouteKey || p.key == k
+		}
+	}
+	return hasPath && hasRouteKey
+}
+
+func (a *jsAnalyzer) routeFrom(props []jsProp, parent string) (FrontendRoute, bool) {
+	if !isRouteObject(props) {
+		return FrontendRoute{}, false
+	}
+	var r FrontendRoute
+	for _, p := range props {
+		switch p.key {
+		case "path":
+			v, _ := a.evalExpr(a.toks, p.value)
+			if !v.lit || !validRoutePath(v.s, parent) {
+				return FrontendRoute{}, false
+			}
+			r.Path = joinRoutePath(parent, v.s)
+		case "name":
+			if v, _ := a.evalExpr(a.toks, p.value); v.lit {
+				r.Name = v.s
+			}
+		case "component", "components", "element", "Component", "lazy":
+			if r.Component == "" {
+				r.Component = a.componentName(p.value)
+			}
+		case "hidden":
+			r.Hidden = a.truthy(p.value)
+		case "meta":
+			if !a.isPunct(a.toks, p.value, "{") {
+				continue
+			}
+			meta, _ := a.parseObject(a.toks, p.value)
+			for _, m := range meta {
+				switch {
+				case routeAuthKeys[m.key]:
+					r.RequiresAuth = r.RequiresAuth || a.truthy(m.value)
+				case m.key == "hidden" || m.key == "hideInMenu":
+					r.Hidden = r.Hidden || a.truthy(m.value)
+				case m.key == "title":
+					if v, _ := a.evalExpr(a.toks, m.value); v.lit {
+						r.Title = v.s
+					}
+				}
+			}
+		}
+	}
+	return r, true
+}
+
+// validRoutePath accepts "/admin", "*", ":id" style paths; relative paths only below a parent.
+func validRoutePath(p, parent string) bool {
+	if len(p) > 200 || strings.ContainsAny(p, " \t\r\n\"'<>\\") || strings.Contains(p, "://") {
+		return false
+	}
+	if parent == "" {
+		return strings.HasPrefix(p, "/") || p == "*" || strings.HasPrefix(p, "/:")
+	}
+	return true
+}
+
+func joinRoutePath(parent, p string) string {
+	switch {
+	case strings.HasPrefix(p, "/") || parent == "":
+		return p
+	case p == "":
+		return parent
+	}
+	return strings.TrimRight(parent, "/") + "/" + p
+}
+
+// componentName describes a route component: the imported file for lazy routes
+// (() => import("@/views/admin/index.vue")), otherwise the component identifier.
+func (a *jsAnalyzer) componentName(start int) string {
+	end := a.valueEnd(a.toks, start)
+	ident := ""
+	for i := start; i < end; i++ {
+		t := a.toks[i]
+		switch t.kind {
+		case jsTokString:
+			if strings.Contains(t.text, "/") || strings.HasSuffix(t.text, ".vue") {
+				return t.text
+			}
+		case jsTokIdent:
+			if a.isPunct(a.toks, i-1, ".") || jsReserved[t.text] || t.text == "import" || t.text == "require" || t.text == "Promise" || t.text == "resolve" || t.text == "then" {
+				continue
+			}
+			if ident == "" || t.text[0] >= 'A' && t.text[0] <= 'Z' && !(ident[0] >= 'A' && ident[0] <= 'Z') {
+				ident = t.text
+			}
+		}
+	}
+	return ident
+}
+
+// truthy matches true, !0 and 1.
+func (a *jsAnalyzer) truthy(i int) bool {
+	if i >= len(a.toks) {
+		return false
+	}
+	t := a.toks[i]
+	switch {
+	case t.kind == jsTokIdent && t.text == "true", t.kind == jsTokNumber && t.text == "1":
+		return true
+	case a.isPunct(a.toks, i, "!") && i+1 < len(a.toks) && a.toks[i+1].kind == jsTokNumber && a.toks[i+1].text == "0":
+		return true
+	}
+	return false
+}
+
+func SaveFrontendRoutes(db *sql.DB, rootURL, sourceURL string, routes []FrontendRoute) error {
+	if db == nil || len(routes) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO frontend_routes (root_url, source_url, path, name, component, title, requires_auth, hidden) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range routes {
+		if _, err := stmt.Exec(rootURL, sourceURL, r.Path, r.Name, r.Component, r.Title, r.RequiresAuth, r.Hidden); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func LoadFrontendRoutes(db *sql.DB) ([]FrontendRoute, error) {
+	rows, err := db.Query(`SELECT root_url, source_url, path, name, component, title, requires_auth, hidden FROM frontend_routes ORDER BY root_url, path`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []FrontendRoute
+	for rows.Next() {
+		var r FrontendRoute
+		if err := rows.Scan(&r.RootURL, &r.SourceURL, &r.Path, &r.Name, &r.Component, &r.Title, &r.RequiresAuth, &r.Hidden); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testVueRoutes = `
+var ADMIN = "/admin";
+var routes = [
+	{path: "/login", name: "Login", component: () => import("@/views/login/index.vue"), hidden: true},
+	{path: ADMIN, component: Layout, meta: {requiresAuth: !0, title: "系统管理"}, children: [
+		{path: "user", name: "User", component: function(){return n.e("chunk-1").then(n.bind(null, "./src/views/system/user.vue"))}},
+		{path: "/secret/debug", component: Debug, meta: {hidden: true}},
+	]},
+	{path: "*", redirect: "/404"},
+];
+var api = {path: "/api/user", method: "get"};
+e.createElement(Route, {path: "/dashboard", component: Dashboard});
+`
+
+func TestExtractFrontendRoutes(t *testing.T) {
+	routes := ExtractFrontendRoutes(testVueRoutes + "\n<Route path=\"/reports/:id\" element={<ReportPage />} />")
+	byPath := make(map[string]FrontendRoute)
+	for _, r := range routes {
+		byPath[r.Path] = r
+	}
+	want := []string{"/login", "/admin", "/admin/user", "/secret/debug", "*", "/dashboard", "/reports/:id"}
+	if len(routes) != len(want) {
+		t.Fatalf("got %d routes, want %d: %+v", len(routes), len(want), routes)
+	}
+	for _, p := range want {
+		if _, ok := byPath[p]; !ok {
+			t.Fatalf("missing route %s: %+v", p, routes)
+		}
+	}
+	if r := byPath["/login"]; r.Component != "@/views/login/index.vue" || r.Name != "Login" || !r.Hidden || r.RequiresAuth {
+		t.Errorf("unexpected /login: %+v", r)
+	}
+	if r := byPath["/admin"]; !r.RequiresAuth || r.Title != "系统管理" || r.Component != "Layout" {
+		t.Errorf("unexpected /admin: %+v", r)
+	}
+	if r := byPath["/admin/user"]; !r.RequiresAuth || r.Component != "./src/views/system/user.vue" {
+		t.Errorf("child should inherit auth and keep lazy component: %+v", r)
+	}
+	if r := byPath["/secret/debug"]; !r.Hidden || r.Component != "Debug" {
+		t.Errorf("unexpected /secret/debug: %+v", r)
+	}
+	if r := byPath["/reports/:id"]; r.Component != "ReportPage" {
+		t.Errorf("unexpected jsx route: %+v", r)
+	}
+}
+
+func TestSaveFrontendRoutes(t *testing.T) {
+	db, err := InitSpiderDB(filepath.Join(t.TempDir(), "spider.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	defer db.Close()
+	routes := ExtractFrontendRoutes(testVueRoutes)
+	if err := SaveFrontendRoutes(db, "http://h", "http://h/app.js", routes); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := SaveFrontendRoutes(db, "http://h", "http://h/other.js", routes); err != nil {
+		t.Fatalf("save again: %v", err)
+	}
+	got, err := LoadFrontendRoutes(db)
+	if err != nil || len(got) != len(routes) {
+		t.Fatalf("rows=%d want %d err=%v", len(got), len(routes), err)
+	}
+	for _, r := range got {
+		if r.Path == "/admin" && (!r.RequiresAuth || r.SourceURL != "http://h/app.js") {
+			t.Fatalf("unexpected stored route: %+v", r)
+		}
+	}
+}
+
+func TestFrontendRoutesSharedAnalyzer(t *testing.T) {
+	a := newJSAnalyzer(testVueRoutes + `axios.get(ADMIN + "/user/list");`)
+	calls := a.apiCalls()
+	routes := a.frontendRoutes()
+	if fmt.Sprint(calls) != fmt.Sprint(ExtractJSAPICalls(a.src)) || fmt.Sprint(routes) != fmt.Sprint(ExtractFrontendRoutes(a.src)) {
+		t.Fatalf("shared analyzer differs: calls=%v routes=%v", calls, routes)
+	}
+	if len(calls) != 1 || calls[0].Path != "/admin/user/list" || len(routes) == 0 {
+		t.Fatalf("calls=%v routes=%v", calls, routes)
+	}
+}
+
+func TestExtractFrontendRoutesDeepNesting(t *testing.T) {
+	const depth = 30000
+	src := "var cfg = " + strings.Repeat("{a:", depth) + "1" + strings.Repeat("}", depth) + `; var routes = [{path: "/home", component: Home}];`
+	start := time.Now()
+	routes := ExtractFrontendRoutes(src)
+	if len(routes) != 1 || routes[0].Path != "/home" {
+		t.Fatalf("routes = %+v", routes)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("nested objects took %v", d)
+	}
+}
@@ -4,6 +4,7 @@ package utils
 import (
 	_ "embed"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -735,18 +736,23 @@ func (v *VScan) Explore(target Target, config *Config) (Result, error) {
 
 func (v *VScan) scanWithProbes(target Target, probes *[]Probe, config *Config) (Result, error) {
 	var result = Result{Target: target}
+	answered := false
 	for _, probe := range *probes {
 		Debug("Try Probe(%s), Data(%s)", probe.Name, probe.Data)
 		response, _ := grabResponse(target, probe.DecodedData, config)
 		if len(response) == 0 {
 			continue
 		}
+		answered = true
 		Debug("Get response %d bytes from destination with Probe(%s)", len(response), probe.Name)
 		res, matched := v.matchProbe(target, probe, response)
 		if matched {
 			return res, nil
 		}
 	}
+	if answered {
+		return result, errNoServiceMatch
+	}
 	return result, errEmptyResponse
 }
 
@@ -875,7 +881,8 @@ func grabResponse(target Target, data []byte, config *Config) ([]byte, error) {
 
 // 错误类型
 var (
-	errEmptyResponse = errors.New("empty response fetched from destination'")
+	errEmptyResponse  = errors.New("empty response fetched from destination'")
+	errNoServiceMatch = errors.New("no probe matched the responses")
 )
 
 func ConfigInit() {
@@ -893,9 +900,10 @@ func ConfigInit() {
 }
 
 type Worker struct {
-	In     chan Target
-	Out    chan Result
-	Config *Config
+	In         chan Target
+	Out        chan Result
+	Config     *Config
+	Checkpoint *Checkpoint
 }
 
 func (w *Worker) Start(v *VScan, wg *sync.WaitGroup) {
@@ -907,6 +915,10 @@ func (w *Worker) Start(v *VScan, wg *sync.WaitGroup) {
 			}
 			result, err := v.Explore(target, w.Config)
 			if err != nil {
+				// only a port that answered is finished; dial and read failures are retried on resume
+				if err == errNoServiceMatch {
+					w.Checkpoint.Mark(target.GetAddress(), target.Protocol, "")
+				}
 				continue
 			}
 			if err == errEmptyResponse {
@@ -918,7 +930,61 @@ func (w *Worker) Start(v *VScan, wg *sync.WaitGroup) {
 	}()
 }
 
-func ScanWithIpAndPort(addr []ProtocolInfo) {
+// serviceRowFromResult flattens a vscan Result for the service_results table.
+func serviceRowFromResult(r Result) ServiceResultRow {
+	return ServiceResultRow{
+		IP:         r.Target.IP,
+		Port:       r.Target.Port,
+		Protocol:   r.Target.Protocol,
+		Service:    r.Service.Name,
+		Product:    r.Extras.VendorProduct,
+		Version:    r.Extras.Version,
+		Info:       r.Extras.Info,
+		Hostname:   r.Extras.Hostname,
+		OS:         r.Extras.OperatingSystem,
+		DeviceType: r.Extras.DeviceType,
+		CPE:        r.Extras.CPE,
+		SoftMatch:  r.Details.IsSoftMatched,
+		Probe:      r.Details.ProbeName,
+		Banner:     printableBanner(r.BannerBytes, 1024),
+	}
+}
+
+// WebServiceURL returns the base url for an identified http/https/ssl/http service, or "" otherwise.
+func WebServiceURL(r ServiceResultRow) string {
+	name := strings.ToLower(strings.TrimSpace(r.Service))
+	scheme := ""
+	switch {
+	case name == "https" || strings.HasPrefix(name, "ssl/http"):
+		scheme = "https"
+	case strings.HasPrefix(name, "http"):
+		scheme = "http"
+	default:
+		return ""
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(r.IP, strconv.Itoa(r.Port)))
+}
+
+// printableBanner keeps text banners readable and escapes binary bytes as \xNN.
+func printableBanner(b []byte, max int) string {
+	if max > 0 && len(b) > max {
+		b = b[:max]
+	}
+	var sb strings.Builder
+	for _, c := range b {
+		switch {
+		case c == '\n' || c == '\r' || c == '\t':
+			sb.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&sb, "\\x%02x", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+func ScanWithIpAndPort(addr []ProtocolInfo) []ServiceResultRow {
 	Info("Total addr(s): %d", len(addr))
 	ConfigInit()
 	runtime.GOMAXPROCS(runtime.NumCPU())
@@ -941,14 +1007,19 @@ func ScanWithIpAndPort(addr []ProtocolInfo) {
 	wgWorkers.Add(int(config.Routines))
 
 	// 启动协程并开始监听处理输入的 Target
+	ck := NewCheckpoint(GetSpiderDB(), "service", viper.GetBool("resume"))
+	defer ck.Close()
+	var resumed []ServiceResultRow
+
 	for i := 0; i < config.Routines; i++ {
-		worker := Worker{inTargetChan, outResultChan, &config}
+		worker := Worker{inTargetChan, outResultChan, &config, ck}
 		worker.Start(&v, &wgWorkers)
 	}
 
 	// 实时结果输出协程
 	wgOutput := sync.WaitGroup{}
 	wgOutput.Add(1)
+	var identified []ServiceResultRow
 
 	go func(wg *sync.WaitGroup) {
 		for {
@@ -972,6 +1043,14 @@ func ScanWithIpAndPort(addr []ProtocolInfo) {
 				if banner != "" {
 					Info("Banner for %s:\n%s", ServiceInfoResult, hex.Dump([]byte(banner)))
 				}
+				row := serviceRowFromResult(result)
+				identified = append(identified, row)
+				if err := SaveServiceResult(GetSpiderDB(), row); err != nil {
+					Debug("save service result %s failed: %v", ServiceInfoResult, err)
+				}
+				if data, err := json.Marshal(row); err == nil {
+					ck.Mark(result.Target.GetAddress(), result.Target.Protocol, string(data))
+				}
 				bar.Increment()
 			} else {
 				break
@@ -986,6 +1065,15 @@ func ScanWithIpAndPort(addr []ProtocolInfo) {
 			Port:     a.Port,
 			Protocol: "tcp",
 		}
+		if ck.Done(target.GetAddress(), target.Protocol) {
+			data, _ := ck.Data(target.GetAddress(), target.Protocol)
+			var row ServiceResultRow
+			if data != "" && json.Unmarshal([]byte(data), &row) == nil {
+				resumed = append(resumed, row)
+			}
+			bar.Increment()
+			continue
+		}
 		inTargetChan <- target
 	}
 	close(inTargetChan)
@@ -995,4 +1083,8 @@ func ScanWithIpAndPort(addr []ProtocolInfo) {
 	Debug("Output goroutine finished")
 	wgOutput.Wait()
 	bar.Finish()
+	if skipped := ck.Skipped(); skipped > 0 {
+		Info("resume: skipped %d finished service probe(s)", skipped)
+	}
+	return append(identified, resumed...)
 }
@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestPortServicesPersisted(t *testing.T) {
+	db, err := InitSpiderDB(filepath.Join(t.TempDir(), "spider.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	defer db.Close()
+
+	if err := SaveOpenPorts(db, []OpenPortRow{
+		{IP: "10.0.0.1", Port: 22, Protocol: "tcp"},
+		{IP: "10.0.0.1", Port: 8080, Protocol: "tcp"},
+		{IP: "10.0.0.1", Port: 22, Protocol: "tcp"},
+	}); err != nil {
+		t.Fatalf("save open ports: %v", err)
+	}
+
+	res := Result{Target: Target{IP: "10.0.0.1", Port: 22, Protocol: "tcp"}}
+	res.Service.Name = "ssh"
+	res.Service.Extras = Extras{VendorProduct: "OpenSSH", Version: "8.9p1", CPE: "cpe:/a:openbsd:openssh:8.9p1"}
+	res.Service.Details.IsSoftMatched = true
+	res.BannerBytes = []byte("SSH-2.0-OpenSSH_8.9p1\x00\xff")
+	if err := SaveServiceResult(db, serviceRowFromResult(res)); err != nil {
+		t.Fatalf("save service: %v", err)
+	}
+
+	rows, err := LoadPortServices(db)
+	if err != nil {
+		t.Fatalf("load port services: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("rows = %d, want 2: %+v", len(rows), rows)
+	}
+	ssh := rows[0]
+	if ssh.Port != 22 || ssh.Service != "ssh" || ssh.Product != "OpenSSH" || ssh.CPE == "" || !ssh.SoftMatch {
+		t.Fatalf("unexpected ssh row: %+v", ssh)
+	}
+	if ssh.Banner != `SSH-2.0-OpenSSH_8.9p1\x00\xff` {
+		t.Fatalf("banner not escaped: %q", ssh.Banner)
+	}
+	if rows[1].Port != 8080 || rows[1].Service != "" {
+		t.Fatalf("open port without service should still be listed: %+v", rows[1])
+	}
+}
+
+func TestWebServiceURL(t *testing.T) {
+	tests := []struct {
+		svc  string
+		want string
+	}{
+		{"http", "http://10.0.0.1:8080"},
+		{"http-proxy", "http://10.0.0.1:8080"},
+		{"ssl/http", "https://10.0.0.1:8080"},
+		{"https", "https://10.0.0.1:8080"},
+		{"ssh", ""},
+	}
+	for _, tt := range tests {
+		got := WebServiceURL(ServiceResultRow{IP: "10.0.0.1", Port: 8080, Service: tt.svc})
+		if got != tt.want {
+			t.Fatalf("WebServiceURL(%q) = %q, want %q", tt.svc, got, tt.want)
+		}
+	}
+}
+
+func TestSpiderSummaryLinksToService(t *testing.T) {
+	db, err := InitSpiderDB(filepath.Join(t.TempDir(), "spider.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	defer db.Close()
+
+	_ = SaveOpenPorts(db, []OpenPortRow{{IP: "10.0.0.2", Port: 8443, Protocol: "tcp"}})
+	_ = SaveServiceResult(db, ServiceResultRow{IP: "10.0.0.2", Port: 8443, Protocol: "tcp", Service: "ssl/http"})
+	if err := SaveSpiderSummary(db, SpiderRecord{Url: "https://10.0.0.2:8443", Status: 200, ServiceIP: "10.0.0.2", ServicePort: 8443}); err != nil {
+		t.Fatalf("save summary: %v", err)
+	}
+	// a later plain spider run must not drop the link
+	if err := SaveSpiderSummary(db, SpiderRecord{Url: "https://10.0.0.2:8443", Status: 200}); err != nil {
+		t.Fatalf("save summary: %v", err)
+	}
+
+	rows, err := LoadPortServices(db)
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("load port services: %v %+v", err, rows)
+	}
+	if rows[0].SpiderURL != "https://10.0.0.2:8443" {
+		t.Fatalf("spider url = %q", rows[0].SpiderURL)
+	}
+	recs, _ := LoadSpiderSummaries(db)
+	if len(recs) != 1 || recs[0].ServiceIP != "10.0.0.2" || recs[0].ServicePort != 8443 {
+		t.Fatalf("summary link lost: %+v", recs)
+	}
+}
+
+func TestPortScanJournalsHosts(t *testing.T) {
+	open, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("skip: cannot listen (%v)", err)
+	}
+	defer open.Close()
+	go func() {
+		for {
+			c, err := open.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+	openPort := open.Addr().(*net.TCPAddr).Port
+
+	db, err := InitSpiderDB(filepath.Join(t.TempDir(), "spider.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	defer db.Close()
+	oldDB := GetSpiderDB()
+	SetSpiderDB(db)
+	defer SetSpiderDB(oldDB)
+	for k, v := range map[string]any{"threads": 4, "private-ip": true, "resume": false, "port-dial-timeout": 1} {
+		old := viper.Get(k)
+		viper.Set(k, v)
+		defer viper.Set(k, old)
+	}
+
+	ports := fmt.Sprintf("%d,%d", openPort, closedPort)
+	PortScan("127.0.0.1", ports)
+	var units []string
+	rows, err := db.Query(`SELECT task, data FROM checkpoints WHERE scope = 'port' ORDER BY task`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var task, data string
+		rows.Scan(&task, &data)
+		units = append(units, task+"="+data)
+	}
+	rows.Close()
+	// the open port and the finished host, nothing for the closed port
+	want := []string{strconv.Itoa(openPort) + "=open", portListKey([]int{openPort, closedPort}) + "="}
+	sort.Strings(want)
+	if fmt.Sprint(units) != fmt.Sprint(want) {
+		t.Fatalf("journal %v, want %v", units, want)
+	}
+	// the open port never answered a probe, so service identification did not finish it
+	var serviceUnits int
+	db.QueryRow(`SELECT COUNT(*) FROM checkpoints WHERE scope = 'service'`).Scan(&serviceUnits)
+	if serviceUnits != 0 {
+		t.Fatalf("%d silent port(s) journaled as identified", serviceUnits)
+	}
+
+	// the resumed run probes nothing and rebuilds the open port from the journal alone
+	viper.Set("resume", true)
+	open.Close()
+	db.Exec(`DELETE FROM open_ports`)
+	PortScan("127.0.0.1", ports)
+	opened, err := LoadOpenPorts(db)
+	if err != nil || len(opened) != 1 || opened[0].Port != openPort {
+		t.Fatalf("open port not restored on resume: %v %+v", err, opened)
+	}
+}
@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"hash/crc32"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Offline validation status of a secret hit. Nothing here touches the network: the checks are
+// structural (decoding, formats, checksums) plus a placeholder heuristic.
+const (
+	SecretValidated  = "validated"
+	SecretLikelyFake = "likely_fake"
+	SecretUnverified = "unverified"
+	// SecretMasked marks values the server redacted before returning them (actuator "******").
+	SecretMasked = "masked"
+)
+
+var (
+	placeholderWords = []string{"example", "placeholder", "changeme", "change_me", "your_", "your-", "yourkey", "dummy",
+		"sample", "fake", "xxxx", "****", "todo", "replace", "insert", "<", "${", "{{", "%s"}
+	placeholderValues = map[string]bool{"123456": true, "12345678": true, "password": true, "passwd": true, "test": true,
+		"admin": true, "null": true, "undefined": true, "none": true, "secret": true, "token": true, "demo": true, "true": true,
+		"false": true, "string": true, "value": true, "key": true, "abc123": true, "qwerty": true, "root": true}
+	awsKeyIDRe    = regexp.MustCompile(`^(AKIA|ASIA|ABIA|ACCA)[A-Z2-7]{16}$`)
+	aliyunKeyIDRe = regexp.MustCompile(`^LTAI[0-9A-Za-z]{12}([0-9A-Za-z]{8})?$`)
+	tencentIDRe   = regexp.MustCompile(`^AKID[0-9A-Za-z]{32}$`)
+	githubTokenRe = regexp.MustCompile(`^(ghp|gho|ghu|ghs|ghr)_([0-9A-Za-z]{30})([0-9A-Za-z]{6})$`)
+)
+
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ValidateSecret checks a hit offline. value is the secret itself (for generic "key = value"
+// rules the right-hand side); detail describes what was decoded or why it looks fake.
+func ValidateSecret(ruleID, value string) (status, detail string) {
+	value = strings.TrimSpace(value)
+	if ruleID == "private-key" {
+		// base64 key material can contain any word; the PEM parse decides instead.
+		return checkPrivateKey(value)
+	}
+	if reason := placeholderReason(value); reason != "" {
+		return SecretLikelyFake, reason
+	}
+	switch ruleID {
+	case "jwt":
+		return checkJWT(value)
+	case "aws-access-key-id":
+		return checkAWSKeyID(value)
+	case "aliyun-access-key-id":
+		if !aliyunKeyIDRe.MatchString(value) {
+			return SecretLikelyFake, fmt.Sprintf("LTAI key of %d chars, expected 16 or 24", len(value))
+		}
+		return SecretValidated, "format ok"
+	case "tencent-secret-id":
+		if !tencentIDRe.MatchString(value) {
+			return SecretLikelyFake, "AKID key is not 36 alphanumeric chars"
+		}
+		return SecretValidated, "format ok"
+	case "github-token":
+		return checkGitHubToken(value)
+	}
+	return SecretUnverified, ""
+}
+
+// placeholderReason flags values like "******", "xxx", "your_token_here" or "${TOKEN}".
+func placeholderReason(value string) string {
+	v := strings.Trim(strings.ToLower(value), `"'`+"`")
+	if v == "" {
+		return "empty value"
+	}
+	if placeholderValues[v] {
+		return "well-known test value " + v
+	}
+	if len(v) >= 3 && strings.Count(v, v[:1]) == len(v) {
+		return "repeated character"
+	}
+	for _, w := range placeholderWords {
+		if strings.Contains(v, w) {
+			return "placeholder pattern " + strings.TrimSpace(w)
+		}
+	}
+	return ""
+}
+
+func checkJWT(token string) (string, string) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return SecretLikelyFake, "not three dot-separated parts"
+	}
+	var header, claims map[string]any
+	for i, dst := range []*map[string]any{&header, &claims} {
+		raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[i], "="))
+		if err != nil {
+			return SecretLikelyFake, "invalid base64url segment"
+		}
+		if err := json.Unmarshal(raw, dst); err != nil {
+			return SecretLikelyFake, "segment is not json"
+		}
+	}
+	alg, _ := header["alg"].(string)
+	if alg == "" {
+		return SecretLikelyFake, "header has no alg"
+	}
+	detail := []string{"alg=" + alg}
+	if exp, ok := claims["exp"].(float64); ok {
+		t := time.Unix(int64(exp), 0).UTC()
+		state := ""
+		if t.Before(time.Now()) {
+			state = " (expired)"
+		}
+		detail = append(detail, "exp="+t.Format(time.RFC3339)+state)
+	}
+	for _, k := range []string{"iss", "sub", "aud"} {
+		if v, ok := claims[k]; ok {
+			detail = append(detail, fmt.Sprintf("%s=%v", k, v))
+		}
+	}
+	return SecretValidated, strings.Join(detail, " ")
+}
+
+// checkAWSKeyID verifies the base32 alphabet and decodes the account id embedded in the key.
+func checkAWSKeyID(key string) (string, string) {
+	if !awsKeyIDRe.MatchString(key) {
+		return SecretLikelyFake, "not a base32 AWS key id"
+	}
+	raw, err := base32.StdEncoding.DecodeString(key[4:])
+	if err != nil || len(raw) < 6 {
+		return SecretLikelyFake, "base32 decode failed"
+	}
+	buf := make([]byte, 8)
+	copy(buf[2:], raw[:6])
+	account := (binary.BigEndian.Uint64(buf) & 0x7fffffffff80) >> 7
+	return SecretValidated, fmt.Sprintf("account=%012d", account)
+}
+
+// checkGitHubToken verifies the CRC32 checksum carried in the last six base62 characters.
+func checkGitHubToken(token string) (string, string) {
+	m := githubTokenRe.FindStringSubmatch(token)
+	if m == nil {
+		if strings.HasPrefix(token, "github_pat_") {
+			return SecretUnverified, "fine-grained token"
+		}
+		return SecretLikelyFake, "unexpected token layout"
+	}
+	if base62CRC32(m[2]) != m[3] {
+		return SecretLikelyFake, "checksum mismatch"
+	}
+	return SecretValidated, "checksum ok"
+}
+
+func base62CRC32(s string) string {
+	n := crc32.ChecksumIEEE([]byte(s))
+	out := make([]byte, 6)
+	for i := 5; i >= 0; i-- {
+		out[i] = base62Alphabet[n%62]
+		n /= 62
+	}
+	return string(out)
+}
+
+// checkPrivateKey parses a PEM block (with JS "\n" escapes undone) and reports type and size.
+func checkPrivateKey(value string) (string, string) {
+	value = strings.NewReplacer(`\r`, "", `\n`, "\n").Replace(value)
+	block, _ := pem.Decode([]byte(value))
+	if block == nil {
+		return SecretLikelyFake, "pem decode failed"
+	}
+	if block.Type == "OPENSSH PRIVATE KEY" {
+		if len(block.Bytes) < 64 {
+			return SecretLikelyFake, "openssh key too short"
+		}
+		return SecretValidated, "type=openssh"
+	}
+	if strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED") || block.Type == "ENCRYPTED PRIVATE KEY" {
+		return SecretValidated, "type=" + block.Type + " (encrypted)"
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return SecretLikelyFake, "unparseable " + strings.ToLower(block.Type)
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return SecretValidated, fmt.Sprintf("type=rsa bits=%d", k.N.BitLen())
+	case *ecdsa.PrivateKey:
+		return SecretValidated, fmt.Sprintf("type=ecdsa bits=%d", k.Curve.Params().BitSize)
+	case ed25519.PrivateKey:
+		return SecretValidated, "type=ed25519 bits=256"
+	}
+	return SecretValidated, "type=" + strings.ToLower(block.Type)
+}
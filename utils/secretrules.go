@@ -0,0 +1,251 @@
+package utils
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Confidence levels of secret rules. Rules with ConfidenceInfo collect recon data (urls, internal
+// ips, phone numbers) instead of secrets.
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+	ConfidenceInfo   = "info"
+)
+
+//go:embed secrets.json
+var secretRulesJSON string
+
+// SecretRule is one detector of the sensitive-info registry.
+type SecretRule struct {
+	ID       string `json:"id"`
+	Category string `json:"category"`
+	Regex    string `json:"regex"`
+	// Group is the capture group stored as the hit; EntropyGroup the one measured (defaults to Group).
+	Group        int `json:"group"`
+	EntropyGroup int `json:"entropy_group"`
+	// Keywords is a case-insensitive prefilter: the regex only runs when one of them occurs.
+	Keywords   []string `json:"keywords"`
+	Entropy    float64  `json:"entropy"`
+	Confidence string   `json:"confidence"`
+	// Allowlist drops hits whose value matches any of these regexes (examples, placeholders).
+	Allowlist []string `json:"allowlist"`
+	// NoiseFilter applies the generic "password = ..." noise heuristics to hits.
+	NoiseFilter bool `json:"noise_filter"`
+
+	re      *regexp.Regexp
+	allowRe []*regexp.Regexp
+}
+
+type secretRulePack struct {
+	Rules []SecretRule `json:"rules"`
+}
+
+var (
+	secretRulesMu sync.Mutex
+	secretRules   []*SecretRule
+)
+
+// InitSecretRules loads the embedded registry plus user rule files (or directories of *.json).
+// A user rule replaces the built-in rule with the same id.
+func InitSecretRules(paths []string) error {
+	rules, err := LoadSecretRules(paths)
+	if err != nil {
+		return err
+	}
+	secretRulesMu.Lock()
+	defer secretRulesMu.Unlock()
+	secretRules = rules
+	if len(paths) > 0 {
+		Info("secret rules: %d rule(s) loaded (built-in + %d user path(s))", len(rules), len(paths))
+	}
+	return nil
+}
+
+func currentSecretRules() []*SecretRule {
+	secretRulesMu.Lock()
+	defer secretRulesMu.Unlock()
+	if secretRules == nil {
+		rules, err := LoadSecretRules(nil)
+		if err != nil {
+			Error("load built-in secret rules failed: %v", err)
+		}
+		secretRules = rules
+	}
+	return secretRules
+}
+
+func LoadSecretRules(paths []string) ([]*SecretRule, error) {
+	rules, err := parseSecretRules([]byte(secretRulesJSON), "builtin")
+	if err != nil {
+		return nil, err
+	}
+	files, err := expandRulePaths("secret rules", paths)
+	if err != nil {
+		return rules, err
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return rules, fmt.Errorf("read secret rules %s: %w", f, err)
+		}
+		user, err := parseSecretRules(data, filepath.Base(f))
+		if err != nil {
+			return rules, fmt.Errorf("%s: %w", f, err)
+		}
+		for _, r := range user {
+			replaced := false
+			for i := range rules {
+				if rules[i].ID == r.ID {
+					rules[i] = r
+					replaced = true
+				}
+			}
+			if !replaced {
+				rules = append(rules, r)
+			}
+		}
+	}
+	return rules, nil
+}
+
+func parseSecretRules(data []byte, pack string) ([]*SecretRule, error) {
+	var p secretRulePack
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unmarshal secret rules %s: %w", pack, err)
+	}
+	out := make([]*SecretRule, 0, len(p.Rules))
+	for i := range p.Rules {
+		r := p.Rules[i]
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("secret rule #%d (%s): %w", i, r.ID, err)
+		}
+		out = append(out, &r)
+	}
+	return out, nil
+}
+
+func (r *SecretRule) compile() error {
+	if strings.TrimSpace(r.ID) == "" {
+		return fmt.Errorf("empty id")
+	}
+	re, err := regexp.Compile(r.Regex)
+	if err != nil {
+		return fmt.Errorf("bad regex: %w", err)
+	}
+	if r.Group < 0 || r.Group > re.NumSubexp() || r.EntropyGroup < 0 || r.EntropyGroup > re.NumSubexp() {
+		return fmt.Errorf("capture group out of range (regex has %d)", re.NumSubexp())
+	}
+	switch r.Confidence {
+	case "":
+		r.Confidence = ConfidenceMedium
+	case ConfidenceHigh, ConfidenceMedium, ConfidenceLow, ConfidenceInfo:
+	default:
+		return fmt.Errorf("unknown confidence %q", r.Confidence)
+	}
+	if r.Category == "" {
+		r.Category = r.ID
+	}
+	if r.EntropyGroup == 0 {
+		r.EntropyGroup = r.Group
+	}
+	r.re = re
+	r.allowRe = r.allowRe[:0]
+	for _, a := range r.Allowlist {
+		are, err := regexp.Compile(a)
+		if err != nil {
+			return fmt.Errorf("bad allowlist %q: %w", a, err)
+		}
+		r.allowRe = append(r.allowRe, are)
+	}
+	for i, k := range r.Keywords {
+		r.Keywords[i] = strings.ToLower(k)
+	}
+	return nil
+}
+
+// IsSecret reports whether hits of the rule are credentials rather than recon data.
+func (r *SecretRule) IsSecret() bool {
+	return r.Confidence != ConfidenceInfo
+}
+
+// SecretMatch is one hit of a rule; Content is the stored capture group.
+type SecretMatch struct {
+	Rule    *SecretRule
+	Content string
+	Value   string
+	Entropy float64
+}
+
+// Find runs the rule over lowered (the lowercase copy of content, for the keyword prefilter).
+func (r *SecretRule) Find(content, lowered string) []SecretMatch {
+	if len(r.Keywords) > 0 {
+		hit := false
+		for _, k := range r.Keywords {
+			if strings.Contains(lowered, k) {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			return nil
+		}
+	}
+	var out []SecretMatch
+	for _, m := range r.re.FindAllStringSubmatch(content, -1) {
+		value := m[r.EntropyGroup]
+		if value == "" {
+			continue
+		}
+		entropy := calculateEntropy(value)
+		if r.Entropy > 0 && entropy < r.Entropy {
+			continue
+		}
+		if r.allowed(m[r.Group]) {
+			continue
+		}
+		out = append(out, SecretMatch{Rule: r, Content: m[r.Group], Value: value, Entropy: entropy})
+	}
+	return out
+}
+
+func (r *SecretRule) allowed(value string) bool {
+	for _, a := range r.allowRe {
+		if a.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandRulePaths turns files and directories (all *.json inside) into a sorted file list.
+func expandRulePaths(kind string, paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", kind, p, err)
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(p, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
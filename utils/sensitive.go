@@ -16,26 +16,12 @@ import (
 type SensitiveData struct {
 	Content string
 	Entropy float64
+	// Value is the secret part of Content; Status/Detail come from ValidateSecret.
+	Value  string
+	Status string
+	Detail string
 }
 
-func SecList() string {
-	return "(?:" + strings.Join([]string{"secret", "secret[_-]?key", "token", "secret[_-]?token", "password",
-		"aws[_-]?access[_-]?key[_-]?id", "aws[_-]?secret[_-]?access[_-]?key", "auth[-_]?token", "access[-_]?token",
-		"auth[-_]?key", "client[-_]?secret", "access[-_]?key(?:secret|id)?",
-		"id_dsa", "encryption[-_]?key", "passwd", "authorization", "bearer", "GITHUB[_-]?TOKEN",
-		"api[_-]?key", "api[-_]?secret", "client[_-]?key", "ssh[-_]?key",
-		"ssh[-_]?key", "irc_pass", "xoxa-2", "xoxr", "private[_-]?key", "consumer[_-]?key", "consumer[_-]?secret",
-		"SLACK_BOT_TOKEN", "api[-_]?token", "session[_-]?token", "session[_-]?key",
-		"session[_-]?secret", "slack[_-]?token"}, "|") + ")"
-}
-
-// password = "123"
-// password := "123"
-// password === "123"
-// password == "123"
-// password != "123"
-// "123" != password
-
 func calculateEntropy(str string) float64 {
 	charCount := make(map[rune]int)
 
@@ -122,10 +108,14 @@ func PrintTable(Url string, key string, data []SensitiveData) {
 	table.SetColumnConfigs([]prettytable.ColumnConfig{
 		{Number: 1, WidthMax: 80},
 	})
-	table.AppendHeader(prettytable.Row{"Content", "Entropy"})
+	table.AppendHeader(prettytable.Row{"Content", "Entropy", "Status"})
 
 	for _, d := range data {
-		table.AppendRow(prettytable.Row{d.Content, fmt.Sprintf("%.2f", d.Entropy)})
+		status := d.Status
+		if d.Detail != "" {
+			status += " (" + d.Detail + ")"
+		}
+		table.AppendRow(prettytable.Row{d.Content, fmt.Sprintf("%.2f", d.Entropy), status})
 	}
 	if table.Length() >= 1 {
 		table.Render()
@@ -144,76 +134,65 @@ func UrlFilter(Url string) bool {
 }
 func SensitiveInfoCollect(db *sql.DB, Url string, Content string, directory string) {
 	Info("Checking sensitive info in %s", Url)
-	space := `[\s]{0,30}`
-	mustQuote := "['\"`]"
-	quote := "['\"`]?"
-	content := `([\w\.\!\@\#\$\%\^\&\*\~\-\+ \=]{2,500})`
-	// x := '123456', x = '123456', x == '123456' x === '123456'  x !== '123456' x != '123456'
-	equals := `(:=|=|==|===|!==|!=|:)`
-	// '123456' == x '123456' === x  '123456' !== x  '123456' != x
-	equalss := `(==|===|!==|!=)`
-	sec := SecList()
-
-	infoMap := make(map[string]string)
-
-	infoMap["Chinese Mobile Number"] = `[^\d]((?:(?:\+|00)86)?1(?:(?:3[\d])|(?:4[5-79])|(?:5[0-35-9])|(?:6[5-7])|(?:7[0-8])|(?:8[\d])|(?:9[189]))\d{8})[^\d]`
-	infoMap["Internal IP Address"] = `[^0-9]((10\.([0-1]?[0-9]{1,2}|2[0-4][0-9]|25[0-5])\.([0-1]?[0-9]{1,2}|2[0-4][0-9]|25[0-5])\.([0-1]?[0-9]{1,2}|2[0-4][0-9]|25[0-5]))|(172\.((1[6-9]|2[0-9]|3[0-1]))\.([0-1]?[0-9]{1,2}|2[0-4][0-9]|25[0-5])\.([0-1]?[0-9]{1,2}|2[0-4][0-9]|25[0-5]))|(192\.168\.([0-1]?[0-9]{1,2}|2[0-4][0-9]|25[0-5])\.([0-1]?[0-9]{1,2}|2[0-4][0-9]|25[0-5])))`
-	infoMap["Url"] = `((https?|ftp)://(?:[^\s:@/]+(?::[^\s:@/]*)?@)?[\w_\-\.]{5,256}(?::\d+)?(?:[/?][\w_\-\&\#/%.]*)?)`
-	// 内容在右边
-	infoMap["security-rule-0"] = `(?i)` + `(` + quote + sec + quote + space + equals + space + mustQuote + content + mustQuote + `)`
-	// 内容在左边
-	infoMap["security-rule-1"] = `(?i)` + `(` + mustQuote + content + mustQuote + space + equalss + space + quote + sec + quote + `)`
-
-	for key := range infoMap {
-		reg := regexp.MustCompile(infoMap[key])
-		res := reg.FindAllStringSubmatch(html.UnescapeString(Content), -1)
-		secData := []SensitiveData{}
-		otherData := []string{}
-		if len(res) > 0 {
-			for _, tmp := range res {
-				switch key {
-				case "security-rule-0":
-					entropy := calculateEntropy(tmp[3])
-					secData = append(secData, SensitiveData{Content: tmp[1], Entropy: entropy})
-				case "security-rule-1":
-					entropy := calculateEntropy(tmp[2])
-					secData = append(secData, SensitiveData{Content: tmp[1], Entropy: entropy})
-				default:
-					otherData = append(otherData, tmp[0])
-				}
+	text := html.UnescapeString(Content)
+	lowered := strings.ToLower(text)
+	for _, rule := range currentSecretRules() {
+		matches := rule.Find(text, lowered)
+		if len(matches) == 0 {
+			continue
+		}
+		if !rule.IsSecret() {
+			otherData := []string{}
+			for _, m := range matches {
+				otherData = append(otherData, m.Content)
 			}
 			otherData = RemoveDuplicatesString(otherData)
-			if len(otherData) > 0 {
-				FileWrite(directory+"urls.txt", "======[%s]======[%s]\n%s", Url, key, strings.Join(otherData, "\n")+"\n")
-				InfoFile("[%s] [%s] %d item(s) saved to ./%s\n%s", Url, key, len(otherData), directory+"urls.txt", strings.Join(otherData, "\n"))
-				var hits []SensitiveHit
-				for _, c := range otherData {
-					hits = append(hits, SensitiveHit{
-						SourceURL: Url,
-						Category:  key,
-						Content:   c,
-						Entropy:   0,
-						SaveDir:   directory,
-					})
-				}
-				SaveSensitiveHits(db, hits)
-			}
-			if len(secData) > 0 {
-				dedup := DeduplicateByContent(secData)
-				dedup = filterLowValue(dedup)
-				PrintTable(Url, key, dedup)
-				var hits []SensitiveHit
-				for _, d := range dedup {
-					hits = append(hits, SensitiveHit{
-						SourceURL: Url,
-						Category:  key,
-						Content:   d.Content,
-						Entropy:   d.Entropy,
-						SaveDir:   directory,
-					})
-				}
-				SaveSensitiveHits(db, hits)
+			FileWrite(directory+"urls.txt", "======[%s]======[%s]\n%s", Url, rule.Category, strings.Join(otherData, "\n")+"\n")
+			InfoFile("[%s] [%s] %d item(s) saved to ./%s\n%s", Url, rule.Category, len(otherData), directory+"urls.txt", strings.Join(otherData, "\n"))
+			var hits []SensitiveHit
+			for _, c := range otherData {
+				hits = append(hits, SensitiveHit{
+					SourceURL:  Url,
+					Category:   rule.Category,
+					RuleID:     rule.ID,
+					Confidence: rule.Confidence,
+					Content:    c,
+					Entropy:    0,
+					SaveDir:    directory,
+				})
 			}
+			SaveSensitiveHits(db, hits)
+			continue
+		}
+		secData := []SensitiveData{}
+		for _, m := range matches {
+			secData = append(secData, SensitiveData{Content: m.Content, Entropy: m.Entropy, Value: m.Value})
+		}
+		dedup := DeduplicateByContent(secData)
+		if rule.NoiseFilter {
+			dedup = filterLowValue(dedup)
+		}
+		if len(dedup) == 0 {
+			continue
+		}
+		for i := range dedup {
+			dedup[i].Status, dedup[i].Detail = ValidateSecret(rule.ID, dedup[i].Value)
+		}
+		PrintTable(Url, rule.Category, dedup)
+		var hits []SensitiveHit
+		for _, d := range dedup {
+			hits = append(hits, SensitiveHit{
+				SourceURL:  Url,
+				Category:   rule.Category,
+				RuleID:     rule.ID,
+				Confidence: rule.Confidence,
+				Content:    d.Content,
+				Entropy:    d.Entropy,
+				Status:     d.Status,
+				Detail:     d.Detail,
+				SaveDir:    directory,
+			})
 		}
+		SaveSensitiveHits(db, hits)
 	}
 }
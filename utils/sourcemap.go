@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// sourceMapDoc is the subset of the source map v3 format needed to rebuild original files.
+type sourceMapDoc struct {
+	Version        int       `json:"version"`
+	SourceRoot     string    `json:"sourceRoot"`
+	Sources        []string  `json:"sources"`
+	SourcesContent []*string `json:"sourcesContent"`
+}
+
+// SourceMapFile is one original source recovered from a source map.
+type SourceMapFile struct {
+	Source  string
+	Path    string
+	Content string
+}
+
+func parseSourceMap(body []byte) (sourceMapDoc, error) {
+	var doc sourceMapDoc
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return doc, err
+	}
+	if len(doc.Sources) == 0 {
+		return doc, fmt.Errorf("source map has no sources")
+	}
+	return doc, nil
+}
+
+// sanitizeSourcePath turns a source map entry like "webpack:///./src/api/user.js" into a safe relative path.
+// Any ".." segments are dropped so the result always stays below the extraction directory.
+func sanitizeSourcePath(source string) string {
+	s := strings.TrimSpace(source)
+	if i := strings.Index(s, "://"); i >= 0 {
+		s = s[i+3:]
+	}
+	if i := strings.IndexAny(s, "?#"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.ReplaceAll(s, "\\", "/")
+	var parts []string
+	for _, p := range strings.Split(s, "/") {
+		p = strings.TrimSpace(p)
+		if p == "" || p == "." || p == ".." || strings.HasSuffix(p, ":") {
+			continue
+		}
+		parts = append(parts, p)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return path.Join(parts...)
+}
+
+// writeSourceMapFiles writes every source with inline content under baseDir and returns what was written.
+func writeSourceMapFiles(doc sourceMapDoc, baseDir string) ([]SourceMapFile, error) {
+	absBase, err := filepath.Abs(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	// every name written so far; a duplicate source gets the first free "name.N"
+	seen := make(map[string]bool)
+	var out []SourceMapFile
+	for i, src := range doc.Sources {
+		if i >= len(doc.SourcesContent) || doc.SourcesContent[i] == nil {
+			continue
+		}
+		rel := sanitizeSourcePath(path.Join(doc.SourceRoot, src))
+		if rel == "" {
+			rel = fmt.Sprintf("source_%d.js", i)
+		}
+		for n, base := 1, rel; seen[rel]; n++ {
+			rel = fmt.Sprintf("%s.%d", base, n)
+		}
+		seen[rel] = true
+		target := filepath.Join(absBase, filepath.FromSlash(rel))
+		if target != absBase && !strings.HasPrefix(target, absBase+string(os.PathSeparator)) {
+			Debug("skip source map entry outside output dir: %s", src)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return out, err
+		}
+		content := *doc.SourcesContent[i]
+		if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
+			return out, err
+		}
+		out = append(out, SourceMapFile{Source: src, Path: target, Content: content})
+	}
+	return out, nil
+}
+
+func downloadSourceMap(mapURL string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, mapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	SetHeaders(req)
+	resp, err := Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	maxBody := viper.GetInt("sourcemap-max-bytes")
+	if maxBody <= 0 {
+		maxBody = 32 * 1024 * 1024
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, int64(maxBody)))
+}
+
+// isScannableSource skips third-party dependencies and non-code assets bundled into the map.
+func isScannableSource(source string) bool {
+	ls := strings.ToLower(source)
+	if strings.Contains(ls, "node_modules/") || strings.Contains(ls, "(webpack)/") {
+		return false
+	}
+	for _, ext := range []string{".js", ".mjs", ".cjs", ".jsx", ".ts", ".tsx", ".vue", ".json", ".env"} {
+		if strings.HasSuffix(ls, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// unpackSourceMap downloads a full source map, rebuilds the original tree under saveDir
+// and runs API / sensitive extraction over the recovered first-party sources.
+func unpackSourceMap(rootURL, mapURL, saveDir string, apiCounter *int, db *sql.DB) {
+	body, err := downloadSourceMap(mapURL)
+	if err != nil {
+		Debug("source map download failed %s: %v", mapURL, err)
+		return
+	}
+	extractSourceMap(rootURL, mapURL, saveDir, body, apiCounter, db)
+}
+
+// extractSourceMap rebuilds and scans the sources of an already fetched source map body.
+func extractSourceMap(rootURL, mapURL, saveDir string, body []byte, apiCounter *int, db *sql.DB) {
+	doc, err := parseSourceMap(body)
+	if err != nil {
+		Debug("source map parse failed %s: %v", mapURL, err)
+		return
+	}
+	u, err := url.Parse(mapURL)
+	if err != nil {
+		return
+	}
+	name := sanitizeSourcePath(u.Path)
+	if name == "" {
+		name = "sourcemap"
+	}
+	baseDir := filepath.Join(saveDir, "sourcemap_src", strings.ReplaceAll(name, "/", "_"))
+	files, err := writeSourceMapFiles(doc, baseDir)
+	if err != nil {
+		Warning("source map unpack %s: %v", mapURL, err)
+	}
+	if len(files) == 0 {
+		Debug("source map %s has no inline sourcesContent", mapURL)
+		return
+	}
+	Success("source map unpacked: %s (%d/%d files) -> %s", mapURL, len(files), len(doc.Sources), baseDir)
+	if apiCounter == nil {
+		apiCounter = new(int)
+	}
+	for _, f := range files {
+		if !isScannableSource(f.Source) {
+			continue
+		}
+		srcURL := mapURL + "#" + f.Source
+		if _, ok := sensitiveUrl.Load(srcURL); !ok {
+			sensitiveUrl.Store(srcURL, true)
+			SensitiveInfoCollect(db, srcURL, f.Content, saveDir)
+		}
+		ParseJavaScriptUrl(srcURL, rootURL, f.Content, saveDir, apiCounter, db)
+	}
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestSanitizeSourcePath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"webpack:///./src/api/user.js", "src/api/user.js"},
+		{"webpack:///src/views/Login.vue?0d1a", "src/views/Login.vue"},
+		{"../../../etc/passwd", "etc/passwd"},
+		{"/abs/../path.ts", "abs/path.ts"},
+		{`C:\project\src\main.ts`, "project/src/main.ts"},
+		{"..", ""},
+	}
+	for _, tt := range tests {
+		if got := sanitizeSourcePath(tt.in); got != tt.want {
+			t.Fatalf("sanitizeSourcePath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteSourceMapFilesStaysInBase(t *testing.T) {
+	body := `{"version":3,"sourceRoot":"","sources":["webpack:///./src/a.js","../../evil.js","webpack:///./src/nocontent.js"],"sourcesContent":["const a=1;","x",null]}`
+	doc, err := parseSourceMap([]byte(body))
+	if err != nil {
+		t.Fatalf("parseSourceMap: %v", err)
+	}
+	base := t.TempDir()
+	files, err := writeSourceMapFiles(doc, base)
+	if err != nil {
+		t.Fatalf("writeSourceMapFiles: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("files = %d, want 2", len(files))
+	}
+	for _, f := range files {
+		if !strings.HasPrefix(f.Path, base) {
+			t.Fatalf("file escaped base dir: %s", f.Path)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(base, "src", "a.js")); err != nil || string(data) != "const a=1;" {
+		t.Fatalf("src/a.js content = %q err=%v", data, err)
+	}
+}
+
+func TestWriteSourceMapFilesDuplicateNames(t *testing.T) {
+	body := `{"version":3,"sources":["a.js","a.js.1","a.js","a.js"],"sourcesContent":["first","real a.js.1","second","third"]}`
+	doc, err := parseSourceMap([]byte(body))
+	if err != nil {
+		t.Fatalf("parseSourceMap: %v", err)
+	}
+	base := t.TempDir()
+	files, err := writeSourceMapFiles(doc, base)
+	if err != nil || len(files) != 4 {
+		t.Fatalf("files=%d err=%v", len(files), err)
+	}
+	for name, want := range map[string]string{"a.js": "first", "a.js.1": "real a.js.1", "a.js.2": "second", "a.js.3": "third"} {
+		if data, err := os.ReadFile(filepath.Join(base, name)); err != nil || string(data) != want {
+			t.Errorf("%s = %q err=%v, want %q", name, data, err, want)
+		}
+	}
+}
+
+// TestSpiderUnpacksSourceMap ensures the opt-in download mode feeds original sources into api_paths.
+func TestSpiderUnpacksSourceMap(t *testing.T) {
+	server := mustTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><script src="/app.js"></script></body></html>`))
+		case "/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			_, _ = w.Write([]byte(`var a=1;`))
+		case "/app.js.map":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"version":3,"sources":["webpack:///./src/api.js"],"sourcesContent":["axios.get(\"/internal/listUsers\")"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	oldClient, oldNoRedirect := Client, ClientNoRedirect
+	Client, ClientNoRedirect = server.Client(), server.Client()
+	t.Cleanup(func() {
+		Client, ClientNoRedirect = oldClient, oldNoRedirect
+	})
+
+	tmpDir := t.TempDir()
+	cwd, _ := os.Getwd()
+	defer os.Chdir(cwd)
+	_ = os.Chdir(tmpDir)
+
+	db, err := InitSpiderDB(filepath.Join(tmpDir, "spider.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	defer db.Close()
+	SetSpiderDB(db)
+
+	oldMaxBody := viper.Get("max-body-bytes")
+	viper.Set("max-body-bytes", 1024)
+	viper.Set("spider-sourcemap-download", true)
+	t.Cleanup(func() {
+		viper.Set("max-body-bytes", oldMaxBody)
+		viper.Set("spider-sourcemap-download", false)
+	})
+
+	summary := FingerSummary(server.URL, 2, db)
+	if summary.Err != nil {
+		t.Fatalf("finger summary error: %v", summary.Err)
+	}
+	paths, err := LoadAPIPaths(db)
+	if err != nil {
+		t.Fatalf("load api paths: %v", err)
+	}
+	found := false
+	for _, p := range paths {
+		if p.Path == "/internal/listUsers" && strings.HasSuffix(p.SourceURL, "#webpack:///./src/api.js") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("api from source map not stored, got %+v", paths)
+	}
+	if _, err := os.Stat(filepath.Join(summary.SaveDir, "sourcemap_src", "app.js.map", "src", "api.js")); err != nil {
+		t.Fatalf("reconstructed source missing: %v", err)
+	}
+}
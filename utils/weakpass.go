@@ -230,6 +230,15 @@ func combination(prefixList []string, keywordList []string, sepList []string, su
 }
 
 func GenerateWeakPassword() []string {
+	UniqPasswordList := BuildWeakPasswords()
+	outputListFormat(UniqPasswordList)
+	println("total:", len(UniqPasswordList))
+	return UniqPasswordList
+}
+
+// BuildWeakPasswords returns the deduplicated list GenerateWeakPassword prints, driven by the
+// same keyword/suffix/sep/prefix/full/variant settings.
+func BuildWeakPasswords() []string {
 	var PasswordList = []string{}
 	var KeywordList = getKeywordList()
 	var SuffixList = getSuffixList()
@@ -281,7 +290,5 @@ func GenerateWeakPassword() []string {
 		Error("%s", err)
 		return []string{}
 	}
-	outputListFormat(UniqPasswordList.([]string))
-	println("total:", len(UniqPasswordList.([]string)))
 	return UniqPasswordList.([]string)
 }
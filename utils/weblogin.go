@@ -0,0 +1,471 @@
+package utils
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/godspeedcurry/godscan/common"
+)
+
+const (
+	WebLoginUserMarker = "§USER§"
+	WebLoginPassMarker = "§PASS§"
+)
+
+var (
+	webLoginUserRe = regexp.MustCompile(`(?i)(user|login|account|acct|email|mail|name|phone|mobile|uid|uname)`)
+	// hidden fields that change per session: refetched before every attempt
+	webLoginCSRFRe    = regexp.MustCompile(`(?i)(csrf|xsrf|token|authenticity|nonce|__viewstate|__eventvalidation)`)
+	webLoginCaptchaRe = regexp.MustCompile(`(?i)(captcha|kaptcha|verify_?code|vcode|valicode|yzm)`)
+)
+
+// WebLogin is a login form detected on a page, or a raw request template with §USER§/§PASS§ markers.
+type WebLogin struct {
+	PageURL   string
+	Method    string
+	Action    string
+	UserField string
+	PassField string
+	// Fields holds the other inputs of the form (hidden fields, checked boxes, submit button).
+	Fields url.Values
+	// CSRFField is set when a hidden field looks per-session, the page is then fetched before every attempt.
+	CSRFField string
+	// Raw replaces the form when set, paths are relative to the root of PageURL.
+	Raw string
+	jar http.CookieJar
+}
+
+// WebLoginMatch decides whether a login response is a valid login. Fail conditions win; when no
+// success condition is set, a response differing from a wrong-password baseline in status or
+// redirect location (or length, with LengthDelta) counts as success.
+type WebLoginMatch struct {
+	SuccessStatus   []int
+	FailStatus      []int
+	SuccessLocation string
+	FailLocation    string
+	SuccessKeywords []string
+	FailKeywords    []string
+	// LengthDelta flags bodies whose length differs from the baseline by more than this, 0 to disable.
+	LengthDelta int
+}
+
+type webLoginResponse struct {
+	status   int
+	location string
+	body     []byte
+}
+
+// DetectWebLogin fetches pageURL and picks the form holding a password input.
+func DetectWebLogin(pageURL string) (*WebLogin, error) {
+	jar, _ := cookiejar.New(nil)
+	body, err := webLoginFetch(jar, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	w, err := parseLoginForm(pageURL, body)
+	if err != nil {
+		return nil, err
+	}
+	w.jar = jar
+	return w, nil
+}
+
+// ParseWebLoginRequest checks a raw request template sent against the root of pageURL.
+func ParseWebLoginRequest(pageURL, raw string) (*WebLogin, error) {
+	if !strings.Contains(raw, WebLoginPassMarker) {
+		return nil, fmt.Errorf("request template has no %s marker", WebLoginPassMarker)
+	}
+	w := &WebLogin{PageURL: pageURL, Raw: raw}
+	if _, err := w.build(nil, "user", "pass"); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func webLoginFetch(jar http.CookieJar, pageURL string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	SetHeaders(req)
+	client := *Client
+	client.Jar = jar
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := readResponseBody(resp)
+	return body, nil
+}
+
+func parseLoginForm(pageURL string, body []byte) (*WebLogin, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	form := doc.Find("form").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return s.Find(`input[type="password" i]`).Length() > 0
+	}).First()
+	if form.Length() == 0 {
+		// script driven pages often leave the inputs outside any form
+		if doc.Find(`input[type="password" i]`).Length() == 0 {
+			return nil, fmt.Errorf("no password field on %s, use a request template", pageURL)
+		}
+		form = doc.Selection
+	}
+	w := &WebLogin{PageURL: pageURL, Method: http.MethodGet, Action: pageURL, Fields: url.Values{}}
+	if !form.Is("form") || strings.EqualFold(strings.TrimSpace(form.AttrOr("method", "")), http.MethodPost) {
+		w.Method = http.MethodPost
+	}
+	if a, ok := form.Attr("action"); ok && strings.TrimSpace(a) != "" && !strings.HasPrefix(strings.TrimSpace(a), "javascript:") {
+		base, err := url.Parse(pageURL)
+		if err != nil {
+			return nil, err
+		}
+		ref, err := url.Parse(strings.TrimSpace(a))
+		if err != nil {
+			return nil, err
+		}
+		w.Action = base.ResolveReference(ref).String()
+	}
+	var texts []string
+	submitted := false
+	form.Find("input, select, textarea, button").Each(func(_ int, s *goquery.Selection) {
+		name, _ := s.Attr("name")
+		if name == "" {
+			return
+		}
+		typ := strings.ToLower(s.AttrOr("type", "text"))
+		if s.Is("button") {
+			typ = strings.ToLower(s.AttrOr("type", "submit"))
+		}
+		switch {
+		case typ == "password":
+			if w.PassField == "" {
+				w.PassField = name
+			}
+			return
+		case s.Is("select"):
+			opt := s.Find("option[selected]").First()
+			if opt.Length() == 0 {
+				opt = s.Find("option").First()
+			}
+			w.Fields.Set(name, opt.AttrOr("value", strings.TrimSpace(opt.Text())))
+			return
+		case s.Is("textarea"):
+			w.Fields.Set(name, s.Text())
+			return
+		}
+		switch typ {
+		case "text", "email", "tel", "number", "":
+			if w.PassField == "" {
+				texts = append(texts, name)
+			}
+			w.Fields.Set(name, s.AttrOr("value", ""))
+		case "hidden":
+			w.Fields.Set(name, s.AttrOr("value", ""))
+			if w.CSRFField == "" && webLoginCSRFRe.MatchString(name) {
+				w.CSRFField = name
+			}
+		case "checkbox", "radio":
+			if _, ok := s.Attr("checked"); ok {
+				w.Fields.Add(name, s.AttrOr("value", "on"))
+			}
+		case "submit", "image":
+			// only the clicked button is sent
+			if !submitted {
+				submitted = true
+				w.Fields.Set(name, s.AttrOr("value", ""))
+			}
+		}
+	})
+	for _, name := range texts {
+		if webLoginUserRe.MatchString(name) && !webLoginCaptchaRe.MatchString(name) {
+			w.UserField = name
+			break
+		}
+	}
+	if w.UserField == "" && len(texts) > 0 {
+		w.UserField = texts[0]
+	}
+	w.Fields.Del(w.UserField)
+	for name := range w.Fields {
+		if webLoginCaptchaRe.MatchString(name) {
+			Warning("login form of %s has a captcha field (%s), results are unreliable", pageURL, name)
+			break
+		}
+	}
+	return w, nil
+}
+
+// build returns the request of one attempt; a form with a CSRF field is fetched again first.
+func (w *WebLogin) build(jar http.CookieJar, user, pass string) (*http.Request, error) {
+	if w.Raw != "" {
+		u, err := url.Parse(w.PageURL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid target %q", w.PageURL)
+		}
+		return parsePocRaw(webLoginRender(w.Raw, user, pass), pocVars{"BaseURL": u.Scheme + "://" + u.Host})
+	}
+	fields := w.Fields
+	if w.CSRFField != "" {
+		body, err := webLoginFetch(jar, w.PageURL)
+		if err != nil {
+			return nil, err
+		}
+		fresh, err := parseLoginForm(w.PageURL, body)
+		if err != nil {
+			return nil, err
+		}
+		fields = fresh.Fields
+		fields.Del(w.UserField)
+	}
+	values := url.Values{}
+	for k, v := range fields {
+		values[k] = v
+	}
+	if w.UserField != "" {
+		values.Set(w.UserField, user)
+	}
+	values.Set(w.PassField, pass)
+	if w.Method == http.MethodGet {
+		u, err := url.Parse(w.Action)
+		if err != nil {
+			return nil, err
+		}
+		q := u.Query()
+		for k, v := range values {
+			q[k] = v
+		}
+		u.RawQuery = q.Encode()
+		req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		SetHeaders(req)
+		return req, nil
+	}
+	req, err := http.NewRequest(w.Method, w.Action, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	SetHeaders(req)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Referer", w.PageURL)
+	return req, nil
+}
+
+// webLoginRender fills the markers, escaped for the request line and the body content type.
+func webLoginRender(raw, user, pass string) string {
+	raw = strings.ReplaceAll(strings.TrimLeft(raw, "\r\n"), "\r\n", "\n")
+	head, body, hasBody := strings.Cut(raw, "\n\n")
+	head = strings.NewReplacer(WebLoginUserMarker, url.QueryEscape(user), WebLoginPassMarker, url.QueryEscape(pass)).Replace(head)
+	if !hasBody {
+		return head
+	}
+	esc := func(s string) string { return s }
+	ct := strings.ToLower(head)
+	switch {
+	case strings.Contains(ct, "content-type: application/x-www-form-urlencoded"):
+		esc = url.QueryEscape
+	case strings.Contains(ct, "json"):
+		esc = func(s string) string {
+			var b strings.Builder
+			enc := json.NewEncoder(&b)
+			enc.SetEscapeHTML(false)
+			enc.Encode(s)
+			out := strings.TrimSuffix(b.String(), "\n")
+			return out[1 : len(out)-1]
+		}
+	}
+	body = strings.NewReplacer(WebLoginUserMarker, esc(user), WebLoginPassMarker, esc(pass)).Replace(body)
+	return head + "\n\n" + body
+}
+
+// Target is the url the credentials are posted to.
+func (w *WebLogin) Target() string {
+	if w.Raw != "" {
+		if req, err := w.build(nil, "", ""); err == nil {
+			return req.URL.String()
+		}
+	}
+	if w.Action != "" {
+		return w.Action
+	}
+	return w.PageURL
+}
+
+func (w *WebLogin) attempt(user, pass string) (webLoginResponse, error) {
+	jar := w.jar
+	if w.CSRFField != "" {
+		// a fresh session per attempt, tokens are usually bound to it
+		jar, _ = cookiejar.New(nil)
+	}
+	req, err := w.build(jar, user, pass)
+	if err != nil {
+		return webLoginResponse{}, err
+	}
+	client := *enforceNoRedirectClient(ClientNoRedirect)
+	client.Jar = jar
+	resp, err := client.Do(req)
+	if err != nil {
+		return webLoginResponse{}, err
+	}
+	defer resp.Body.Close()
+	body, _ := readResponseBody(resp)
+	return webLoginResponse{status: resp.StatusCode, location: resp.Header.Get("Location"), body: body}, nil
+}
+
+func (m WebLoginMatch) hasSuccess() bool {
+	return len(m.SuccessStatus) > 0 || m.SuccessLocation != "" || len(m.SuccessKeywords) > 0 || m.LengthDelta > 0
+}
+
+func (m WebLoginMatch) hasFail() bool {
+	return len(m.FailStatus) > 0 || m.FailLocation != "" || len(m.FailKeywords) > 0
+}
+
+func (m WebLoginMatch) success(r, base webLoginResponse) bool {
+	if containsInt(m.FailStatus, r.status) || m.FailLocation != "" && strings.Contains(r.location, m.FailLocation) || webLoginContains(r.body, m.FailKeywords) {
+		return false
+	}
+	if m.hasSuccess() {
+		delta := len(r.body) - len(base.body)
+		return containsInt(m.SuccessStatus, r.status) ||
+			m.SuccessLocation != "" && strings.Contains(r.location, m.SuccessLocation) ||
+			webLoginContains(r.body, m.SuccessKeywords) ||
+			m.LengthDelta > 0 && (delta > m.LengthDelta || -delta > m.LengthDelta)
+	}
+	if m.hasFail() {
+		return true
+	}
+	return r.status != base.status || webLoginLocation(r.location) != webLoginLocation(base.location)
+}
+
+// webLoginLocation drops the query and ;jsessionid= so per-request tokens do not look like a change.
+func webLoginLocation(loc string) string {
+	loc, _, _ = strings.Cut(loc, "?")
+	loc, _, _ = strings.Cut(loc, ";")
+	return strings.TrimSuffix(loc, "/")
+}
+
+func webLoginContains(body []byte, keywords []string) bool {
+	for _, k := range keywords {
+		if k != "" && bytes.Contains(body, []byte(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// RunWebLogin tries username x password pairs one at a time against w, after a baseline attempt
+// with a random password. Valid logins are saved to brute_results with service "http".
+func RunWebLogin(w *WebLogin, match WebLoginMatch, opts BruteOptions, db *sql.DB) []BruteResult {
+	if opts.MaxErrors <= 0 {
+		opts.MaxErrors = 5
+	}
+	users := opts.Users
+	if len(users) == 0 {
+		users = common.Userdict["web"]
+	}
+	passwords := opts.Passwords
+	if len(passwords) == 0 {
+		passwords = common.Passwords
+	}
+	target := w.Target()
+	base, err := w.attempt(users[0], pocRandText(12))
+	if err != nil {
+		Error("weak %s: baseline request failed: %v", target, err)
+		return nil
+	}
+	if match.success(base, base) {
+		Warning("weak %s: a random password already matches the success conditions (status %d, location %q), giving up", target, base.status, base.location)
+		return nil
+	}
+	if !match.hasSuccess() && !match.hasFail() && !isRedirect(base.status) {
+		Info("weak %s: comparing status/redirect with the baseline (%d), add --fail-keyword or --length-delta for logins answering 200", target, base.status)
+	}
+
+	var tick <-chan time.Time
+	if opts.Rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(opts.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	var out []BruteResult
+	errs := 0
+	attempts := 0
+	for _, user := range users {
+		// unlike services, an empty password is rejected by the form itself
+		candidates := bruteCandidates(user, passwords)[1:]
+		tries := 0
+		for i := 0; i < len(candidates); i++ {
+			if opts.MaxPerUser > 0 && tries >= opts.MaxPerUser {
+				break
+			}
+			if tick != nil {
+				<-tick
+			}
+			if opts.Delay > 0 {
+				time.Sleep(opts.Delay)
+			}
+			pass := candidates[i]
+			r, err := w.attempt(user, pass)
+			if err == nil && (r.status == http.StatusTooManyRequests || r.status == http.StatusServiceUnavailable) {
+				err = errors.New(http.StatusText(r.status))
+			}
+			if err != nil {
+				errs++
+				Debug("weak %s %s: %v", target, user, err)
+				if errs >= opts.MaxErrors {
+					Warning("weak %s: %d errors in a row (%v), giving up", target, errs, err)
+					return out
+				}
+				i--
+				time.Sleep(time.Duration(errs) * time.Second)
+				continue
+			}
+			errs = 0
+			tries++
+			attempts++
+			if !match.success(r, base) {
+				continue
+			}
+			note := fmt.Sprintf("status %d", r.status)
+			if r.location != "" {
+				note += " -> " + r.location
+			}
+			res := BruteResult{Target: target, Service: "http", Username: user, Password: pass, Note: note}
+			Success("[weak] %s %s:%s %s", target, user, pass, note)
+			out = append(out, res)
+			if err := SaveBruteResults(db, []BruteResult{res}); err != nil {
+				Error("save brute results failed: %v", err)
+			}
+			if opts.StopOnSuccess {
+				return out
+			}
+			break
+		}
+	}
+	Info("weak %s: %d attempt(s), %d valid", target, attempts, len(out))
+	return out
+}
@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseLoginForm(t *testing.T) {
+	page := `<html><body>
+<form action="/search"><input name="q"></form>
+<form method="post" action="../auth/doLogin">
+  <input type="hidden" name="_csrf" value="tok1">
+  <input name="captcha">
+  <input type="email" name="j_email">
+  <input type="PASSWORD" name="j_password">
+  <select name="lang"><option value="en">en</option><option value="zh" selected>zh</option></select>
+  <input type="checkbox" name="remember" checked>
+  <input type="checkbox" name="public">
+  <button name="action" value="login">Login</button>
+  <button name="action" value="register">Register</button>
+</form></body></html>`
+	w, err := parseLoginForm("http://example.com/app/login.html", []byte(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Method != "POST" || w.Action != "http://example.com/auth/doLogin" || w.UserField != "j_email" || w.PassField != "j_password" || w.CSRFField != "_csrf" {
+		t.Fatalf("unexpected form %+v", w)
+	}
+	want := "_csrf=tok1&action=login&captcha=&lang=zh&remember=on"
+	if got := w.Fields.Encode(); got != want {
+		t.Fatalf("fields %s, want %s", got, want)
+	}
+
+	w, err = parseLoginForm("http://example.com/", []byte(`<div id="app"><input name="username"><input type="password" name="pwd"></div>`))
+	if err != nil || w.Method != "POST" || w.Action != "http://example.com/" || w.UserField != "username" || w.PassField != "pwd" {
+		t.Fatalf("formless inputs: %v %+v", err, w)
+	}
+	if _, err := parseLoginForm("http://example.com/", []byte(`<form><input name="q"></form>`)); err == nil {
+		t.Fatal("page without password field accepted")
+	}
+}
+
+func TestWebLoginRender(t *testing.T) {
+	raw := "POST /api/login?u=§USER§ HTTP/1.1\r\nHost: x\r\nContent-Type: application/json\r\n\r\n{\"u\":\"§USER§\",\"p\":\"§PASS§\"}"
+	got := webLoginRender(raw, "a b", `p"w&d`)
+	if !strings.HasPrefix(got, "POST /api/login?u=a+b HTTP/1.1\n") || !strings.HasSuffix(got, `{"u":"a b","p":"p\"w&d"}`) {
+		t.Fatalf("json render: %q", got)
+	}
+	got = webLoginRender("POST /login HTTP/1.1\nContent-Type: application/x-www-form-urlencoded\n\nu=§USER§&p=§PASS§", "root", "a&b=c")
+	if !strings.HasSuffix(got, "u=root&p=a%26b%3Dc") {
+		t.Fatalf("form render: %q", got)
+	}
+}
+
+// loginApp is a session + CSRF protected form login accepting admin / admin@123.
+func loginApp(t *testing.T) (http.Handler, *int) {
+	var (
+		mu       sync.Mutex
+		attempts int
+		sessions = map[string]string{}
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodGet {
+			sid := fmt.Sprintf("s%d", len(sessions))
+			sessions[sid] = "t" + sid
+			http.SetCookie(w, &http.Cookie{Name: "SID", Value: sid})
+			fmt.Fprintf(w, `<form method="post" action="/doLogin"><input type="hidden" name="csrf_token" value="%s"><input name="user"><input type="password" name="pass"></form>`, sessions[sid])
+			return
+		}
+	})
+	mux.HandleFunc("/doLogin", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		c, err := r.Cookie("SID")
+		if err != nil || sessions[c.Value] == "" || sessions[c.Value] != r.PostFormValue("csrf_token") {
+			t.Errorf("attempt without a valid session token: %v %q", err, r.PostFormValue("csrf_token"))
+			http.Error(w, "csrf", http.StatusForbidden)
+			return
+		}
+		attempts++
+		if r.PostFormValue("user") == "admin" && r.PostFormValue("pass") == "admin@123" {
+			http.Redirect(w, r, "/index;jsessionid=x", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, "/login?error="+fmt.Sprint(attempts), http.StatusFound)
+	})
+	return mux, &attempts
+}
+
+func TestRunWebLoginForm(t *testing.T) {
+	app, attempts := loginApp(t)
+	srv := mustTestServer(t, app)
+	defer srv.Close()
+	oldClient, oldNoRedirect := Client, ClientNoRedirect
+	Client, ClientNoRedirect = srv.Client(), srv.Client()
+	defer func() { Client, ClientNoRedirect = oldClient, oldNoRedirect }()
+
+	w, err := DetectWebLogin(srv.URL + "/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.CSRFField != "csrf_token" || w.Action != srv.URL+"/doLogin" {
+		t.Fatalf("unexpected form %+v", w)
+	}
+	db, err := InitSpiderDB(filepath.Join(t.TempDir(), "spider.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	opts := BruteOptions{Users: []string{"test", "admin"}, Passwords: []string{"123456", "admin@123"}, StopOnSuccess: true}
+	res := RunWebLogin(w, WebLoginMatch{}, opts, db)
+	if len(res) != 1 || res[0].Username != "admin" || res[0].Password != "admin@123" || res[0].Service != "http" || !strings.Contains(res[0].Note, "302") {
+		t.Fatalf("unexpected results %+v", res)
+	}
+	// baseline + test:{test,123456,admin@123} + admin:{admin,123456,admin@123}
+	if *attempts != 7 {
+		t.Fatalf("%d attempts", *attempts)
+	}
+	if saved, _ := LoadBruteResults(db); len(saved) != 1 || saved[0].Target != srv.URL+"/doLogin" {
+		t.Fatalf("saved %+v", saved)
+	}
+
+	// a success condition the failure page already meets stops before any guess
+	*attempts = 0
+	if res := RunWebLogin(w, WebLoginMatch{SuccessLocation: "/login"}, opts, nil); len(res) != 0 || *attempts != 1 {
+		t.Fatalf("baseline guard: %d attempts %+v", *attempts, res)
+	}
+	*attempts = 0
+	opts.MaxPerUser = 1
+	if res := RunWebLogin(w, WebLoginMatch{FailLocation: "error="}, opts, nil); len(res) != 0 || *attempts != 3 {
+		t.Fatalf("max-per-user: %d attempts %+v", *attempts, res)
+	}
+}
+
+func TestRunWebLoginRequest(t *testing.T) {
+	srv := mustTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Username, Password string }
+		if r.URL.Path != "/api/login" || json.NewDecoder(r.Body).Decode(&body) != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if body.Username == "root" && body.Password == `Pa"ss` {
+			fmt.Fprint(w, `{"code":200,"msg":"ok","token":"eyJhbGciOiJIUzI1NiJ9.e30.x"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"code":500,"msg":"用户名或密码错误 %s"}`, body.Username)
+	}))
+	defer srv.Close()
+	oldClient, oldNoRedirect := Client, ClientNoRedirect
+	Client, ClientNoRedirect = srv.Client(), srv.Client()
+	defer func() { Client, ClientNoRedirect = oldClient, oldNoRedirect }()
+
+	if _, err := ParseWebLoginRequest(srv.URL, "POST /api/login HTTP/1.1\n\n{}"); err == nil {
+		t.Fatal("template without a password marker accepted")
+	}
+	raw := "POST /api/login HTTP/1.1\nHost: app\nContent-Type: application/json\n\n{\"username\":\"§USER§\",\"password\":\"§PASS§\"}"
+	w, err := ParseWebLoginRequest(srv.URL+"/#/login", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := BruteOptions{Users: []string{"root"}, Passwords: []string{"123456", `Pa"ss`}, StopOnSuccess: true}
+	for _, m := range []WebLoginMatch{{FailKeywords: []string{"密码错误"}}, {SuccessKeywords: []string{`"token"`}}, {LengthDelta: 5}} {
+		res := RunWebLogin(w, m, opts, nil)
+		if len(res) != 1 || res[0].Password != `Pa"ss` || res[0].Target != srv.URL+"/api/login" {
+			t.Fatalf("%+v: unexpected results %+v", m, res)
+		}
+	}
+	// statuses and locations are equal, the automatic mode finds nothing
+	if res := RunWebLogin(w, WebLoginMatch{}, opts, nil); len(res) != 0 {
+		t.Fatalf("automatic mode: %+v", res)
+	}
+}